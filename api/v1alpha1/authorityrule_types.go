@@ -0,0 +1,86 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AuthorityRuleSpec defines a Sentinel authority rule of an app, which lets
+// only the listed origins call a resource, or blocks them.
+type AuthorityRuleSpec struct {
+	RuleTarget `json:",inline"`
+
+	// Resource the rule applies to.
+	// +kubebuilder:validation:MinLength=1
+	Resource string `json:"resource"`
+
+	// Whether the origins are the only ones allowed or the ones blocked.
+	// +kubebuilder:validation:Enum=White;Black
+	// +kubebuilder:default=White
+	// +optional
+	Strategy AuthorityStrategy `json:"strategy,omitempty"`
+
+	// Origins the strategy applies to.
+	// +kubebuilder:validation:MinItems=1
+	LimitApps []string `json:"limitApps"`
+}
+
+// AuthorityStrategy is whether an authority rule lists the allowed or the blocked origins.
+type AuthorityStrategy string
+
+const (
+	AuthorityStrategyWhite AuthorityStrategy = "White"
+	AuthorityStrategyBlack AuthorityStrategy = "Black"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Dashboard",type=string,JSONPath=`.spec.dashboardRef.name`
+//+kubebuilder:printcolumn:name="App",type=string,JSONPath=`.spec.app`
+//+kubebuilder:printcolumn:name="Resource",type=string,JSONPath=`.spec.resource`
+//+kubebuilder:printcolumn:name="Strategy",type=string,JSONPath=`.spec.strategy`
+//+kubebuilder:printcolumn:name="Published",type=string,JSONPath=`.status.conditions[?(@.type=="Published")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AuthorityRule is the Schema for the authorityrules API
+type AuthorityRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AuthorityRuleSpec `json:"spec,omitempty"`
+	Status RuleStatus        `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AuthorityRuleList contains a list of AuthorityRule
+type AuthorityRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AuthorityRule `json:"items"`
+}
+
+var _ Rule = &AuthorityRule{}
+
+func (r *AuthorityRule) GetTarget() RuleTarget { return r.Spec.RuleTarget }
+
+func (r *AuthorityRule) GetRuleStatus() *RuleStatus { return &r.Status }
+
+func init() {
+	SchemeBuilder.Register(&AuthorityRule{}, &AuthorityRuleList{})
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var authorityrulelog = logf.Log.WithName("authorityrule-resource")
+
+func (r *AuthorityRule) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-sentinel-sentinelguard-io-v1alpha1-authorityrule,mutating=false,failurePolicy=fail,sideEffects=None,groups=sentinel.sentinelguard.io,resources=authorityrules,verbs=create;update,versions=v1alpha1,name=vauthorityrule.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &AuthorityRule{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *AuthorityRule) ValidateCreate() error {
+	authorityrulelog.Info("validate create", "name", r.Name)
+
+	return r.toInvalid(r.validateSpec())
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *AuthorityRule) ValidateUpdate(old runtime.Object) error {
+	authorityrulelog.Info("validate update", "name", r.Name)
+
+	oldRule, ok := old.(*AuthorityRule)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a AuthorityRule but got a %T", old))
+	}
+
+	allErrs := r.validateSpec()
+	allErrs = append(allErrs, validateTargetUpdate(r.Spec.RuleTarget, oldRule.Spec.RuleTarget)...)
+	return r.toInvalid(allErrs)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *AuthorityRule) ValidateDelete() error {
+	return nil
+}
+
+func (r *AuthorityRule) toInvalid(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("AuthorityRule").GroupKind(), r.Name, allErrs)
+}
+
+func (r *AuthorityRule) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+	limitAppsPath := field.NewPath("spec", "limitApps")
+
+	if len(r.Spec.LimitApps) == 0 {
+		allErrs = append(allErrs, field.Required(limitAppsPath, "at least one origin is required"))
+	}
+	for i, app := range r.Spec.LimitApps {
+		if app == "" || strings.Contains(app, ",") {
+			allErrs = append(allErrs, field.Invalid(limitAppsPath.Index(i), app, "must be a non-empty origin without commas"))
+		}
+	}
+	return allErrs
+}
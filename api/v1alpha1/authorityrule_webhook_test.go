@@ -0,0 +1,68 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newValidAuthorityRule() *AuthorityRule {
+	return &AuthorityRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "order-service-callers", Namespace: "sentinel-group"},
+		Spec: AuthorityRuleSpec{
+			RuleTarget: RuleTarget{
+				DashboardRef: corev1.LocalObjectReference{Name: "sentinel-dashboard"},
+				App:          "order-service",
+			},
+			Resource:  "/orders",
+			Strategy:  AuthorityStrategyWhite,
+			LimitApps: []string{"gateway", "payment-service"},
+		},
+	}
+}
+
+var _ = Describe("AuthorityRule webhook", func() {
+	DescribeTable("ValidateCreate",
+		func(mutate func(*AuthorityRule), field string) {
+			rule := newValidAuthorityRule()
+			mutate(rule)
+			expectValidation(rule.ValidateCreate(), field)
+		},
+		Entry("accepts a valid rule", func(*AuthorityRule) {}, ""),
+		Entry("rejects an origin list in a single item", func(rule *AuthorityRule) {
+			rule.Spec.LimitApps = []string{"gateway,payment-service"}
+		}, "spec.limitApps[0]"),
+	)
+
+	DescribeTable("ValidateUpdate",
+		func(mutate func(*AuthorityRule), field string) {
+			old := newValidAuthorityRule()
+			rule := old.DeepCopy()
+			mutate(rule)
+			expectValidation(rule.ValidateUpdate(old), field)
+		},
+		Entry("accepts changing the origins", func(rule *AuthorityRule) {
+			rule.Spec.LimitApps = append(rule.Spec.LimitApps, "stock-service")
+		}, ""),
+		Entry("rejects moving the rule to another dashboard", func(rule *AuthorityRule) {
+			rule.Spec.DashboardRef.Name = "other-dashboard"
+		}, "spec.dashboardRef"),
+	)
+})
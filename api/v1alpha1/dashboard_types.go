@@ -18,9 +18,13 @@ package v1alpha1
 
 import (
 	"fmt"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -33,81 +37,642 @@ type DashboardSpec struct {
 
 	// Number of desired pods. This is a pointer to distinguish between explicit
 	// zero and not specified. Defaults to 1.
+	// +kubebuilder:default=1
 	// +optional
 	Replicas *int32 `json:"replicas,omitempty"`
 
 	// Container image name.
 	// More info: https://kubernetes.io/docs/concepts/containers/images
-	// This field is optional to allow higher level config management to default or override
-	// container images in workload controllers like Deployments and StatefulSets.
+	// Defaults to the image the operator is configured with.
 	// +optional
 	Image string `json:"image,omitempty"`
 
 	// type determines how the Service is exposed. Defaults to ClusterIP. Valid
-	// options are ExternalName, ClusterIP, NodePort, and LoadBalancer.
+	// options are ClusterIP, NodePort, and LoadBalancer.
 	// "ClusterIP" allocates a cluster-internal IP address for load-balancing
-	// to endpoints. Endpoints are determined by the selector or if that is not
-	// specified, by manual construction of an Endpoints object or
-	// EndpointSlice objects. If clusterIP is "None", no virtual IP is
-	// allocated and the endpoints are published as a set of endpoints rather
-	// than a virtual IP.
+	// to endpoints.
 	// "NodePort" builds on ClusterIP and allocates a port on every node which
 	// routes to the same endpoints as the clusterIP.
 	// "LoadBalancer" builds on NodePort and creates an external load-balancer
 	// (if supported in the current cloud) which routes to the same endpoints
 	// as the clusterIP.
-	// "ExternalName" aliases this service to the specified externalName.
-	// Several other fields do not apply to ExternalName services.
 	// More info: https://kubernetes.io/docs/concepts/services-networking/service/#publishing-services-service-types
+	// +kubebuilder:default=ClusterIP
 	// +optional
 	Type corev1.ServiceType `json:"type,omitempty"`
 
-	// The list of ports that are exposed by this service.
+	// The list of ports that are exposed by this service. Defaults to port 8080.
 	// More info: https://kubernetes.io/docs/concepts/services-networking/service/#virtual-ips-and-service-proxies
 	// +patchMergeKey=port
 	// +patchStrategy=merge
 	// +listType=map
 	// +listMapKey=port
 	// +listMapKey=protocol
+	// +kubebuilder:default={{port: 8080, protocol: TCP}}
+	// +optional
 	Ports []corev1.ServicePort `json:"ports,omitempty"`
 
 	// List of environment variables to set in the container.
-	// Cannot be updated.
+	// Variables declared here take precedence over the variables the operator
+	// renders from datasource of the same name.
+	// Unlike the env of a container, it can be updated: a change rolls out
+	// new pods.
 	// +optional
 	Env []corev1.EnvVar `json:"env,omitempty"`
 
+	// Probes overrides the default probes of the dashboard container, which
+	// query the /version endpoint of the dashboard.
+	// +optional
+	Probes *ProbesSpec `json:"probes,omitempty"`
+
+	// Autoscaling makes the controller own a HorizontalPodAutoscaler for the
+	// dashboard deployment. While set, the autoscaler decides the number of
+	// replicas and spec.replicas only applies when the deployment is created.
+	// +optional
+	Autoscaling *AutoscalingSpec `json:"autoscaling,omitempty"`
+
+	// WorkloadKind is the kind of workload running the dashboard, one of
+	// Deployment or StatefulSet. Defaults to Deployment.
+	// Switching the kind deletes the old workload before creating the new
+	// one, and the volume of spec.storage is kept across the switch.
+	// The pods of a StatefulSet get their DNS names from the headless Service
+	// <name>-headless.
+	// +kubebuilder:validation:Enum=Deployment;StatefulSet
+	// +kubebuilder:default=Deployment
+	// +optional
+	WorkloadKind WorkloadKind `json:"workloadKind,omitempty"`
+
+	// Storage persists the logs of the dashboard on a volume when set, instead
+	// of an emptyDir volume. A Deployment shares one PersistentVolumeClaim
+	// between its pods, and a StatefulSet gets one per pod. The claims are
+	// kept when storage is removed. Only the size of the claim of a
+	// Deployment can be changed, to expand it; other changes are reported
+	// by the StorageSynced condition.
+	// +optional
+	Storage *StorageSpec `json:"storage,omitempty"`
+
+	// Scheduling constrains the nodes the dashboard pods run on.
+	// +optional
+	Scheduling *SchedulingSpec `json:"scheduling,omitempty"`
+
+	// PodDisruptionBudget limits the voluntary disruptions of the dashboard
+	// pods. A PodDisruptionBudget allowing one unavailable pod is created
+	// whenever the dashboard may run more than one replica, and this field
+	// overrides it or creates one for a single replica.
+	// +optional
+	PodDisruptionBudget *PodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
+
+	// PodSecurityContext holds the pod-level security attributes of the
+	// dashboard. Defaults to running as user and group 1000 with the
+	// RuntimeDefault seccomp profile, as required by the restricted Pod
+	// Security Standard. Setting it replaces the default as a whole.
+	// +optional
+	PodSecurityContext *corev1.PodSecurityContext `json:"podSecurityContext,omitempty"`
+
+	// SecurityContext of the dashboard container. Defaults to dropping all
+	// capabilities, forbidding privilege escalation and a read-only root
+	// filesystem; /tmp and the log directory are writable volumes. Setting it
+	// replaces the default as a whole. The PodSecurity condition reports
+	// whether the pod satisfies the level enforced on the namespace.
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+
+	// ImagePullSecrets references Secrets in the namespace of the Dashboard
+	// used to pull the image.
+	// More info: https://kubernetes.io/docs/concepts/containers/images#specifying-imagepullsecrets-on-a-pod
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// Name of the ServiceAccount the dashboard runs as. Defaults to the name
+	// of the Dashboard when createServiceAccount is set, else to the default
+	// ServiceAccount of the namespace.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// CreateServiceAccount makes the operator create and own the ServiceAccount
+	// named by serviceAccountName.
+	// +optional
+	CreateServiceAccount bool `json:"createServiceAccount,omitempty"`
+
+	// AutomountServiceAccountToken indicates whether a token of the
+	// ServiceAccount is mounted into the pods. Defaults to false, as the
+	// dashboard does not call the Kubernetes API.
+	// +optional
+	AutomountServiceAccountToken *bool `json:"automountServiceAccountToken,omitempty"`
+
+	// JVM configures the JVM of the dashboard.
+	// +optional
+	JVM *JVMSpec `json:"jvm,omitempty"`
+
+	// Server configures the dashboard application.
+	// +optional
+	Server *ServerSpec `json:"server,omitempty"`
+
+	// Ingress exposes the dashboard through an Ingress when set. Removing it
+	// deletes the Ingress.
+	// +optional
+	Ingress *IngressSpec `json:"ingress,omitempty"`
+
+	// GatewayRoute exposes the dashboard through a Gateway API HTTPRoute when
+	// set. It requires the Gateway API CRDs; the RouteAccepted condition
+	// reports whether the Gateways accepted the route. Removing it deletes
+	// the HTTPRoute.
+	// +optional
+	GatewayRoute *GatewayRouteSpec `json:"gatewayRoute,omitempty"`
+
+	// HealthCheck configures how the operator checks the health of the dashboard.
+	// +optional
+	HealthCheck *HealthCheckSpec `json:"healthCheck,omitempty"`
+
+	// DeletionPolicy decides what happens to the resources of the dashboard
+	// when the Dashboard is deleted. Defaults to Delete.
+	// "Delete" deletes the owned resources along with the Dashboard.
+	// "Retain" keeps the owned resources running and releases them from the Dashboard.
+	// "Backup" exports the apps and rules known to the dashboard to the
+	// ConfigMap <name>-backup before deleting the owned resources, logging in
+	// with spec.server.auth, or the default login of the image. Deletion
+	// waits until the backup succeeds; switch to Delete to give up on it.
+	// +kubebuilder:validation:Enum=Delete;Retain;Backup
+	// +kubebuilder:default=Delete
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Datasource configures where the dashboard persists its rules.
+	// The datasource backend cannot be changed once set.
+	// +optional
+	Datasource *DatasourceSpec `json:"datasource,omitempty"`
+
+	// RuleSync configures how the rules declared by the rule resources are
+	// kept in sync with the datasource.
+	// +optional
+	RuleSync *RuleSyncSpec `json:"ruleSync,omitempty"`
+
 	// Compute Resources required by this container.
-	// Cannot be updated.
+	// Unlike the resources of a container, they can be updated: a change
+	// rolls out new pods.
 	// More info: https://kubernetes.io/docs/concepts/configuration/manage-resources-containers/
 	// +optional
 	Resources corev1.ResourceRequirements `json:"resources,omitempty" protobuf:"bytes,8,opt,name=resources"`
 }
 
+// DeletionPolicy describes how the resources of a Dashboard are handled on deletion.
+type DeletionPolicy string
+
+const (
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	DeletionPolicyBackup DeletionPolicy = "Backup"
+)
+
+// ProbesSpec defines the probes of the dashboard container. A probe that is
+// set replaces the default one as a whole.
+type ProbesSpec struct {
+	// Periodic probe of container liveness.
+	// More info: https://kubernetes.io/docs/concepts/workloads/pods/pod-lifecycle#container-probes
+	// +optional
+	Liveness *corev1.Probe `json:"liveness,omitempty"`
+
+	// Periodic probe of container service readiness.
+	// More info: https://kubernetes.io/docs/concepts/workloads/pods/pod-lifecycle#container-probes
+	// +optional
+	Readiness *corev1.Probe `json:"readiness,omitempty"`
+
+	// Startup probe indicating that the dashboard has finished booting. The
+	// default allows the JVM five minutes to start.
+	// More info: https://kubernetes.io/docs/concepts/workloads/pods/pod-lifecycle#container-probes
+	// +optional
+	Startup *corev1.Probe `json:"startup,omitempty"`
+}
+
+// WorkloadKind is the kind of workload running the dashboard.
+type WorkloadKind string
+
+const (
+	WorkloadKindDeployment  WorkloadKind = "Deployment"
+	WorkloadKindStatefulSet WorkloadKind = "StatefulSet"
+)
+
+// StorageSpec defines the persistent volume of the dashboard.
+type StorageSpec struct {
+	// Size of the volume.
+	Size resource.Quantity `json:"size"`
+
+	// Name of the StorageClass of the volume. The default StorageClass of the
+	// cluster is used when empty.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// Access modes of the volume. Defaults to ReadWriteOnce. A Deployment with
+	// more than one replica needs ReadWriteMany.
+	// +optional
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+
+	// Path the volume is mounted at. The logs of the dashboard, set by
+	// csp.sentinel.log.dir, are written to its logs directory. Defaults to /var/lib/sentinel.
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+}
+
+// HasAccessMode reports whether mode is among the access modes of the volume.
+func (s *StorageSpec) HasAccessMode(mode corev1.PersistentVolumeAccessMode) bool {
+	for _, m := range s.AccessModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// SchedulingSpec defines the scheduling constraints of the dashboard pods.
+// When the dashboard may run more than one replica and no pod anti-affinity
+// is set, the pods prefer to be spread across zones and nodes.
+type SchedulingSpec struct {
+	// NodeSelector is a selector which must be true for the pod to fit on a node.
+	// More info: https://kubernetes.io/docs/concepts/configuration/assign-pod-node/
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// If specified, the pod's scheduling constraints.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// If specified, the pod's tolerations.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// If specified, indicates the pod's priority.
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// TopologySpreadConstraints describes how the pods ought to spread across topology domains.
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+}
+
+// PodDisruptionBudgetSpec defines the PodDisruptionBudget of the dashboard.
+// At most one of minAvailable and maxUnavailable may be set; maxUnavailable
+// defaults to 1 when neither is.
+type PodDisruptionBudgetSpec struct {
+	// Minimum number or percentage of pods that must be available after an eviction.
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+
+	// Maximum number or percentage of pods that can be unavailable after an eviction.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// JVMSpec defines the JVM options of the dashboard. They are rendered, with
+// the system properties of spec.server, into the JAVA_TOOL_OPTIONS env, which
+// an env of the same name in spec.env replaces.
+type JVMSpec struct {
+	// Maximum heap size in percent of the memory limit of the container,
+	// rendered as -Xmx. Defaults to 75. Ignored when maxHeapSize is set or
+	// when the container has no memory limit.
+	// +kubebuilder:validation:Minimum=10
+	// +kubebuilder:validation:Maximum=95
+	// +optional
+	HeapPercentage *int32 `json:"heapPercentage,omitempty"`
+
+	// Maximum heap size, rendered as -Xmx.
+	// +optional
+	MaxHeapSize *resource.Quantity `json:"maxHeapSize,omitempty"`
+
+	// Initial heap size, rendered as -Xms. Must not exceed the maximum heap size.
+	// +optional
+	InitialHeapSize *resource.Quantity `json:"initialHeapSize,omitempty"`
+
+	// Garbage collector, one of G1, Parallel, Serial or Z. The JVM picks one when empty.
+	// +kubebuilder:validation:Enum=G1;Parallel;Serial;Z
+	// +optional
+	GarbageCollector string `json:"garbageCollector,omitempty"`
+
+	// Extra options appended to the rendered ones.
+	// +optional
+	Options []string `json:"options,omitempty"`
+}
+
+// ServerSpec defines the configuration of the dashboard application. It is
+// rendered as system properties, which take precedence over the
+// application.properties of the image. server.port is always set to the
+// container port of the first port of spec.ports.
+type ServerSpec struct {
+	// Servlet context path of the dashboard, server.servlet.context-path.
+	// The probes and health checks are made under it.
+	// +optional
+	ContextPath string `json:"contextPath,omitempty"`
+
+	// Timeout of the login sessions, server.servlet.session.timeout.
+	// +optional
+	SessionTimeout *metav1.Duration `json:"sessionTimeout,omitempty"`
+
+	// Auth configures the login of the dashboard.
+	// +optional
+	Auth *DashboardAuthSpec `json:"auth,omitempty"`
+
+	// Properties are extra csp.sentinel.*, sentinel.dashboard.*, auth.*,
+	// server.* and logging.* properties. Only the properties known to the
+	// dashboard are accepted, and the ones set by other fields are not.
+	// +optional
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// DashboardAuthSpec defines the login of the dashboard.
+type DashboardAuthSpec struct {
+	// Username, sentinel.dashboard.auth.username.
+	Username string `json:"username"`
+
+	// Key of a Secret holding the password, sentinel.dashboard.auth.password.
+	PasswordSecretRef corev1.SecretKeySelector `json:"passwordSecretRef"`
+}
+
+// IngressSpec defines the Ingress of the dashboard. Every path of every host
+// is routed to the first port of the Service.
+type IngressSpec struct {
+	// Name of the IngressClass of the Ingress. The default IngressClass of the
+	// cluster is used when empty.
+	// +optional
+	IngressClassName *string `json:"ingressClassName,omitempty"`
+
+	// Annotations added to the Ingress, typically to configure the ingress controller.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Hosts the dashboard is served at. The Ingress matches every host when empty.
+	// +optional
+	Hosts []string `json:"hosts,omitempty"`
+
+	// Paths the dashboard is served at. Defaults to /.
+	// +optional
+	Paths []string `json:"paths,omitempty"`
+
+	// PathType of the paths, one of Exact, Prefix or ImplementationSpecific. Defaults to Prefix.
+	// +kubebuilder:validation:Enum=Exact;Prefix;ImplementationSpecific
+	// +optional
+	PathType *networkingv1.PathType `json:"pathType,omitempty"`
+
+	// Name of the Secret holding the TLS certificate for the hosts. TLS is
+	// not terminated by the Ingress when empty.
+	// +optional
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+}
+
+// GatewayRouteSpec defines the HTTPRoute of the dashboard. Requests matching
+// the path on any of the hostnames are routed to the first port of the Service.
+type GatewayRouteSpec struct {
+	// Gateways the route attaches to.
+	// +kubebuilder:validation:MinItems=1
+	ParentRefs []GatewayParentReference `json:"parentRefs"`
+
+	// Hostnames the route matches. The route matches the hostnames of the
+	// Gateway listeners when empty.
+	// +optional
+	Hostnames []string `json:"hostnames,omitempty"`
+
+	// Path prefix the dashboard is served at. Defaults to /.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Annotations added to the HTTPRoute.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// GatewayParentReference identifies a Gateway, or a listener of it, the route attaches to.
+type GatewayParentReference struct {
+	// Group of the parent. Defaults to gateway.networking.k8s.io.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Kind of the parent. Defaults to Gateway.
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Namespace of the parent. Defaults to the namespace of the Dashboard.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the parent.
+	Name string `json:"name"`
+
+	// Name of the listener of the parent to attach to. The route attaches to
+	// every listener that allows it when empty.
+	// +optional
+	SectionName string `json:"sectionName,omitempty"`
+
+	// Port of the listener of the parent to attach to.
+	// +optional
+	Port *int32 `json:"port,omitempty"`
+}
+
+// HealthCheckMethod is the way the operator reaches the dashboard to check its health.
+type HealthCheckMethod string
+
+const (
+	// HealthCheckProxy requests the Service through the service proxy of the API server.
+	HealthCheckProxy HealthCheckMethod = "Proxy"
+	// HealthCheckDirect requests the Service directly and requires the operator to run in the cluster.
+	HealthCheckDirect HealthCheckMethod = "Direct"
+	// HealthCheckPod requests every running pod directly and requires the operator to run in the cluster.
+	HealthCheckPod HealthCheckMethod = "Pod"
+)
+
+// HealthCheckSpec defines the health check the operator runs against the dashboard.
+type HealthCheckSpec struct {
+	// Method of reaching the dashboard, one of Proxy, Direct or Pod. Defaults to Proxy.
+	// "Proxy" goes through the service proxy of the API server.
+	// "Direct" requests the Service from the operator.
+	// "Pod" requests every running pod from the operator and fails when any of them fails.
+	// +kubebuilder:validation:Enum=Proxy;Direct;Pod
+	// +kubebuilder:default=Proxy
+	// +optional
+	Method HealthCheckMethod `json:"method,omitempty"`
+
+	// Path of the endpoint returning the dashboard version, relative to the
+	// servlet context path of the dashboard. Defaults to /version.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Number of seconds after which a health check request times out. Defaults to 3.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+
+	// Number of seconds the dashboard may stay not ready before it is marked
+	// as Failed. The operator keeps checking with an exponential backoff in
+	// the meantime, and afterwards. Defaults to 600.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ReadinessTimeoutSeconds *int32 `json:"readinessTimeoutSeconds,omitempty"`
+}
+
+// AutoscalingSpec defines the HorizontalPodAutoscaler of the dashboard.
+type AutoscalingSpec struct {
+	// Lower limit for the number of replicas. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// Upper limit for the number of replicas.
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// Target average CPU utilization over all pods, in percent of the requested CPU.
+	// Defaults to 80 when no target is set.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+
+	// Target average memory utilization over all pods, in percent of the requested memory.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetMemoryUtilizationPercentage *int32 `json:"targetMemoryUtilizationPercentage,omitempty"`
+}
+
+// RuleSyncSpec defines how the rule sets published to the datasource are
+// checked for changes made outside of the rule resources, e.g. in the
+// dashboard UI.
+type RuleSyncSpec struct {
+	// What to do when the datasource holds other rules than the ones last
+	// published: overwrite them with the declared rules, write them back to
+	// the rule resources, or only report the drift on the rules.
+	// +kubebuilder:validation:Enum=Enforce;Adopt;Report
+	// +kubebuilder:default=Enforce
+	// +optional
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+
+	// How often the rule sets are read back from the datasource. Defaults to 5m.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// DriftPolicy describes how the rules changed in the datasource are handled.
+type DriftPolicy string
+
+const (
+	DriftPolicyEnforce DriftPolicy = "Enforce"
+	DriftPolicyAdopt   DriftPolicy = "Adopt"
+	DriftPolicyReport  DriftPolicy = "Report"
+)
+
+// DefaultRuleSyncInterval is how often the rule sets are read back by default.
+const DefaultRuleSyncInterval = 5 * time.Minute
+
+// MinRuleSyncInterval is the shortest interval the rule sets are read back at.
+const MinRuleSyncInterval = 10 * time.Second
+
+const (
+	// InjectAnnotation on a pod or its namespace names the dashboard, as name
+	// or namespace/name, the Sentinel clients of the pod report to. A name
+	// without namespace refers to a dashboard in the namespace of the pod. On
+	// a pod, the value InjectDisabled opts out of the injection configured on
+	// its namespace.
+	InjectAnnotation = "sentinel.sentinelguard.io/inject"
+
+	// InjectDisabled is the value of InjectAnnotation disabling the injection.
+	InjectDisabled = "false"
+
+	// InjectContainerAnnotation names the container of the pod the Sentinel
+	// client configuration is injected into, the first container by default.
+	InjectContainerAnnotation = "sentinel.sentinelguard.io/inject-container"
+
+	// InjectedLabel marks the pods the Sentinel client configuration has been
+	// injected into.
+	InjectedLabel = "sentinel.sentinelguard.io/injected"
+
+	// InjectedDashboardAnnotation holds the namespace/name of the dashboard
+	// injected into the pod.
+	InjectedDashboardAnnotation = "sentinel.sentinelguard.io/injected-dashboard"
+)
+
 // DashboardStatus defines the observed state of Dashboard
 type DashboardStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
+	// +kubebuilder:validation:Enum=Waiting;Running;Deleting;NotReady;Upgrading;Failed
 	// +optional
 	Phase Phase `json:"phase,omitempty"`
 
+	// The generation observed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Total number of pods of the dashboard.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Label selector of the dashboard pods, used by the scale subresource.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// Total number of ready pods of the dashboard.
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// Total number of available pods of the dashboard.
+	// +optional
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+
+	// Total number of pods running the latest spec of the dashboard.
+	// +optional
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+
+	// Digest of the image the newest ready pod is running.
+	// +optional
+	ImageDigest string `json:"imageDigest,omitempty"`
+
+	// URL the dashboard is reachable at.
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Version reported by the dashboard health check.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Number of running pods the client configuration of the dashboard has
+	// been injected into.
+	// +optional
+	InjectedPods int32 `json:"injectedPods,omitempty"`
+
 	Conditions []DashboardCondition `json:"conditions,omitempty"`
 }
 
+// Phase is a simple, high-level summary of where the Dashboard is in its lifecycle.
 type Phase string
 
 const (
-	PhaseWaiting  Phase = "Waiting"
-	PhaseRunning  Phase = "Running"
+	// PhaseWaiting means the first revision is rolling out or has not passed the health check yet.
+	PhaseWaiting Phase = "Waiting"
+	// PhaseRunning means the latest revision is rolled out and passed the health check.
+	PhaseRunning Phase = "Running"
+	// PhaseDeleting means the Dashboard is being deleted.
 	PhaseDeleting Phase = "Deleting"
+	// PhaseNotReady means the dashboard was running but the health check is failing.
 	PhaseNotReady Phase = "NotReady"
+	// PhaseUpgrading means a new revision is rolling out over a running dashboard.
+	PhaseUpgrading Phase = "Upgrading"
+	// PhaseFailed means the resources could not be applied, the rollout exceeded its deadline
+	// or the dashboard did not become ready within the readiness timeout.
+	PhaseFailed Phase = "Failed"
 )
 
 type DashboardConditionType string
 
 const (
-	AppliedConditionType DashboardConditionType = "Applied"
-	ReadyConditionType   DashboardConditionType = "Ready"
+	AppliedConditionType    DashboardConditionType = "Applied"
+	ReadyConditionType      DashboardConditionType = "Ready"
+	DatasourceConditionType DashboardConditionType = "DatasourceReady"
+	// PodSecurityConditionType reports whether the dashboard pod satisfies the
+	// Pod Security Standard enforced on the namespace.
+	PodSecurityConditionType DashboardConditionType = "PodSecurity"
+	// RouteAcceptedConditionType reports whether the Gateways accepted the
+	// HTTPRoute of spec.gatewayRoute. It is absent when the route is not set.
+	RouteAcceptedConditionType DashboardConditionType = "RouteAccepted"
+	// StorageSyncedConditionType reports whether spec.storage is applied to the
+	// data volume claim of the workload, as claim templates are immutable and
+	// claims can only be expanded. It is absent when no storage is set.
+	StorageSyncedConditionType DashboardConditionType = "StorageSynced"
 )
 
 type DashboardCondition struct {
@@ -149,6 +714,14 @@ func (s *Dashboard) String() string {
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+//+kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.version`
+//+kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.status.url`
+//+kubebuilder:printcolumn:name="Digest",type=string,JSONPath=`.status.imageDigest`,priority=1
+//+kubebuilder:printcolumn:name="Injected",type=integer,JSONPath=`.status.injectedPods`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Dashboard is the Schema for the dashboards API
 type Dashboard struct {
@@ -159,6 +732,36 @@ type Dashboard struct {
 	Status DashboardStatus `json:"status,omitempty"`
 }
 
+// MaxHeapBytes returns the maximum heap size of the dashboard in bytes:
+// spec.jvm.maxHeapSize, or else spec.jvm.heapPercentage of the memory limit of
+// the container. It returns false when neither is set.
+func (r *Dashboard) MaxHeapBytes() (int64, bool) {
+	jvm := r.Spec.JVM
+	if jvm == nil {
+		jvm = &JVMSpec{}
+	}
+	if jvm.MaxHeapSize != nil {
+		return jvm.MaxHeapSize.Value(), true
+	}
+	limit, ok := r.Spec.Resources.Limits[corev1.ResourceMemory]
+	if !ok {
+		return 0, false
+	}
+	percentage := int64(DefaultHeapPercentage)
+	if jvm.HeapPercentage != nil {
+		percentage = int64(*jvm.HeapPercentage)
+	}
+	return limit.Value() * percentage / 100, true
+}
+
+// ScalesOut reports whether the dashboard may run more than one replica.
+func (r *Dashboard) ScalesOut() bool {
+	if r.Spec.Autoscaling != nil {
+		return r.Spec.Autoscaling.MaxReplicas > 1
+	}
+	return r.Spec.Replicas != nil && *r.Spec.Replicas > 1
+}
+
 //+kubebuilder:object:root=true
 
 // DashboardList contains a list of Dashboard
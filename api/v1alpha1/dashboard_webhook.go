@@ -0,0 +1,409 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// DefaultPort is the port the dashboard listens on unless spec.ports says otherwise.
+	DefaultPort = 8080
+
+	// DefaultStorageMountPath is the path the volume of spec.storage is mounted at unless set.
+	DefaultStorageMountPath = "/var/lib/sentinel"
+
+	// DefaultHeapPercentage is the percentage of the memory limit the maximum heap size defaults to.
+	DefaultHeapPercentage = 75
+
+	// MinNodePort and MaxNodePort bound the default service node port range of kube-apiserver.
+	MinNodePort = 30000
+	MaxNodePort = 32767
+)
+
+// knownServerProperties are the properties of the dashboard accepted in
+// spec.server.properties, besides the logging.level.* ones.
+var knownServerProperties = map[string]bool{
+	"csp.sentinel.api.port":                         true,
+	"csp.sentinel.charset":                          true,
+	"csp.sentinel.heartbeat.client.ip":              true,
+	"csp.sentinel.heartbeat.interval.ms":            true,
+	"csp.sentinel.log.output.type":                  true,
+	"csp.sentinel.log.use.pid":                      true,
+	"csp.sentinel.metric.file.single.size":          true,
+	"csp.sentinel.metric.file.total.count":          true,
+	"csp.sentinel.statistic.max.rt":                 true,
+	"sentinel.dashboard.app.hideAppNoMachineMillis": true,
+	"sentinel.dashboard.removeAppNoMachineMillis":   true,
+	"sentinel.dashboard.unhealthyMachineMillis":     true,
+	"sentinel.dashboard.autoRemoveMachineMillis":    true,
+	"auth.filter.exclude-urls":                      true,
+	"auth.filter.exclude-url-suffixes":              true,
+	"server.servlet.session.cookie.name":            true,
+	"server.servlet.encoding.charset":               true,
+	"server.servlet.encoding.force":                 true,
+	"server.servlet.encoding.enabled":               true,
+	"logging.file.name":                             true,
+	"logging.pattern.file":                          true,
+	"logging.pattern.console":                       true,
+}
+
+// reservedServerProperties are the properties set from other fields.
+var reservedServerProperties = map[string]string{
+	"server.port":                       "spec.ports",
+	"server.servlet.context-path":       "spec.server.contextPath",
+	"server.servlet.session.timeout":    "spec.server.sessionTimeout",
+	"sentinel.dashboard.auth.username":  "spec.server.auth",
+	"sentinel.dashboard.auth.password":  "spec.server.auth",
+	"auth.username":                     "spec.server.auth",
+	"auth.password":                     "spec.server.auth",
+	"csp.sentinel.log.dir":              "spec.storage.mountPath",
+	"csp.sentinel.dashboard.server":     "",
+	"project.name":                      "",
+	"spring.config.additional-location": "",
+}
+
+// DefaultImage is the dashboard image used when spec.image is empty, unless
+// the operator is configured with another one.
+const DefaultImage = "sentinel-group/sentinel-dashboard:v0.1.0"
+
+// log is for logging in this package.
+var dashboardlog = logf.Log.WithName("dashboard-resource")
+
+// SetupWebhookWithManager registers the webhooks of the type. image is the
+// image spec.image defaults to.
+func (r *Dashboard) SetupWebhookWithManager(mgr ctrl.Manager, image string) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&DashboardDefaulter{Image: image}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-sentinel-sentinelguard-io-v1alpha1-dashboard,mutating=true,failurePolicy=fail,sideEffects=None,groups=sentinel.sentinelguard.io,resources=dashboards,verbs=create;update,versions=v1alpha1,name=mdashboard.kb.io,admissionReviewVersions=v1
+
+// DashboardDefaulter defaults the Dashboards through the mutating webhook.
+// +kubebuilder:object:generate=false
+type DashboardDefaulter struct {
+	// Image is the image spec.image defaults to, DefaultImage when empty.
+	Image string
+}
+
+var _ admission.CustomDefaulter = &DashboardDefaulter{}
+
+// Default implements admission.CustomDefaulter.
+func (d *DashboardDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	r, ok := obj.(*Dashboard)
+	if !ok {
+		return fmt.Errorf("expected a Dashboard but got a %T", obj)
+	}
+	dashboardlog.Info("default", "name", r.Name)
+	r.SetDefaults(d.Image)
+	return nil
+}
+
+// SetDefaults sets the unset fields of the spec to their defaults, with
+// spec.image defaulting to image, or DefaultImage when empty. The controller
+// applies it as well, so that the dashboards are rendered the same when the
+// webhooks are disabled.
+func (r *Dashboard) SetDefaults(image string) {
+	if image == "" {
+		image = DefaultImage
+	}
+	if r.Spec.Replicas == nil {
+		replicas := int32(1)
+		r.Spec.Replicas = &replicas
+	}
+	if r.Spec.Image == "" {
+		r.Spec.Image = image
+	}
+	if r.Spec.Type == "" {
+		r.Spec.Type = corev1.ServiceTypeClusterIP
+	}
+	if r.Spec.WorkloadKind == "" {
+		r.Spec.WorkloadKind = WorkloadKindDeployment
+	}
+	if storage := r.Spec.Storage; storage != nil {
+		if len(storage.AccessModes) == 0 {
+			storage.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+		}
+		if storage.MountPath == "" {
+			storage.MountPath = DefaultStorageMountPath
+		}
+	}
+	if r.Spec.DeletionPolicy == "" {
+		r.Spec.DeletionPolicy = DeletionPolicyDelete
+	}
+	if len(r.Spec.Ports) == 0 {
+		r.Spec.Ports = []corev1.ServicePort{{Port: DefaultPort}}
+	}
+	for i := range r.Spec.Ports {
+		if r.Spec.Ports[i].Protocol == "" {
+			r.Spec.Ports[i].Protocol = corev1.ProtocolTCP
+		}
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-sentinel-sentinelguard-io-v1alpha1-dashboard,mutating=false,failurePolicy=fail,sideEffects=None,groups=sentinel.sentinelguard.io,resources=dashboards,verbs=create;update,versions=v1alpha1,name=vdashboard.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &Dashboard{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Dashboard) ValidateCreate() error {
+	dashboardlog.Info("validate create", "name", r.Name)
+
+	return r.toInvalid(r.validateSpec())
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Dashboard) ValidateUpdate(old runtime.Object) error {
+	dashboardlog.Info("validate update", "name", r.Name)
+
+	oldDashboard, ok := old.(*Dashboard)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a Dashboard but got a %T", old))
+	}
+
+	allErrs := r.validateSpec()
+	allErrs = append(allErrs, r.validateImmutable(oldDashboard)...)
+	return r.toInvalid(allErrs)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Dashboard) ValidateDelete() error {
+	return nil
+}
+
+func (r *Dashboard) toInvalid(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Dashboard").GroupKind(), r.Name, allErrs)
+}
+
+func (r *Dashboard) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	switch r.Spec.Type {
+	case "", corev1.ServiceTypeClusterIP, corev1.ServiceTypeNodePort, corev1.ServiceTypeLoadBalancer:
+	default:
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("type"), r.Spec.Type, []string{
+			string(corev1.ServiceTypeClusterIP), string(corev1.ServiceTypeNodePort), string(corev1.ServiceTypeLoadBalancer),
+		}))
+	}
+
+	portsPath := specPath.Child("ports")
+	if len(r.Spec.Ports) == 0 {
+		allErrs = append(allErrs, field.Required(portsPath, "at least one port is required"))
+	}
+	for i, port := range r.Spec.Ports {
+		idxPath := portsPath.Index(i)
+		if port.Port < 1 || port.Port > 65535 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("port"), port.Port, "must be between 1 and 65535, inclusive"))
+		}
+		if port.NodePort == 0 {
+			continue
+		}
+		if r.Spec.Type != corev1.ServiceTypeNodePort && r.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			allErrs = append(allErrs, field.Forbidden(idxPath.Child("nodePort"), "may only be set when type is NodePort or LoadBalancer"))
+		} else if port.NodePort < MinNodePort || port.NodePort > MaxNodePort {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("nodePort"), port.NodePort,
+				fmt.Sprintf("must be between %d and %d, inclusive", MinNodePort, MaxNodePort)))
+		}
+	}
+
+	if autoscaling := r.Spec.Autoscaling; autoscaling != nil && autoscaling.MinReplicas != nil &&
+		*autoscaling.MinReplicas > autoscaling.MaxReplicas {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("autoscaling", "minReplicas"), *autoscaling.MinReplicas,
+			"must be less than or equal to maxReplicas"))
+	}
+
+	if storage := r.Spec.Storage; storage != nil {
+		storagePath := specPath.Child("storage")
+		if storage.Size.Sign() <= 0 {
+			allErrs = append(allErrs, field.Invalid(storagePath.Child("size"), storage.Size.String(), "must be greater than zero"))
+		}
+		if storage.MountPath != "" && !strings.HasPrefix(storage.MountPath, "/") {
+			allErrs = append(allErrs, field.Invalid(storagePath.Child("mountPath"), storage.MountPath, "must be an absolute path"))
+		}
+		if r.Spec.WorkloadKind != WorkloadKindStatefulSet && r.ScalesOut() && !storage.HasAccessMode(corev1.ReadWriteMany) {
+			allErrs = append(allErrs, field.Invalid(storagePath.Child("accessModes"), storage.AccessModes,
+				"must include ReadWriteMany when a Deployment may run more than one replica, or use the StatefulSet workload kind"))
+		}
+	}
+
+	if pdb := r.Spec.PodDisruptionBudget; pdb != nil && pdb.MinAvailable != nil && pdb.MaxUnavailable != nil {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("podDisruptionBudget"),
+			"minAvailable and maxUnavailable cannot be both set"))
+	}
+
+	allErrs = append(allErrs, r.validateJVM(specPath.Child("jvm"))...)
+	allErrs = append(allErrs, r.validateServer(specPath.Child("server"))...)
+
+	if ingress := r.Spec.Ingress; ingress != nil {
+		for i, path := range ingress.Paths {
+			if !strings.HasPrefix(path, "/") {
+				allErrs = append(allErrs, field.Invalid(specPath.Child("ingress", "paths").Index(i), path, "must start with /"))
+			}
+		}
+	}
+
+	if route := r.Spec.GatewayRoute; route != nil && route.Path != "" && !strings.HasPrefix(route.Path, "/") {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("gatewayRoute", "path"), route.Path, "must start with /"))
+	}
+
+	if healthCheck := r.Spec.HealthCheck; healthCheck != nil && healthCheck.Path != "" && !strings.HasPrefix(healthCheck.Path, "/") {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("healthCheck", "path"), healthCheck.Path, "must start with /"))
+	}
+
+	if sync := r.Spec.RuleSync; sync != nil && sync.Interval != nil && sync.Interval.Duration < MinRuleSyncInterval {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("ruleSync", "interval"), sync.Interval.Duration.String(),
+			fmt.Sprintf("must be at least %s", MinRuleSyncInterval)))
+	}
+
+	resourcesPath := specPath.Child("resources")
+	for name, request := range r.Spec.Resources.Requests {
+		limit, ok := r.Spec.Resources.Limits[name]
+		if ok && request.Cmp(limit) > 0 {
+			allErrs = append(allErrs, field.Invalid(resourcesPath.Child("requests").Key(string(name)), request.String(),
+				fmt.Sprintf("must be less than or equal to %s limit", name)))
+		}
+	}
+
+	return allErrs
+}
+
+func (r *Dashboard) validateJVM(jvmPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	jvm := r.Spec.JVM
+	if jvm == nil {
+		return allErrs
+	}
+
+	limit, hasLimit := r.Spec.Resources.Limits[corev1.ResourceMemory]
+	if jvm.MaxHeapSize != nil && hasLimit && jvm.MaxHeapSize.Cmp(limit) >= 0 {
+		allErrs = append(allErrs, field.Invalid(jvmPath.Child("maxHeapSize"), jvm.MaxHeapSize.String(),
+			"must be less than the memory limit"))
+	}
+	if maxHeap, ok := r.MaxHeapBytes(); ok && jvm.InitialHeapSize != nil && jvm.InitialHeapSize.Value() > maxHeap {
+		detail := "must be less than or equal to maxHeapSize"
+		if jvm.MaxHeapSize == nil {
+			detail = fmt.Sprintf("must be less than or equal to the maximum heap size, %s of the memory limit",
+				resource.NewQuantity(maxHeap, resource.BinarySI).String())
+		}
+		allErrs = append(allErrs, field.Invalid(jvmPath.Child("initialHeapSize"), jvm.InitialHeapSize.String(), detail))
+	}
+	for i, option := range jvm.Options {
+		if !strings.HasPrefix(option, "-") {
+			allErrs = append(allErrs, field.Invalid(jvmPath.Child("options").Index(i), option, "must start with -"))
+		}
+	}
+	return allErrs
+}
+
+func (r *Dashboard) validateServer(serverPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	server := r.Spec.Server
+	if server == nil {
+		return allErrs
+	}
+
+	if server.ContextPath != "" && (!strings.HasPrefix(server.ContextPath, "/") || strings.HasSuffix(server.ContextPath, "/")) {
+		allErrs = append(allErrs, field.Invalid(serverPath.Child("contextPath"), server.ContextPath,
+			"must start with / and must not end with /"))
+	}
+	if server.Auth != nil && strings.ContainsAny(server.Auth.Username, " \t\n") {
+		allErrs = append(allErrs, field.Invalid(serverPath.Child("auth", "username"), server.Auth.Username,
+			"must not contain whitespaces"))
+	}
+
+	propertiesPath := serverPath.Child("properties")
+	for key, value := range server.Properties {
+		if by, ok := reservedServerProperties[key]; ok {
+			detail := "is set by the operator"
+			if by != "" {
+				detail = "is set from " + by
+			}
+			allErrs = append(allErrs, field.Forbidden(propertiesPath.Key(key), detail))
+			continue
+		}
+		if !knownServerProperties[key] && !strings.HasPrefix(key, "logging.level.") {
+			allErrs = append(allErrs, field.NotSupported(propertiesPath.Key(key), key, nil))
+			continue
+		}
+		if value == "" || strings.ContainsAny(value, " \t\n") {
+			allErrs = append(allErrs, field.Invalid(propertiesPath.Key(key), value, "must be non-empty and must not contain whitespaces"))
+		}
+	}
+	return allErrs
+}
+
+// validateImmutable rejects switching the datasource to another backend,
+// which would leave the persisted rules behind.
+func (r *Dashboard) validateImmutable(old *Dashboard) field.ErrorList {
+	var allErrs field.ErrorList
+	if old.Spec.Datasource == nil || r.Spec.Datasource == nil {
+		return allErrs
+	}
+
+	oldType, newType := datasourceType(old.Spec.Datasource), datasourceType(r.Spec.Datasource)
+	if oldType != "" && newType != "" && oldType != newType {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "datasource"),
+			fmt.Sprintf("cannot change datasource from %s to %s", oldType, newType)))
+	}
+	return allErrs
+}
+
+// datasourceType returns the name of the datasource set in spec, or empty
+// when none or more than one is set.
+func datasourceType(spec *DatasourceSpec) string {
+	var types []string
+	if spec.Nacos != nil {
+		types = append(types, "nacos")
+	}
+	if spec.Apollo != nil {
+		types = append(types, "apollo")
+	}
+	if spec.ZooKeeper != nil {
+		types = append(types, "zookeeper")
+	}
+	if spec.Etcd != nil {
+		types = append(types, "etcd")
+	}
+	if spec.Consul != nil {
+		types = append(types, "consul")
+	}
+	if spec.Redis != nil {
+		types = append(types, "redis")
+	}
+	if len(types) != 1 {
+		return ""
+	}
+	return types[0]
+}
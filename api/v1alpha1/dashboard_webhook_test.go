@@ -0,0 +1,266 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func newValidDashboard() *Dashboard {
+	return &Dashboard{
+		ObjectMeta: metav1.ObjectMeta{Name: "sentinel-dashboard", Namespace: "sentinel-group"},
+		Spec: DashboardSpec{
+			Image: "sentinel-group/sentinel-dashboard:v0.1.0",
+			Type:  corev1.ServiceTypeNodePort,
+			Ports: []corev1.ServicePort{{Port: 8080, NodePort: 30080}},
+			Resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("1"),
+					corev1.ResourceMemory: resource.MustParse("1Gi"),
+				},
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("1"),
+					corev1.ResourceMemory: resource.MustParse("1Gi"),
+				},
+			},
+			WorkloadKind:   WorkloadKindStatefulSet,
+			DeletionPolicy: DeletionPolicyRetain,
+			Datasource: &DatasourceSpec{
+				Nacos: &NacosDatasource{ServerAddr: []string{"nacos.nacos-group:8848"}},
+			},
+		},
+	}
+}
+
+var _ = Describe("Dashboard webhook", func() {
+	var dashboard *Dashboard
+
+	BeforeEach(func() {
+		dashboard = newValidDashboard()
+	})
+
+	Context("Default", func() {
+		It("defaults an empty spec", func() {
+			dashboard.Spec = DashboardSpec{}
+			dashboard.SetDefaults("")
+			Expect(dashboard.Spec.Replicas).To(HaveValue(BeEquivalentTo(1)))
+			Expect(dashboard.Spec.Image).To(Equal(DefaultImage))
+			Expect(dashboard.Spec.Type).To(Equal(corev1.ServiceTypeClusterIP))
+			Expect(dashboard.Spec.WorkloadKind).To(Equal(WorkloadKindDeployment))
+			Expect(dashboard.Spec.DeletionPolicy).To(Equal(DeletionPolicyDelete))
+			Expect(dashboard.Spec.Ports).To(Equal([]corev1.ServicePort{{Port: DefaultPort, Protocol: corev1.ProtocolTCP}}))
+			Expect(dashboard.ValidateCreate()).To(Succeed())
+		})
+
+		It("keeps values that are set", func() {
+			expected := dashboard.DeepCopy()
+			expected.Spec.Replicas = new(int32)
+			dashboard.Spec.Replicas = new(int32)
+			expected.Spec.Ports[0].Protocol = corev1.ProtocolTCP
+			dashboard.SetDefaults("")
+			Expect(dashboard.Spec).To(Equal(expected.Spec))
+		})
+
+		It("defaults the storage", func() {
+			dashboard.Spec.Storage = &StorageSpec{Size: resource.MustParse("1Gi")}
+			dashboard.SetDefaults("")
+			Expect(dashboard.Spec.Storage.AccessModes).To(Equal([]corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}))
+			Expect(dashboard.Spec.Storage.MountPath).To(Equal(DefaultStorageMountPath))
+		})
+
+		It("defaults the image to the one of the defaulter", func() {
+			dashboard.Spec.Image = ""
+			defaulter := &DashboardDefaulter{Image: "registry.example.com/sentinel-dashboard:v1"}
+			Expect(defaulter.Default(context.Background(), dashboard)).To(Succeed())
+			Expect(dashboard.Spec.Image).To(Equal("registry.example.com/sentinel-dashboard:v1"))
+
+			Expect(defaulter.Default(context.Background(), &FlowRule{})).NotTo(Succeed())
+		})
+	})
+
+	Context("ValidateCreate", func() {
+		It("accepts a valid dashboard", func() {
+			Expect(dashboard.ValidateCreate()).To(Succeed())
+		})
+
+		It("rejects empty ports", func() {
+			dashboard.Spec.Ports = nil
+			expectInvalid(dashboard.ValidateCreate(), "spec.ports")
+		})
+
+		It("rejects an out of range port", func() {
+			dashboard.Spec.Ports[0].Port = 70000
+			expectInvalid(dashboard.ValidateCreate(), "spec.ports[0].port")
+		})
+
+		It("rejects an unsupported service type", func() {
+			dashboard.Spec.Type = corev1.ServiceTypeExternalName
+			expectInvalid(dashboard.ValidateCreate(), "spec.type")
+		})
+
+		It("rejects a node port outside the node port range", func() {
+			dashboard.Spec.Ports[0].NodePort = 8080
+			expectInvalid(dashboard.ValidateCreate(), "spec.ports[0].nodePort")
+		})
+
+		It("rejects a node port on a ClusterIP service", func() {
+			dashboard.Spec.Type = corev1.ServiceTypeClusterIP
+			expectInvalid(dashboard.ValidateCreate(), "spec.ports[0].nodePort")
+		})
+
+		It("rejects minReplicas greater than maxReplicas", func() {
+			minReplicas := int32(3)
+			dashboard.Spec.Autoscaling = &AutoscalingSpec{MinReplicas: &minReplicas, MaxReplicas: 2}
+			expectInvalid(dashboard.ValidateCreate(), "spec.autoscaling.minReplicas")
+		})
+
+		It("rejects a shared ReadWriteOnce volume for a scaled out Deployment", func() {
+			replicas := int32(2)
+			dashboard.Spec.Replicas = &replicas
+			dashboard.Spec.WorkloadKind = WorkloadKindDeployment
+			dashboard.Spec.Storage = &StorageSpec{
+				Size:        resource.MustParse("1Gi"),
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			}
+			expectInvalid(dashboard.ValidateCreate(), "spec.storage.accessModes")
+
+			dashboard.Spec.WorkloadKind = WorkloadKindStatefulSet
+			Expect(dashboard.ValidateCreate()).To(Succeed())
+		})
+
+		It("rejects both minAvailable and maxUnavailable", func() {
+			one := intstr.FromInt(1)
+			dashboard.Spec.PodDisruptionBudget = &PodDisruptionBudgetSpec{MinAvailable: &one, MaxUnavailable: &one}
+			expectInvalid(dashboard.ValidateCreate(), "spec.podDisruptionBudget")
+		})
+
+		It("rejects a relative ingress path", func() {
+			dashboard.Spec.Ingress = &IngressSpec{Hosts: []string{"sentinel.example.com"}, Paths: []string{"/", "dashboard"}}
+			expectInvalid(dashboard.ValidateCreate(), "spec.ingress.paths[1]")
+		})
+
+		It("rejects a relative gateway route path", func() {
+			dashboard.Spec.GatewayRoute = &GatewayRouteSpec{
+				ParentRefs: []GatewayParentReference{{Name: "public"}},
+				Path:       "dashboard",
+			}
+			expectInvalid(dashboard.ValidateCreate(), "spec.gatewayRoute.path")
+		})
+
+		It("rejects a relative health check path", func() {
+			dashboard.Spec.HealthCheck = &HealthCheckSpec{Path: "version"}
+			expectInvalid(dashboard.ValidateCreate(), "spec.healthCheck.path")
+		})
+
+		It("rejects a max heap size not below the memory limit", func() {
+			maxHeapSize := resource.MustParse("1Gi")
+			dashboard.Spec.JVM = &JVMSpec{MaxHeapSize: &maxHeapSize}
+			expectInvalid(dashboard.ValidateCreate(), "spec.jvm.maxHeapSize")
+		})
+
+		It("rejects an initial heap size above the max heap size", func() {
+			maxHeapSize, initialHeapSize := resource.MustParse("512Mi"), resource.MustParse("600Mi")
+			dashboard.Spec.JVM = &JVMSpec{MaxHeapSize: &maxHeapSize, InitialHeapSize: &initialHeapSize}
+			expectInvalid(dashboard.ValidateCreate(), "spec.jvm.initialHeapSize")
+		})
+
+		It("checks the initial heap size against the heap percentage of the memory limit", func() {
+			initialHeapSize := resource.MustParse("900Mi")
+			dashboard.Spec.JVM = &JVMSpec{InitialHeapSize: &initialHeapSize}
+			expectInvalid(dashboard.ValidateCreate(), "spec.jvm.initialHeapSize")
+
+			heapPercentage := int32(90)
+			dashboard.Spec.JVM.HeapPercentage = &heapPercentage
+			Expect(dashboard.ValidateCreate()).To(Succeed())
+		})
+
+		It("rejects a JVM option that is not an option", func() {
+			dashboard.Spec.JVM = &JVMSpec{Options: []string{"-XX:+ExitOnOutOfMemoryError", "Xss512k"}}
+			expectInvalid(dashboard.ValidateCreate(), "spec.jvm.options[1]")
+		})
+
+		It("rejects a relative context path", func() {
+			dashboard.Spec.Server = &ServerSpec{ContextPath: "sentinel"}
+			expectInvalid(dashboard.ValidateCreate(), "spec.server.contextPath")
+		})
+
+		It("validates the server properties", func() {
+			dashboard.Spec.Server = &ServerSpec{Properties: map[string]string{
+				"csp.sentinel.statistic.max.rt": "10000",
+				"logging.level.root":            "WARN",
+			}}
+			Expect(dashboard.ValidateCreate()).To(Succeed())
+
+			dashboard.Spec.Server.Properties["server.port"] = "8081"
+			expectInvalid(dashboard.ValidateCreate(), "spec.server.properties[server.port]")
+
+			delete(dashboard.Spec.Server.Properties, "server.port")
+			dashboard.Spec.Server.Properties["csp.sentinel.unknown"] = "true"
+			expectInvalid(dashboard.ValidateCreate(), "spec.server.properties[csp.sentinel.unknown]")
+
+			delete(dashboard.Spec.Server.Properties, "csp.sentinel.unknown")
+			dashboard.Spec.Server.Properties["logging.level.root"] = "WARN -Dserver.port=8081"
+			expectInvalid(dashboard.ValidateCreate(), "spec.server.properties[logging.level.root]")
+		})
+
+		It("rejects a rule sync interval below the minimum", func() {
+			dashboard.Spec.RuleSync = &RuleSyncSpec{Interval: &metav1.Duration{Duration: time.Second}}
+			expectInvalid(dashboard.ValidateCreate(), "spec.ruleSync.interval")
+
+			dashboard.Spec.RuleSync.Interval.Duration = time.Minute
+			Expect(dashboard.ValidateCreate()).To(Succeed())
+		})
+
+		It("rejects requests greater than limits", func() {
+			dashboard.Spec.Resources.Requests[corev1.ResourceMemory] = resource.MustParse("2Gi")
+			err := dashboard.ValidateCreate()
+			expectInvalid(err, "spec.resources.requests[memory]")
+			Expect(err.Error()).To(ContainSubstring("must be less than or equal to memory limit"))
+		})
+	})
+
+	Context("ValidateUpdate", func() {
+		It("accepts changing the datasource settings", func() {
+			old := dashboard.DeepCopy()
+			dashboard.Spec.Datasource.Nacos.Group = "SENTINEL_GROUP"
+			Expect(dashboard.ValidateUpdate(old)).To(Succeed())
+		})
+
+		It("rejects switching the datasource backend", func() {
+			old := dashboard.DeepCopy()
+			dashboard.Spec.Datasource = &DatasourceSpec{
+				Redis: &RedisDatasource{Address: "redis:6379"},
+			}
+			expectInvalid(dashboard.ValidateUpdate(old), "spec.datasource")
+		})
+
+		It("validates the new spec", func() {
+			old := dashboard.DeepCopy()
+			dashboard.Spec.Ports = nil
+			expectInvalid(dashboard.ValidateUpdate(old), "spec.ports")
+		})
+	})
+})
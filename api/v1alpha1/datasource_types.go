@@ -0,0 +1,181 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DatasourceSpec defines the dynamic rule datasource of the dashboard.
+// Exactly one datasource must be set.
+// +kubebuilder:validation:MinProperties=1
+// +kubebuilder:validation:MaxProperties=1
+type DatasourceSpec struct {
+	// Nacos persists rules in a Nacos config server.
+	// +optional
+	Nacos *NacosDatasource `json:"nacos,omitempty"`
+
+	// Apollo persists rules in an Apollo config center.
+	// +optional
+	Apollo *ApolloDatasource `json:"apollo,omitempty"`
+
+	// ZooKeeper persists rules in a ZooKeeper ensemble.
+	// +optional
+	ZooKeeper *ZooKeeperDatasource `json:"zookeeper,omitempty"`
+
+	// Etcd persists rules in an etcd cluster.
+	// +optional
+	Etcd *EtcdDatasource `json:"etcd,omitempty"`
+
+	// Consul persists rules in the Consul KV store.
+	// +optional
+	Consul *ConsulDatasource `json:"consul,omitempty"`
+
+	// Redis persists rules in Redis.
+	// +optional
+	Redis *RedisDatasource `json:"redis,omitempty"`
+}
+
+// NacosDatasource defines the Nacos config server the dashboard connects to.
+type NacosDatasource struct {
+	// List of Nacos server addresses in host:port form.
+	// +kubebuilder:validation:MinItems=1
+	ServerAddr []string `json:"serverAddr"`
+
+	// Nacos namespace id the rules are stored in. Defaults to the public namespace.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Nacos group the rules are stored in. Defaults to SENTINEL_GROUP.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Context path of the Nacos server. Defaults to /nacos.
+	// +optional
+	ContextPath string `json:"contextPath,omitempty"`
+
+	// Credentials used to authenticate against Nacos.
+	// +optional
+	Credentials *SecretCredentials `json:"credentials,omitempty"`
+}
+
+// ApolloDatasource defines the Apollo config center the dashboard connects to.
+type ApolloDatasource struct {
+	// URL of the Apollo portal, used to publish rules through the open API.
+	// +kubebuilder:validation:MinLength=1
+	PortalURL string `json:"portalURL"`
+
+	// Apollo app id the rules are stored in.
+	// +kubebuilder:validation:MinLength=1
+	AppID string `json:"appID"`
+
+	// Apollo environment. Defaults to DEV.
+	// +optional
+	Env string `json:"env,omitempty"`
+
+	// Apollo cluster. Defaults to default.
+	// +optional
+	Cluster string `json:"cluster,omitempty"`
+
+	// Apollo namespace the rules are stored in. Defaults to application.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Open API token of the Apollo portal.
+	// +optional
+	Token *corev1.SecretKeySelector `json:"token,omitempty"`
+}
+
+// ZooKeeperDatasource defines the ZooKeeper ensemble the dashboard connects to.
+type ZooKeeperDatasource struct {
+	// List of ZooKeeper server addresses in host:port form.
+	// +kubebuilder:validation:MinItems=1
+	ServerAddr []string `json:"serverAddr"`
+
+	// Root path of the rule nodes. Defaults to /sentinel_rule_config.
+	// +optional
+	RootPath string `json:"rootPath,omitempty"`
+
+	// Credentials used for digest authentication.
+	// +optional
+	Credentials *SecretCredentials `json:"credentials,omitempty"`
+}
+
+// EtcdDatasource defines the etcd cluster the dashboard connects to.
+type EtcdDatasource struct {
+	// List of etcd endpoints, e.g. http://etcd:2379.
+	// +kubebuilder:validation:MinItems=1
+	Endpoints []string `json:"endpoints"`
+
+	// Key prefix of the rules. Defaults to /sentinel.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Credentials used to authenticate against etcd.
+	// +optional
+	Credentials *SecretCredentials `json:"credentials,omitempty"`
+}
+
+// ConsulDatasource defines the Consul agent the dashboard connects to.
+type ConsulDatasource struct {
+	// Address of the Consul agent in host:port form.
+	// +kubebuilder:validation:MinLength=1
+	Address string `json:"address"`
+
+	// KV path prefix of the rules. Defaults to sentinel.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// ACL token used to access the KV store.
+	// +optional
+	Token *corev1.SecretKeySelector `json:"token,omitempty"`
+}
+
+// RedisDatasource defines the Redis server the dashboard connects to.
+type RedisDatasource struct {
+	// Address of the Redis server in host:port form.
+	// +kubebuilder:validation:MinLength=1
+	Address string `json:"address"`
+
+	// Redis database index. Defaults to 0.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Database int32 `json:"database,omitempty"`
+
+	// Channel rule updates are published to. Defaults to sentinel.rules.
+	// +optional
+	Channel string `json:"channel,omitempty"`
+
+	// Credentials used to authenticate against Redis.
+	// +optional
+	Credentials *SecretCredentials `json:"credentials,omitempty"`
+}
+
+// SecretCredentials references a username and password stored in a Secret
+// in the namespace of the Dashboard.
+type SecretCredentials struct {
+	// The Secret holding the credentials.
+	SecretRef corev1.LocalObjectReference `json:"secretRef"`
+
+	// Key of the username in the Secret. Defaults to username.
+	// +optional
+	UsernameKey string `json:"usernameKey,omitempty"`
+
+	// Key of the password in the Secret. Defaults to password.
+	// +optional
+	PasswordKey string `json:"passwordKey,omitempty"`
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DegradeRuleSpec defines a Sentinel circuit breaking rule of an app.
+type DegradeRuleSpec struct {
+	RuleTarget `json:",inline"`
+
+	// Resource the rule applies to.
+	// +kubebuilder:validation:MinLength=1
+	Resource string `json:"resource"`
+
+	// Metric that trips the circuit breaker: the ratio of slow requests,
+	// the ratio of errors or the number of errors.
+	// +kubebuilder:validation:Enum=SlowRequestRatio;ErrorRatio;ErrorCount
+	// +kubebuilder:default=SlowRequestRatio
+	// +optional
+	Grade DegradeGrade `json:"grade,omitempty"`
+
+	// Threshold of the rule: the response time in milliseconds over which a
+	// request is slow, the error ratio between 0 and 1, or the error count.
+	Count resource.Quantity `json:"count"`
+
+	// Ratio of slow requests between 0 and 1 over which the circuit breaker
+	// trips. Only allowed with the SlowRequestRatio grade. Defaults to 1.
+	// +optional
+	SlowRatioThreshold *resource.Quantity `json:"slowRatioThreshold,omitempty"`
+
+	// Time the circuit breaker stays open, in seconds.
+	// +kubebuilder:validation:Minimum=1
+	TimeWindow int32 `json:"timeWindow"`
+
+	// Minimum number of requests in the statistic interval before the
+	// circuit breaker can trip. Defaults to 5.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinRequestAmount *int32 `json:"minRequestAmount,omitempty"`
+
+	// Statistic interval in milliseconds. Defaults to 1000.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	StatIntervalMs *int32 `json:"statIntervalMs,omitempty"`
+}
+
+// DegradeGrade is the metric that trips a circuit breaker.
+type DegradeGrade string
+
+const (
+	DegradeGradeSlowRequestRatio DegradeGrade = "SlowRequestRatio"
+	DegradeGradeErrorRatio       DegradeGrade = "ErrorRatio"
+	DegradeGradeErrorCount       DegradeGrade = "ErrorCount"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Dashboard",type=string,JSONPath=`.spec.dashboardRef.name`
+//+kubebuilder:printcolumn:name="App",type=string,JSONPath=`.spec.app`
+//+kubebuilder:printcolumn:name="Resource",type=string,JSONPath=`.spec.resource`
+//+kubebuilder:printcolumn:name="Published",type=string,JSONPath=`.status.conditions[?(@.type=="Published")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// DegradeRule is the Schema for the degraderules API
+type DegradeRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DegradeRuleSpec `json:"spec,omitempty"`
+	Status RuleStatus      `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DegradeRuleList contains a list of DegradeRule
+type DegradeRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DegradeRule `json:"items"`
+}
+
+var _ Rule = &DegradeRule{}
+
+func (r *DegradeRule) GetTarget() RuleTarget { return r.Spec.RuleTarget }
+
+func (r *DegradeRule) GetRuleStatus() *RuleStatus { return &r.Status }
+
+func init() {
+	SchemeBuilder.Register(&DegradeRule{}, &DegradeRuleList{})
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var degraderulelog = logf.Log.WithName("degraderule-resource")
+
+func (r *DegradeRule) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-sentinel-sentinelguard-io-v1alpha1-degraderule,mutating=false,failurePolicy=fail,sideEffects=None,groups=sentinel.sentinelguard.io,resources=degraderules,verbs=create;update,versions=v1alpha1,name=vdegraderule.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &DegradeRule{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *DegradeRule) ValidateCreate() error {
+	degraderulelog.Info("validate create", "name", r.Name)
+
+	return r.toInvalid(r.validateSpec())
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *DegradeRule) ValidateUpdate(old runtime.Object) error {
+	degraderulelog.Info("validate update", "name", r.Name)
+
+	oldRule, ok := old.(*DegradeRule)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a DegradeRule but got a %T", old))
+	}
+
+	allErrs := r.validateSpec()
+	allErrs = append(allErrs, validateTargetUpdate(r.Spec.RuleTarget, oldRule.Spec.RuleTarget)...)
+	return r.toInvalid(allErrs)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *DegradeRule) ValidateDelete() error {
+	return nil
+}
+
+func (r *DegradeRule) toInvalid(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("DegradeRule").GroupKind(), r.Name, allErrs)
+}
+
+func (r *DegradeRule) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if r.Spec.Count.Sign() < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("count"), r.Spec.Count.String(), "must be greater than or equal to 0"))
+	}
+	if r.Spec.Grade == DegradeGradeErrorRatio && r.Spec.Count.Cmp(resource.MustParse("1")) > 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("count"), r.Spec.Count.String(), "must be a ratio between 0 and 1"))
+	}
+
+	if threshold := r.Spec.SlowRatioThreshold; threshold != nil {
+		thresholdPath := specPath.Child("slowRatioThreshold")
+		if r.Spec.Grade != "" && r.Spec.Grade != DegradeGradeSlowRequestRatio {
+			allErrs = append(allErrs, field.Forbidden(thresholdPath, "is only allowed with the SlowRequestRatio grade"))
+		} else if threshold.Sign() < 0 || threshold.Cmp(resource.MustParse("1")) > 0 {
+			allErrs = append(allErrs, field.Invalid(thresholdPath, threshold.String(), "must be a ratio between 0 and 1"))
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newValidDegradeRule() *DegradeRule {
+	return &DegradeRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "order-slow-requests", Namespace: "sentinel-group"},
+		Spec: DegradeRuleSpec{
+			RuleTarget: RuleTarget{
+				DashboardRef: corev1.LocalObjectReference{Name: "sentinel-dashboard"},
+				App:          "order-service",
+			},
+			Resource:   "/orders",
+			Grade:      DegradeGradeSlowRequestRatio,
+			Count:      resource.MustParse("200"),
+			TimeWindow: 10,
+		},
+	}
+}
+
+var _ = Describe("DegradeRule webhook", func() {
+	threshold := resource.MustParse("0.5")
+
+	DescribeTable("ValidateCreate",
+		func(mutate func(*DegradeRule), field string) {
+			rule := newValidDegradeRule()
+			mutate(rule)
+			expectValidation(rule.ValidateCreate(), field)
+		},
+		Entry("accepts a valid rule", func(*DegradeRule) {}, ""),
+		Entry("rejects an error ratio over 1", func(rule *DegradeRule) {
+			rule.Spec.Grade = DegradeGradeErrorRatio
+			rule.Spec.Count = resource.MustParse("1.5")
+		}, "spec.count"),
+		Entry("accepts an error ratio below 1", func(rule *DegradeRule) {
+			rule.Spec.Grade = DegradeGradeErrorRatio
+			rule.Spec.Count = resource.MustParse("0.5")
+		}, ""),
+		Entry("accepts a slow ratio threshold with the SlowRequestRatio grade", func(rule *DegradeRule) {
+			rule.Spec.SlowRatioThreshold = &threshold
+		}, ""),
+		Entry("rejects a slow ratio threshold with another grade", func(rule *DegradeRule) {
+			rule.Spec.SlowRatioThreshold = &threshold
+			rule.Spec.Grade = DegradeGradeErrorCount
+		}, "spec.slowRatioThreshold"),
+	)
+
+	DescribeTable("ValidateUpdate",
+		func(mutate func(*DegradeRule), field string) {
+			old := newValidDegradeRule()
+			rule := old.DeepCopy()
+			mutate(rule)
+			expectValidation(rule.ValidateUpdate(old), field)
+		},
+		Entry("accepts changing the time window", func(rule *DegradeRule) {
+			rule.Spec.TimeWindow = 30
+		}, ""),
+		Entry("rejects moving the rule to another dashboard", func(rule *DegradeRule) {
+			rule.Spec.DashboardRef.Name = "other-dashboard"
+		}, "spec.dashboardRef"),
+	)
+})
@@ -0,0 +1,174 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FlowRuleSpec defines a Sentinel flow control rule of an app.
+type FlowRuleSpec struct {
+	RuleTarget `json:",inline"`
+
+	// Resource the rule applies to.
+	// +kubebuilder:validation:MinLength=1
+	Resource string `json:"resource"`
+
+	// Origin the rule applies to: default for any origin, other for the
+	// origins without a specific rule, or a comma separated list of origins.
+	// +kubebuilder:default=default
+	// +optional
+	LimitApp string `json:"limitApp,omitempty"`
+
+	// Metric the threshold applies to, the QPS or the number of concurrent threads.
+	// +kubebuilder:validation:Enum=QPS;Thread
+	// +kubebuilder:default=QPS
+	// +optional
+	Grade FlowGrade `json:"grade,omitempty"`
+
+	// Threshold of the rule.
+	Count resource.Quantity `json:"count"`
+
+	// How the metric is read: from the resource itself, from refResource, or
+	// from the calls to the resource entering through refResource.
+	// +kubebuilder:validation:Enum=Direct;Relate;Chain
+	// +kubebuilder:default=Direct
+	// +optional
+	Strategy FlowStrategy `json:"strategy,omitempty"`
+
+	// Related resource of the Relate strategy or entrance of the Chain strategy.
+	// +optional
+	RefResource string `json:"refResource,omitempty"`
+
+	// How the requests over the threshold are handled: rejected right away,
+	// after a warm up, queued at a constant rate, or both. Only the QPS
+	// grade supports another behavior than Default.
+	// +kubebuilder:validation:Enum=Default;WarmUp;RateLimiter;WarmUpRateLimiter
+	// +kubebuilder:default=Default
+	// +optional
+	ControlBehavior FlowControlBehavior `json:"controlBehavior,omitempty"`
+
+	// Warm up period of the WarmUp behaviors, in seconds. Defaults to 10.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	WarmUpPeriodSec *int32 `json:"warmUpPeriodSec,omitempty"`
+
+	// Maximum queueing time of the RateLimiter behaviors, in milliseconds. Defaults to 500.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxQueueingTimeMs *int32 `json:"maxQueueingTimeMs,omitempty"`
+
+	// Whether the threshold is checked by the cluster token server.
+	// +optional
+	ClusterMode bool `json:"clusterMode,omitempty"`
+
+	// Cluster settings of the rule. Only allowed in cluster mode.
+	// +optional
+	ClusterConfig *FlowClusterConfig `json:"clusterConfig,omitempty"`
+}
+
+// FlowGrade is the metric a flow rule limits.
+type FlowGrade string
+
+const (
+	FlowGradeQPS    FlowGrade = "QPS"
+	FlowGradeThread FlowGrade = "Thread"
+)
+
+// FlowStrategy is how a flow rule reads its metric.
+type FlowStrategy string
+
+const (
+	FlowStrategyDirect FlowStrategy = "Direct"
+	FlowStrategyRelate FlowStrategy = "Relate"
+	FlowStrategyChain  FlowStrategy = "Chain"
+)
+
+// FlowControlBehavior is how a flow rule handles the requests over its threshold.
+type FlowControlBehavior string
+
+const (
+	FlowControlBehaviorDefault           FlowControlBehavior = "Default"
+	FlowControlBehaviorWarmUp            FlowControlBehavior = "WarmUp"
+	FlowControlBehaviorRateLimiter       FlowControlBehavior = "RateLimiter"
+	FlowControlBehaviorWarmUpRateLimiter FlowControlBehavior = "WarmUpRateLimiter"
+)
+
+// FlowClusterConfig defines how a flow rule is checked in cluster mode.
+type FlowClusterConfig struct {
+	// Id of the rule on the token server, unique among all the apps sharing
+	// it. Defaults to an id derived from the uid of the FlowRule.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	FlowID *int64 `json:"flowId,omitempty"`
+
+	// Whether count is the threshold of the whole cluster or of each instance.
+	// +kubebuilder:validation:Enum=Global;AvgLocal
+	// +kubebuilder:default=AvgLocal
+	// +optional
+	ThresholdType ClusterThresholdType `json:"thresholdType,omitempty"`
+
+	// Whether the rule is checked locally when the token server cannot be
+	// reached. Defaults to true.
+	// +optional
+	FallbackToLocalWhenFail *bool `json:"fallbackToLocalWhenFail,omitempty"`
+}
+
+// ClusterThresholdType is the scope of the threshold of a cluster rule.
+type ClusterThresholdType string
+
+const (
+	ClusterThresholdGlobal   ClusterThresholdType = "Global"
+	ClusterThresholdAvgLocal ClusterThresholdType = "AvgLocal"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Dashboard",type=string,JSONPath=`.spec.dashboardRef.name`
+//+kubebuilder:printcolumn:name="App",type=string,JSONPath=`.spec.app`
+//+kubebuilder:printcolumn:name="Resource",type=string,JSONPath=`.spec.resource`
+//+kubebuilder:printcolumn:name="Published",type=string,JSONPath=`.status.conditions[?(@.type=="Published")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// FlowRule is the Schema for the flowrules API
+type FlowRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FlowRuleSpec `json:"spec,omitempty"`
+	Status RuleStatus   `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// FlowRuleList contains a list of FlowRule
+type FlowRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FlowRule `json:"items"`
+}
+
+var _ Rule = &FlowRule{}
+
+func (r *FlowRule) GetTarget() RuleTarget { return r.Spec.RuleTarget }
+
+func (r *FlowRule) GetRuleStatus() *RuleStatus { return &r.Status }
+
+func init() {
+	SchemeBuilder.Register(&FlowRule{}, &FlowRuleList{})
+}
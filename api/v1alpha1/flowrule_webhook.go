@@ -0,0 +1,113 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var flowrulelog = logf.Log.WithName("flowrule-resource")
+
+func (r *FlowRule) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-sentinel-sentinelguard-io-v1alpha1-flowrule,mutating=false,failurePolicy=fail,sideEffects=None,groups=sentinel.sentinelguard.io,resources=flowrules,verbs=create;update,versions=v1alpha1,name=vflowrule.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &FlowRule{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *FlowRule) ValidateCreate() error {
+	flowrulelog.Info("validate create", "name", r.Name)
+
+	return r.toInvalid(r.validateSpec())
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *FlowRule) ValidateUpdate(old runtime.Object) error {
+	flowrulelog.Info("validate update", "name", r.Name)
+
+	oldRule, ok := old.(*FlowRule)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a FlowRule but got a %T", old))
+	}
+
+	allErrs := r.validateSpec()
+	allErrs = append(allErrs, validateTargetUpdate(r.Spec.RuleTarget, oldRule.Spec.RuleTarget)...)
+	return r.toInvalid(allErrs)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *FlowRule) ValidateDelete() error {
+	return nil
+}
+
+func (r *FlowRule) toInvalid(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("FlowRule").GroupKind(), r.Name, allErrs)
+}
+
+func (r *FlowRule) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if r.Spec.Count.Sign() < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("count"), r.Spec.Count.String(), "must be greater than or equal to 0"))
+	}
+
+	switch r.Spec.Strategy {
+	case FlowStrategyRelate, FlowStrategyChain:
+		if r.Spec.RefResource == "" {
+			allErrs = append(allErrs, field.Required(specPath.Child("refResource"),
+				fmt.Sprintf("is required by the %s strategy", r.Spec.Strategy)))
+		}
+	default:
+		if r.Spec.RefResource != "" {
+			allErrs = append(allErrs, field.Forbidden(specPath.Child("refResource"), "is only allowed with the Relate and Chain strategies"))
+		}
+	}
+
+	behavior := r.Spec.ControlBehavior
+	if behavior != "" && behavior != FlowControlBehaviorDefault && r.Spec.Grade == FlowGradeThread {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("controlBehavior"), behavior, "requires the QPS grade"))
+	}
+	warmUp := behavior == FlowControlBehaviorWarmUp || behavior == FlowControlBehaviorWarmUpRateLimiter
+	if r.Spec.WarmUpPeriodSec != nil && !warmUp {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("warmUpPeriodSec"), "is only allowed with the WarmUp behaviors"))
+	}
+	rateLimiter := behavior == FlowControlBehaviorRateLimiter || behavior == FlowControlBehaviorWarmUpRateLimiter
+	if r.Spec.MaxQueueingTimeMs != nil && !rateLimiter {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("maxQueueingTimeMs"), "is only allowed with the RateLimiter behaviors"))
+	}
+
+	if r.Spec.ClusterConfig != nil && !r.Spec.ClusterMode {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("clusterConfig"), "is only allowed in cluster mode"))
+	}
+	return allErrs
+}
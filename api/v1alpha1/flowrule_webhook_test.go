@@ -0,0 +1,103 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newValidFlowRule() *FlowRule {
+	return &FlowRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "order-qps", Namespace: "sentinel-group"},
+		Spec: FlowRuleSpec{
+			RuleTarget: RuleTarget{
+				DashboardRef: corev1.LocalObjectReference{Name: "sentinel-dashboard"},
+				App:          "order-service",
+			},
+			Resource:        "/orders",
+			LimitApp:        "default",
+			Grade:           FlowGradeQPS,
+			Count:           resource.MustParse("100"),
+			Strategy:        FlowStrategyDirect,
+			ControlBehavior: FlowControlBehaviorDefault,
+		},
+	}
+}
+
+var _ = Describe("FlowRule webhook", func() {
+	period := int32(10)
+
+	DescribeTable("ValidateCreate",
+		func(mutate func(*FlowRule), field string) {
+			rule := newValidFlowRule()
+			mutate(rule)
+			expectValidation(rule.ValidateCreate(), field)
+		},
+		Entry("accepts a valid rule", func(*FlowRule) {}, ""),
+		Entry("rejects a negative count", func(rule *FlowRule) {
+			rule.Spec.Count = resource.MustParse("-1")
+		}, "spec.count"),
+		Entry("requires refResource with the Relate strategy", func(rule *FlowRule) {
+			rule.Spec.Strategy = FlowStrategyRelate
+		}, "spec.refResource"),
+		Entry("accepts refResource with the Relate strategy", func(rule *FlowRule) {
+			rule.Spec.Strategy = FlowStrategyRelate
+			rule.Spec.RefResource = "/payments"
+		}, ""),
+		Entry("rejects refResource with the Direct strategy", func(rule *FlowRule) {
+			rule.Spec.RefResource = "/payments"
+		}, "spec.refResource"),
+		Entry("rejects a control behavior on the Thread grade", func(rule *FlowRule) {
+			rule.Spec.Grade = FlowGradeThread
+			rule.Spec.ControlBehavior = FlowControlBehaviorRateLimiter
+		}, "spec.controlBehavior"),
+		Entry("rejects settings of another control behavior", func(rule *FlowRule) {
+			rule.Spec.ControlBehavior = FlowControlBehaviorRateLimiter
+			rule.Spec.WarmUpPeriodSec = &period
+		}, "spec.warmUpPeriodSec"),
+		Entry("accepts settings of the control behavior", func(rule *FlowRule) {
+			rule.Spec.ControlBehavior = FlowControlBehaviorWarmUpRateLimiter
+			rule.Spec.WarmUpPeriodSec = &period
+		}, ""),
+		Entry("rejects a cluster config out of cluster mode", func(rule *FlowRule) {
+			rule.Spec.ClusterConfig = &FlowClusterConfig{ThresholdType: ClusterThresholdGlobal}
+		}, "spec.clusterConfig"),
+		Entry("accepts a cluster config in cluster mode", func(rule *FlowRule) {
+			rule.Spec.ClusterConfig = &FlowClusterConfig{ThresholdType: ClusterThresholdGlobal}
+			rule.Spec.ClusterMode = true
+		}, ""),
+	)
+
+	DescribeTable("ValidateUpdate",
+		func(mutate func(*FlowRule), field string) {
+			old := newValidFlowRule()
+			rule := old.DeepCopy()
+			mutate(rule)
+			expectValidation(rule.ValidateUpdate(old), field)
+		},
+		Entry("accepts changing the threshold", func(rule *FlowRule) {
+			rule.Spec.Count = resource.MustParse("50")
+		}, ""),
+		Entry("rejects moving the rule to another app", func(rule *FlowRule) {
+			rule.Spec.App = "payment-service"
+		}, "spec.app"),
+	)
+})
@@ -0,0 +1,134 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ParamFlowRuleSpec defines a Sentinel hot parameter flow control rule of
+// an app, which limits the calls to a resource per value of one of its
+// parameters.
+type ParamFlowRuleSpec struct {
+	RuleTarget `json:",inline"`
+
+	// Resource the rule applies to.
+	// +kubebuilder:validation:MinLength=1
+	Resource string `json:"resource"`
+
+	// Index of the parameter of the resource the rule applies to.
+	// +kubebuilder:validation:Minimum=0
+	ParamIdx int32 `json:"paramIdx"`
+
+	// Metric the threshold applies to, the QPS or the number of concurrent threads.
+	// +kubebuilder:validation:Enum=QPS;Thread
+	// +kubebuilder:default=QPS
+	// +optional
+	Grade FlowGrade `json:"grade,omitempty"`
+
+	// Threshold of each parameter value.
+	Count resource.Quantity `json:"count"`
+
+	// How the requests over the threshold are handled: rejected right away
+	// or queued at a constant rate. Only the QPS grade supports RateLimiter.
+	// +kubebuilder:validation:Enum=Default;RateLimiter
+	// +kubebuilder:default=Default
+	// +optional
+	ControlBehavior FlowControlBehavior `json:"controlBehavior,omitempty"`
+
+	// Maximum queueing time of the RateLimiter behavior, in milliseconds. Defaults to 0.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxQueueingTimeMs *int32 `json:"maxQueueingTimeMs,omitempty"`
+
+	// Number of extra requests allowed in bursts.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	BurstCount int32 `json:"burstCount,omitempty"`
+
+	// Statistic window of the threshold, in seconds.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	// +optional
+	DurationInSec int64 `json:"durationInSec,omitempty"`
+
+	// Thresholds of specific parameter values, overriding count.
+	// +optional
+	ParamFlowItems []ParamFlowItem `json:"paramFlowItems,omitempty"`
+
+	// Whether the threshold is checked by the cluster token server.
+	// +optional
+	ClusterMode bool `json:"clusterMode,omitempty"`
+
+	// Cluster settings of the rule. Only allowed in cluster mode.
+	// +optional
+	ClusterConfig *FlowClusterConfig `json:"clusterConfig,omitempty"`
+}
+
+// ParamFlowItem defines the threshold of a specific parameter value.
+type ParamFlowItem struct {
+	// The parameter value.
+	Object string `json:"object"`
+
+	// Java type of the parameter.
+	// +kubebuilder:validation:Enum=int;long;double;float;char;byte;boolean;java.lang.String
+	// +kubebuilder:default=java.lang.String
+	// +optional
+	ClassType string `json:"classType,omitempty"`
+
+	// Threshold of the value.
+	// +kubebuilder:validation:Minimum=0
+	Count int32 `json:"count"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Dashboard",type=string,JSONPath=`.spec.dashboardRef.name`
+//+kubebuilder:printcolumn:name="App",type=string,JSONPath=`.spec.app`
+//+kubebuilder:printcolumn:name="Resource",type=string,JSONPath=`.spec.resource`
+//+kubebuilder:printcolumn:name="Param",type=integer,JSONPath=`.spec.paramIdx`
+//+kubebuilder:printcolumn:name="Published",type=string,JSONPath=`.status.conditions[?(@.type=="Published")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ParamFlowRule is the Schema for the paramflowrules API
+type ParamFlowRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ParamFlowRuleSpec `json:"spec,omitempty"`
+	Status RuleStatus        `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ParamFlowRuleList contains a list of ParamFlowRule
+type ParamFlowRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ParamFlowRule `json:"items"`
+}
+
+var _ Rule = &ParamFlowRule{}
+
+func (r *ParamFlowRule) GetTarget() RuleTarget { return r.Spec.RuleTarget }
+
+func (r *ParamFlowRule) GetRuleStatus() *RuleStatus { return &r.Status }
+
+func init() {
+	SchemeBuilder.Register(&ParamFlowRule{}, &ParamFlowRuleList{})
+}
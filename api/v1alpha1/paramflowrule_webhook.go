@@ -0,0 +1,106 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var paramflowrulelog = logf.Log.WithName("paramflowrule-resource")
+
+func (r *ParamFlowRule) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-sentinel-sentinelguard-io-v1alpha1-paramflowrule,mutating=false,failurePolicy=fail,sideEffects=None,groups=sentinel.sentinelguard.io,resources=paramflowrules,verbs=create;update,versions=v1alpha1,name=vparamflowrule.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &ParamFlowRule{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *ParamFlowRule) ValidateCreate() error {
+	paramflowrulelog.Info("validate create", "name", r.Name)
+
+	return r.toInvalid(r.validateSpec())
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *ParamFlowRule) ValidateUpdate(old runtime.Object) error {
+	paramflowrulelog.Info("validate update", "name", r.Name)
+
+	oldRule, ok := old.(*ParamFlowRule)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a ParamFlowRule but got a %T", old))
+	}
+
+	allErrs := r.validateSpec()
+	allErrs = append(allErrs, validateTargetUpdate(r.Spec.RuleTarget, oldRule.Spec.RuleTarget)...)
+	return r.toInvalid(allErrs)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *ParamFlowRule) ValidateDelete() error {
+	return nil
+}
+
+func (r *ParamFlowRule) toInvalid(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("ParamFlowRule").GroupKind(), r.Name, allErrs)
+}
+
+func (r *ParamFlowRule) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if r.Spec.Count.Sign() < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("count"), r.Spec.Count.String(), "must be greater than or equal to 0"))
+	}
+
+	behavior := r.Spec.ControlBehavior
+	if behavior == FlowControlBehaviorRateLimiter && r.Spec.Grade == FlowGradeThread {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("controlBehavior"), behavior, "requires the QPS grade"))
+	}
+	if r.Spec.MaxQueueingTimeMs != nil && behavior != FlowControlBehaviorRateLimiter {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("maxQueueingTimeMs"), "is only allowed with the RateLimiter behavior"))
+	}
+
+	itemsPath := specPath.Child("paramFlowItems")
+	seen := make(map[string]bool)
+	for i, item := range r.Spec.ParamFlowItems {
+		key := item.ClassType + "/" + item.Object
+		if seen[key] {
+			allErrs = append(allErrs, field.Duplicate(itemsPath.Index(i).Child("object"), item.Object))
+		}
+		seen[key] = true
+	}
+
+	if r.Spec.ClusterConfig != nil && !r.Spec.ClusterMode {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("clusterConfig"), "is only allowed in cluster mode"))
+	}
+	return allErrs
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newValidParamFlowRule() *ParamFlowRule {
+	return &ParamFlowRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "order-hot-users", Namespace: "sentinel-group"},
+		Spec: ParamFlowRuleSpec{
+			RuleTarget: RuleTarget{
+				DashboardRef: corev1.LocalObjectReference{Name: "sentinel-dashboard"},
+				App:          "order-service",
+			},
+			Resource: "/orders",
+			ParamIdx: 0,
+			Grade:    FlowGradeQPS,
+			Count:    resource.MustParse("10"),
+			ParamFlowItems: []ParamFlowItem{
+				{Object: "vip", ClassType: "java.lang.String", Count: 100},
+			},
+		},
+	}
+}
+
+var _ = Describe("ParamFlowRule webhook", func() {
+	queueing := int32(100)
+
+	DescribeTable("ValidateCreate",
+		func(mutate func(*ParamFlowRule), field string) {
+			rule := newValidParamFlowRule()
+			mutate(rule)
+			expectValidation(rule.ValidateCreate(), field)
+		},
+		Entry("accepts a valid rule", func(*ParamFlowRule) {}, ""),
+		Entry("rejects a duplicate parameter value", func(rule *ParamFlowRule) {
+			rule.Spec.ParamFlowItems = append(rule.Spec.ParamFlowItems,
+				ParamFlowItem{Object: "vip", ClassType: "java.lang.String", Count: 50})
+		}, "spec.paramFlowItems[1].object"),
+		Entry("rejects a queueing time without the RateLimiter behavior", func(rule *ParamFlowRule) {
+			rule.Spec.MaxQueueingTimeMs = &queueing
+		}, "spec.maxQueueingTimeMs"),
+		Entry("accepts a queueing time with the RateLimiter behavior", func(rule *ParamFlowRule) {
+			rule.Spec.MaxQueueingTimeMs = &queueing
+			rule.Spec.ControlBehavior = FlowControlBehaviorRateLimiter
+		}, ""),
+	)
+
+	DescribeTable("ValidateUpdate",
+		func(mutate func(*ParamFlowRule), field string) {
+			old := newValidParamFlowRule()
+			rule := old.DeepCopy()
+			mutate(rule)
+			expectValidation(rule.ValidateUpdate(old), field)
+		},
+		Entry("accepts changing the threshold", func(rule *ParamFlowRule) {
+			rule.Spec.Count = resource.MustParse("20")
+		}, ""),
+		Entry("rejects moving the rule to another dashboard", func(rule *ParamFlowRule) {
+			rule.Spec.DashboardRef.Name = "other-dashboard"
+		}, "spec.dashboardRef"),
+	)
+})
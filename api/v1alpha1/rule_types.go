@@ -0,0 +1,95 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Rule is implemented by the Sentinel rule kinds. The rules of every kind
+// targeting the same dashboard and app are published together, as the rule
+// set of the app, to the datasource of the dashboard.
+// +kubebuilder:object:generate=false
+type Rule interface {
+	client.Object
+	GetTarget() RuleTarget
+	GetRuleStatus() *RuleStatus
+}
+
+// RuleTarget defines the dashboard and app a rule is published to.
+type RuleTarget struct {
+	// Dashboard in the same namespace whose datasource the rule is published to.
+	DashboardRef corev1.LocalObjectReference `json:"dashboardRef"`
+
+	// Name of the app the rule applies to, project.name of the app.
+	// +kubebuilder:validation:MinLength=1
+	App string `json:"app"`
+}
+
+// RuleStatus defines the observed state of a rule.
+type RuleStatus struct {
+	// The generation observed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Key of the datasource the rules of the kind of the app are published to.
+	// +optional
+	DataID string `json:"dataId,omitempty"`
+
+	// Number of rules in the last rule set published for the app and kind.
+	// +optional
+	PublishedRules int32 `json:"publishedRules,omitempty"`
+
+	// Hash of the rule set of the app and kind last published to or adopted
+	// from the datasource. The datasource drifted when it holds another one.
+	// +optional
+	SyncedHash string `json:"syncedHash,omitempty"`
+
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// AppLabel is the label of the pods of an app holding its name, project.name
+// of the app.
+const AppLabel = "sentinel.sentinelguard.io/app"
+
+// PublishedConditionType reports whether the rule is part of the rule set
+// last published to the datasource.
+const PublishedConditionType = "Published"
+
+// DriftedConditionType reports whether the rule set of the app in the
+// datasource was changed outside of the rule resources and left as is.
+const DriftedConditionType = "Drifted"
+
+// validateTargetUpdate forbids moving a rule to another dashboard or app, as
+// the rule set it was published to would keep it.
+func validateTargetUpdate(target, old RuleTarget) field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	if target.DashboardRef.Name != old.DashboardRef.Name {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("dashboardRef"), "is immutable"))
+	}
+	if target.App != old.App {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("app"), "is immutable"))
+	}
+	return allErrs
+}
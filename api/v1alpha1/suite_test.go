@@ -0,0 +1,54 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// These tests use Ginkgo (BDD-style Go testing framework). Refer to
+// http://onsi.github.io/ginkgo/ to learn more about Ginkgo.
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "API Suite")
+}
+
+// expectInvalid expects err to be an Invalid error on field.
+func expectInvalid(err error, field string) {
+	ExpectWithOffset(1, err).To(HaveOccurred())
+	ExpectWithOffset(1, apierrors.IsInvalid(err)).To(BeTrue())
+	ExpectWithOffset(1, err.Error()).To(ContainSubstring(field))
+}
+
+// expectValidation expects err to be an Invalid error on field, or nil when
+// field is empty.
+func expectValidation(err error, field string) {
+	if field == "" {
+		ExpectWithOffset(1, err).NotTo(HaveOccurred())
+		return
+	}
+	ExpectWithOffset(1, err).To(HaveOccurred())
+	ExpectWithOffset(1, apierrors.IsInvalid(err)).To(BeTrue())
+	ExpectWithOffset(1, err.Error()).To(ContainSubstring(field))
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SystemRuleSpec defines a Sentinel system adaptive protection rule of an
+// app. It limits the inbound traffic of the whole app, and at least one
+// threshold must be set. When several rules of an app set the same
+// threshold, the oldest rule is published.
+type SystemRuleSpec struct {
+	RuleTarget `json:",inline"`
+
+	// Maximum system load1 of the host, checked together with the
+	// concurrency the app can sustain.
+	// +optional
+	HighestSystemLoad *resource.Quantity `json:"highestSystemLoad,omitempty"`
+
+	// Maximum CPU usage of the host, between 0 and 1.
+	// +optional
+	HighestCPUUsage *resource.Quantity `json:"highestCpuUsage,omitempty"`
+
+	// Maximum QPS of the inbound traffic.
+	// +optional
+	QPS *resource.Quantity `json:"qps,omitempty"`
+
+	// Maximum average response time of the inbound traffic, in milliseconds.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	AvgRT *int64 `json:"avgRt,omitempty"`
+
+	// Maximum number of concurrent inbound threads.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxThread *int64 `json:"maxThread,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Dashboard",type=string,JSONPath=`.spec.dashboardRef.name`
+//+kubebuilder:printcolumn:name="App",type=string,JSONPath=`.spec.app`
+//+kubebuilder:printcolumn:name="Published",type=string,JSONPath=`.status.conditions[?(@.type=="Published")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SystemRule is the Schema for the systemrules API
+type SystemRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SystemRuleSpec `json:"spec,omitempty"`
+	Status RuleStatus     `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SystemRuleList contains a list of SystemRule
+type SystemRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SystemRule `json:"items"`
+}
+
+var _ Rule = &SystemRule{}
+
+func (r *SystemRule) GetTarget() RuleTarget { return r.Spec.RuleTarget }
+
+func (r *SystemRule) GetRuleStatus() *RuleStatus { return &r.Status }
+
+func init() {
+	SchemeBuilder.Register(&SystemRule{}, &SystemRuleList{})
+}
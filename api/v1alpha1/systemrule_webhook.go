@@ -0,0 +1,98 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var systemrulelog = logf.Log.WithName("systemrule-resource")
+
+func (r *SystemRule) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-sentinel-sentinelguard-io-v1alpha1-systemrule,mutating=false,failurePolicy=fail,sideEffects=None,groups=sentinel.sentinelguard.io,resources=systemrules,verbs=create;update,versions=v1alpha1,name=vsystemrule.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &SystemRule{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *SystemRule) ValidateCreate() error {
+	systemrulelog.Info("validate create", "name", r.Name)
+
+	return r.toInvalid(r.validateSpec())
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *SystemRule) ValidateUpdate(old runtime.Object) error {
+	systemrulelog.Info("validate update", "name", r.Name)
+
+	oldRule, ok := old.(*SystemRule)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a SystemRule but got a %T", old))
+	}
+
+	allErrs := r.validateSpec()
+	allErrs = append(allErrs, validateTargetUpdate(r.Spec.RuleTarget, oldRule.Spec.RuleTarget)...)
+	return r.toInvalid(allErrs)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *SystemRule) ValidateDelete() error {
+	return nil
+}
+
+func (r *SystemRule) toInvalid(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("SystemRule").GroupKind(), r.Name, allErrs)
+}
+
+func (r *SystemRule) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if r.Spec.HighestSystemLoad == nil && r.Spec.HighestCPUUsage == nil && r.Spec.QPS == nil &&
+		r.Spec.AvgRT == nil && r.Spec.MaxThread == nil {
+		allErrs = append(allErrs, field.Required(specPath,
+			"at least one of highestSystemLoad, highestCpuUsage, qps, avgRt or maxThread is required"))
+	}
+	for name, value := range map[string]*resource.Quantity{
+		"highestSystemLoad": r.Spec.HighestSystemLoad,
+		"qps":               r.Spec.QPS,
+	} {
+		if value != nil && value.Sign() < 0 {
+			allErrs = append(allErrs, field.Invalid(specPath.Child(name), value.String(), "must be greater than or equal to 0"))
+		}
+	}
+	if usage := r.Spec.HighestCPUUsage; usage != nil && (usage.Sign() < 0 || usage.Cmp(resource.MustParse("1")) > 0) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("highestCpuUsage"), usage.String(), "must be a ratio between 0 and 1"))
+	}
+	return allErrs
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newValidSystemRule() *SystemRule {
+	cpuUsage := resource.MustParse("0.8")
+	return &SystemRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "order-service-load", Namespace: "sentinel-group"},
+		Spec: SystemRuleSpec{
+			RuleTarget: RuleTarget{
+				DashboardRef: corev1.LocalObjectReference{Name: "sentinel-dashboard"},
+				App:          "order-service",
+			},
+			HighestCPUUsage: &cpuUsage,
+		},
+	}
+}
+
+var _ = Describe("SystemRule webhook", func() {
+	DescribeTable("ValidateCreate",
+		func(mutate func(*SystemRule), field string) {
+			rule := newValidSystemRule()
+			mutate(rule)
+			expectValidation(rule.ValidateCreate(), field)
+		},
+		Entry("accepts a valid rule", func(*SystemRule) {}, ""),
+		Entry("requires a threshold", func(rule *SystemRule) {
+			rule.Spec.HighestCPUUsage = nil
+		}, "spec"),
+		Entry("rejects a cpu usage over 1", func(rule *SystemRule) {
+			usage := resource.MustParse("80")
+			rule.Spec.HighestCPUUsage = &usage
+		}, "spec.highestCpuUsage"),
+	)
+
+	DescribeTable("ValidateUpdate",
+		func(mutate func(*SystemRule), field string) {
+			old := newValidSystemRule()
+			rule := old.DeepCopy()
+			mutate(rule)
+			expectValidation(rule.ValidateUpdate(old), field)
+		},
+		Entry("accepts changing the threshold", func(rule *SystemRule) {
+			usage := resource.MustParse("0.9")
+			rule.Spec.HighestCPUUsage = &usage
+		}, ""),
+		Entry("rejects moving the rule to another dashboard", func(rule *SystemRule) {
+			rule.Spec.DashboardRef.Name = "other-dashboard"
+		}, "spec.dashboardRef"),
+	)
+})
@@ -0,0 +1,141 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TokenServerSpec defines a standalone Sentinel cluster flow control token
+// server, and the apps it checks the cluster rules of.
+type TokenServerSpec struct {
+	// Dashboard in the same namespace the token server reports to. The
+	// assignment of the clients to the token server is published to its
+	// datasource, as the cluster map of every app.
+	DashboardRef corev1.LocalObjectReference `json:"dashboardRef"`
+
+	// Apps the token server checks the cluster rules of. The pods of an app
+	// are the pods of the namespace labelled sentinel.sentinelguard.io/app
+	// with the app name, and are assigned to the token server as clients.
+	// +kubebuilder:validation:MinItems=1
+	Apps []string `json:"apps"`
+
+	// Selects the client pods among the pods of the apps. Defaults to all of them.
+	// +optional
+	ClientSelector *metav1.LabelSelector `json:"clientSelector,omitempty"`
+
+	// Port the clients serve the Sentinel transport API on, which identifies
+	// them in the cluster map, for the client pods not declaring it as their
+	// sentinel-api container port, as the injected pods do. Defaults to 8719.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	ClientPort int32 `json:"clientPort,omitempty"`
+
+	// Container image of the token server. No image is provided by default:
+	// the image runs a Sentinel cluster token server configured by the env
+	// set by the operator. SENTINEL_TOKEN_SERVER_NAMESPACES lists the apps it
+	// serves, comma separated, and SENTINEL_TOKEN_SERVER_PORT the port it
+	// listens on. The datasource env of the dashboard, e.g. NACOS_ADDRESS,
+	// locates the cluster rules of the apps, and JAVA_TOOL_OPTIONS reports the
+	// token server to the dashboard, serving the transport API on port 8719.
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+
+	// Port the token server listens on. Defaults to 18730.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// ImagePullSecrets is an optional list of references to secrets in the
+	// same namespace to use for pulling the image.
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// List of environment variables to set in the container. A variable
+	// replaces the one rendered by the operator with the same name.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Compute Resources required by this container.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// TokenServerStatus defines the observed state of TokenServer
+type TokenServerStatus struct {
+	// The generation observed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Address the clients connect to, in host:port form.
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// Number of ready pods of the token server.
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// Number of client pods assigned to the token server.
+	// +optional
+	Clients int32 `json:"clients,omitempty"`
+
+	// Apps whose cluster map is published to the datasource.
+	// +optional
+	PublishedApps []string `json:"publishedApps,omitempty"`
+
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// TokenServerReadyConditionType reports whether the token server is running.
+const TokenServerReadyConditionType = "Ready"
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Dashboard",type=string,JSONPath=`.spec.dashboardRef.name`
+//+kubebuilder:printcolumn:name="Address",type=string,JSONPath=`.status.address`
+//+kubebuilder:printcolumn:name="Clients",type=integer,JSONPath=`.status.clients`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Published",type=string,JSONPath=`.status.conditions[?(@.type=="Published")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// TokenServer is the Schema for the tokenservers API
+type TokenServer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TokenServerSpec   `json:"spec,omitempty"`
+	Status TokenServerStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// TokenServerList contains a list of TokenServer
+type TokenServerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TokenServer `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TokenServer{}, &TokenServerList{})
+}
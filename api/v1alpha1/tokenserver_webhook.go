@@ -0,0 +1,138 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+const (
+	// DefaultTokenServerPort is the port the token server listens on unless spec.port says otherwise.
+	DefaultTokenServerPort = 18730
+
+	// DefaultTransportPort is the port the Sentinel clients serve the transport API on.
+	DefaultTransportPort = 8719
+)
+
+// log is for logging in this package.
+var tokenserverlog = logf.Log.WithName("tokenserver-resource")
+
+func (r *TokenServer) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-sentinel-sentinelguard-io-v1alpha1-tokenserver,mutating=true,failurePolicy=fail,sideEffects=None,groups=sentinel.sentinelguard.io,resources=tokenservers,verbs=create;update,versions=v1alpha1,name=mtokenserver.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &TokenServer{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *TokenServer) Default() {
+	tokenserverlog.Info("default", "name", r.Name)
+
+	if r.Spec.Port == 0 {
+		r.Spec.Port = DefaultTokenServerPort
+	}
+	if r.Spec.ClientPort == 0 {
+		r.Spec.ClientPort = DefaultTransportPort
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-sentinel-sentinelguard-io-v1alpha1-tokenserver,mutating=false,failurePolicy=fail,sideEffects=None,groups=sentinel.sentinelguard.io,resources=tokenservers,verbs=create;update,versions=v1alpha1,name=vtokenserver.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &TokenServer{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *TokenServer) ValidateCreate() error {
+	tokenserverlog.Info("validate create", "name", r.Name)
+
+	return r.toInvalid(r.validateSpec())
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *TokenServer) ValidateUpdate(old runtime.Object) error {
+	tokenserverlog.Info("validate update", "name", r.Name)
+
+	oldServer, ok := old.(*TokenServer)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a TokenServer but got a %T", old))
+	}
+
+	allErrs := r.validateSpec()
+	if r.Spec.DashboardRef.Name != oldServer.Spec.DashboardRef.Name {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "dashboardRef"), "is immutable"))
+	}
+	return r.toInvalid(allErrs)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *TokenServer) ValidateDelete() error {
+	return nil
+}
+
+func (r *TokenServer) toInvalid(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("TokenServer").GroupKind(), r.Name, allErrs)
+}
+
+func (r *TokenServer) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if r.Spec.Image == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("image"), ""))
+	}
+
+	appsPath := specPath.Child("apps")
+	if len(r.Spec.Apps) == 0 {
+		allErrs = append(allErrs, field.Required(appsPath, "at least one app is required"))
+	}
+	seen := make(map[string]bool, len(r.Spec.Apps))
+	for i, app := range r.Spec.Apps {
+		switch {
+		case app == "":
+			allErrs = append(allErrs, field.Required(appsPath.Index(i), ""))
+		case strings.ContainsAny(app, ", \t\n"):
+			allErrs = append(allErrs, field.Invalid(appsPath.Index(i), app, "must not contain commas or whitespace"))
+		case seen[app]:
+			allErrs = append(allErrs, field.Duplicate(appsPath.Index(i), app))
+		}
+		seen[app] = true
+	}
+
+	if r.Spec.ClientSelector != nil {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(r.Spec.ClientSelector, specPath.Child("clientSelector"))...)
+	}
+
+	if r.Spec.Port == DefaultTransportPort {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("port"), r.Spec.Port,
+			fmt.Sprintf("must not be %d, the port the token server serves the transport API on", DefaultTransportPort)))
+	}
+	return allErrs
+}
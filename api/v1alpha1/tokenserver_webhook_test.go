@@ -0,0 +1,100 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newValidTokenServer() *TokenServer {
+	return &TokenServer{
+		ObjectMeta: metav1.ObjectMeta{Name: "token-server", Namespace: "sentinel-group"},
+		Spec: TokenServerSpec{
+			DashboardRef: corev1.LocalObjectReference{Name: "sentinel-dashboard"},
+			Apps:         []string{"order-service", "payment-service"},
+			Image:        "registry.example.com/sentinel-token-server:1.8.6",
+		},
+	}
+}
+
+var _ = Describe("TokenServer webhook", func() {
+	Context("Default", func() {
+		It("defaults the ports", func() {
+			server := newValidTokenServer()
+			server.Default()
+			Expect(server.Spec.Port).To(Equal(int32(DefaultTokenServerPort)))
+			Expect(server.Spec.ClientPort).To(Equal(int32(DefaultTransportPort)))
+		})
+
+		It("does not default the image", func() {
+			server := newValidTokenServer()
+			server.Spec.Image = ""
+			server.Default()
+			Expect(server.Spec.Image).To(BeEmpty())
+		})
+	})
+
+	DescribeTable("ValidateCreate",
+		func(mutate func(*TokenServer), field string) {
+			server := newValidTokenServer()
+			server.Default()
+			mutate(server)
+			expectValidation(server.ValidateCreate(), field)
+		},
+		Entry("accepts a valid token server", func(*TokenServer) {}, ""),
+		Entry("requires an image", func(server *TokenServer) {
+			server.Spec.Image = ""
+		}, "spec.image"),
+		Entry("requires an app", func(server *TokenServer) {
+			server.Spec.Apps = nil
+		}, "spec.apps"),
+		Entry("rejects duplicate apps", func(server *TokenServer) {
+			server.Spec.Apps = []string{"order-service", "order-service"}
+		}, "spec.apps[1]"),
+		Entry("rejects malformed apps", func(server *TokenServer) {
+			server.Spec.Apps = []string{"order-service,payment-service"}
+		}, "spec.apps[0]"),
+		Entry("rejects an invalid client selector", func(server *TokenServer) {
+			server.Spec.ClientSelector = &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "tier", Operator: metav1.LabelSelectorOpIn},
+			}}
+		}, "spec.clientSelector"),
+		Entry("rejects the transport port", func(server *TokenServer) {
+			server.Spec.Port = DefaultTransportPort
+		}, "spec.port"),
+	)
+
+	DescribeTable("ValidateUpdate",
+		func(mutate func(*TokenServer), field string) {
+			old := newValidTokenServer()
+			old.Default()
+			server := old.DeepCopy()
+			mutate(server)
+			expectValidation(server.ValidateUpdate(old), field)
+		},
+		Entry("accepts changing the apps", func(server *TokenServer) {
+			server.Spec.Apps = append(server.Spec.Apps, "stock-service")
+		}, ""),
+		Entry("rejects moving the token server to another dashboard", func(server *TokenServer) {
+			server.Spec.DashboardRef.Name = "other-dashboard"
+		}, "spec.dashboardRef"),
+	)
+})
@@ -18,21 +18,32 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
 	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/event"
@@ -44,13 +55,39 @@ type DashboardReconciler struct {
 	Scheme     *runtime.Scheme
 	RestConfig *rest.Config
 	Recorder   record.EventRecorder
+
+	// DefaultImage is the image spec.image defaults to.
+	DefaultImage string
+
+	// HealthCheckers checks the health of the dashboards by spec.healthCheck.method.
+	// SetupWithManager fills in the built-in checkers when it is nil.
+	HealthCheckers map[sentinelv1alpha1.HealthCheckMethod]HealthChecker
+
+	// APIReader reads from the API server the objects the manager does not
+	// cache: the Secrets, whose data it does not cache, and the pods of the
+	// dashboards, which lack the app label the Pod cache is restricted to.
+	// SetupWithManager sets the API reader of the manager when it is nil.
+	APIReader client.Reader
+
+	proxy *serviceProxy
 }
 
 //+kubebuilder:rbac:groups=sentinel.sentinelguard.io,resources=dashboards,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=sentinel.sentinelguard.io,resources=dashboards/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=sentinel.sentinelguard.io,resources=dashboards/finalizers,verbs=update
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=service,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -74,25 +111,51 @@ func (r *DashboardReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		logger.Error(err, "failed to get sentinel instance")
 		return ctrl.Result{}, err
 	}
+	if err := r.EnsureFinalizer(ctx, &instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	// the defaults are only persisted by the mutating webhook, which may be disabled
+	instance.SetDefaults(r.DefaultImage)
+
+	if !instance.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.Finalize(ctx, &instance)
+	}
 
 	var g errgroup.Group
 	var err error
+	var version string
+	var healthErr error
 	g.Go(func() error {
 		err = r.UpdateAppliedStatus(ctx, &instance)
 		return errors.Wrapf(err, "type=%s", sentinelv1alpha1.AppliedConditionType)
 	})
 	g.Go(func() error {
-		err = r.UpdateReadyStatus(ctx, &instance)
-		return errors.Wrapf(err, "type=%s", sentinelv1alpha1.ReadyConditionType)
+		// the result is recorded after Wait so that only one goroutine touches the status
+		version, healthErr = r.GetHealth(ctx, &instance)
+		return nil
 	})
 	err = g.Wait()
-	if err != nil {
-		return ctrl.Result{}, errors.Wrap(err, "failed set status")
+	requeueAfter, readyErr := r.UpdateReadyStatus(ctx, &instance, version, healthErr)
+	if readyErr != nil {
+		return ctrl.Result{}, errors.Wrapf(readyErr, "type=%s", sentinelv1alpha1.ReadyConditionType)
+	}
+	if observedErr := r.UpdateObservedStatus(ctx, &instance); observedErr != nil {
+		return ctrl.Result{}, errors.Wrap(observedErr, "failed updating observed status")
+	}
+	if phaseErr := r.UpdatePhase(ctx, &instance); phaseErr != nil {
+		return ctrl.Result{}, errors.Wrap(phaseErr, "failed updating phase")
 	}
 
-	err = r.UpdateStatus(ctx, &instance)
+	// persist the phase and conditions observed so far before backing off
+	if statusErr := r.UpdateStatus(ctx, &instance); statusErr != nil {
+		return ctrl.Result{}, statusErr
+	}
 	if err != nil {
-		return ctrl.Result{}, err
+		return ctrl.Result{}, errors.Wrap(err, "failed set status")
+	}
+	if requeueAfter > 0 {
+		logger.Info("end reconcile, waiting for the dashboard to become ready", "requeueAfter", requeueAfter)
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
 	}
 	logger.Info("end reconcile")
 
@@ -105,26 +168,81 @@ func (r *DashboardReconciler) UpdateAppliedStatus(ctx context.Context, instance
 	logger := log.FromContext(ctx)
 	switch r.GetCondition(ctx, instance, sentinelv1alpha1.AppliedConditionType).Status {
 	default:
-		var deploy appsv1.Deployment
-		deploy.Name = instance.Name
-		deploy.Namespace = instance.Namespace
-		if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-			result, err := controllerutil.CreateOrUpdate(ctx, r.Client, &deploy, func() error {
-				MutateDeployment(instance, &deploy)
-				return controllerutil.SetControllerReference(instance, &deploy, r.Scheme)
-			})
-			if err == nil {
-				logger.Info("succeed updated deployment", "result", result, "deployment name", deploy.Name, "deployment namespace", deploy.Namespace)
+		if len(instance.Spec.Ports) == 0 {
+			// the validating webhook rejects such dashboards, but it may be disabled
+			return errors.Wrapf(r.UpdateCondition(ctx, instance, sentinelv1alpha1.AppliedConditionType, metav1.ConditionFalse,
+				"InvalidSpec", "spec.ports must not be empty"), "failed updating conditions")
+		}
+
+		checksum, err := r.UpdateDatasourceStatus(ctx, instance)
+		if errors.Is(err, errInvalidDatasource) {
+			// the CRD rejects such datasources, but the schema may be bypassed or outdated
+			return errors.Wrapf(r.UpdateCondition(ctx, instance, sentinelv1alpha1.AppliedConditionType, metav1.ConditionFalse,
+				"InvalidDatasource", r.GetCondition(ctx, instance, sentinelv1alpha1.DatasourceConditionType).Message),
+				"failed updating conditions")
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed resolving datasource")
+		}
+
+		var sa corev1.ServiceAccount
+		sa.Name = serviceAccountName(instance)
+		sa.Namespace = instance.Namespace
+		if instance.Spec.CreateServiceAccount {
+			if ok, err := r.applyOwned(ctx, instance, &sa, func() {
+				MutateServiceAccount(instance, &sa)
+			}); !ok || err != nil {
+				return err
+			}
+		} else if sa.Name != "" {
+			if ok, err := r.deleteOwned(ctx, instance, &sa); !ok || err != nil {
+				return err
 			}
+		}
+
+		// the old workload releases its volume before the new one is created
+		workload, stale := newWorkloads(instance)
+		if ok, err := r.deleteOwned(ctx, instance, stale); !ok || err != nil {
 			return err
-		}); err != nil {
-			condErr := r.UpdateCondition(ctx, instance, sentinelv1alpha1.AppliedConditionType, metav1.ConditionFalse, "MutateDeployment", err.Error())
-			if condErr != nil {
-				return errors.Wrapf(err, "failed updating deployment")
+		}
+		if instance.Spec.Storage != nil && workloadKind(instance) == sentinelv1alpha1.WorkloadKindDeployment {
+			var pvc corev1.PersistentVolumeClaim
+			pvc.Name = dataClaimName(instance)
+			pvc.Namespace = instance.Namespace
+			if ok, err := r.applyOwned(ctx, instance, &pvc, func() {
+				MutatePersistentVolumeClaim(instance, &pvc)
+			}); !ok || err != nil {
+				return err
 			}
-			r.Recorder.Eventf(instance, corev1.EventTypeWarning,
-				string(event.DashboardApplied), "Deployment %s applied failed", deploy.Namespace+"/"+deploy.Name)
-			return nil
+		}
+		var headless corev1.Service
+		headless.Name = headlessServiceName(instance)
+		headless.Namespace = instance.Namespace
+		if workloadKind(instance) == sentinelv1alpha1.WorkloadKindStatefulSet {
+			if ok, err := r.applyOwned(ctx, instance, &headless, func() {
+				MutateHeadlessService(instance, &headless)
+			}); !ok || err != nil {
+				return err
+			}
+		} else if ok, err := r.deleteOwned(ctx, instance, &headless); !ok || err != nil {
+			return err
+		}
+		if sts, ok := workload.(*appsv1.StatefulSet); ok {
+			if err := r.Get(ctx, client.ObjectKeyFromObject(sts), sts); client.IgnoreNotFound(err) != nil {
+				return errors.Wrapf(err, "failed getting statefulset")
+			}
+			if statefulSetMissesDataClaim(instance, sts) {
+				logger.Info("recreating statefulset to add the data volume claim", "name", sts.Name, "namespace", sts.Namespace)
+				if ok, err := r.deleteOwned(ctx, instance, sts); !ok || err != nil {
+					return err
+				}
+				workload, _ = newWorkloads(instance)
+			}
+		}
+		if ok, err := r.applyOwned(ctx, instance, workload, func() {
+			mutateWorkload(instance, workload, checksum)
+		}); !ok || err != nil {
+			return err
 		}
 
 		var svc corev1.Service
@@ -150,7 +268,62 @@ func (r *DashboardReconciler) UpdateAppliedStatus(ctx context.Context, instance
 			return nil
 		}
 
-		err := r.UpdateCondition(ctx, instance, sentinelv1alpha1.AppliedConditionType, metav1.ConditionTrue)
+		var hpa autoscalingv2.HorizontalPodAutoscaler
+		hpa.Name = instance.Name
+		hpa.Namespace = instance.Namespace
+		if instance.Spec.Autoscaling != nil {
+			if ok, err := r.applyOwned(ctx, instance, &hpa, func() {
+				MutateHorizontalPodAutoscaler(instance, &hpa)
+			}); !ok || err != nil {
+				return err
+			}
+		} else if ok, err := r.deleteOwned(ctx, instance, &hpa); !ok || err != nil {
+			return err
+		}
+
+		var pdb policyv1.PodDisruptionBudget
+		pdb.Name = instance.Name
+		pdb.Namespace = instance.Namespace
+		if needsPodDisruptionBudget(instance) {
+			if ok, err := r.applyOwned(ctx, instance, &pdb, func() {
+				MutatePodDisruptionBudget(instance, &pdb)
+			}); !ok || err != nil {
+				return err
+			}
+		} else if ok, err := r.deleteOwned(ctx, instance, &pdb); !ok || err != nil {
+			return err
+		}
+
+		var ing networkingv1.Ingress
+		ing.Name = instance.Name
+		ing.Namespace = instance.Namespace
+		if instance.Spec.Ingress != nil {
+			if ok, err := r.applyOwned(ctx, instance, &ing, func() {
+				MutateIngress(instance, &ing)
+			}); !ok || err != nil {
+				return err
+			}
+		} else if ok, err := r.deleteOwned(ctx, instance, &ing); !ok || err != nil {
+			return err
+		}
+
+		// the route is skipped when the Gateway API is not installed, see UpdateRouteStatus
+		if gvk, ok, err := httpRouteGVK(r.RESTMapper()); err != nil {
+			return errors.Wrapf(err, "failed discovering httproute")
+		} else if ok {
+			route := newHTTPRoute(instance, gvk)
+			if instance.Spec.GatewayRoute != nil {
+				if ok, err := r.applyOwned(ctx, instance, route, func() {
+					MutateHTTPRoute(instance, route)
+				}); !ok || err != nil {
+					return err
+				}
+			} else if ok, err := r.deleteOwned(ctx, instance, route); !ok || err != nil {
+				return err
+			}
+		}
+
+		err = r.UpdateCondition(ctx, instance, sentinelv1alpha1.AppliedConditionType, metav1.ConditionTrue)
 		if err != nil {
 			return errors.Wrapf(err, "failed updating conditions")
 		}
@@ -162,26 +335,159 @@ func (r *DashboardReconciler) UpdateAppliedStatus(ctx context.Context, instance
 	return nil
 }
 
-func (r *DashboardReconciler) UpdateReadyStatus(ctx context.Context, instance *sentinelv1alpha1.Dashboard) error {
+// applyOwned creates or updates obj as controlled by instance. When that
+// fails it marks the dashboard as not applied, records a warning event and
+// returns false.
+func (r *DashboardReconciler) applyOwned(ctx context.Context, instance *sentinelv1alpha1.Dashboard,
+	obj client.Object, mutate func()) (bool, error) {
 	logger := log.FromContext(ctx)
-	if err := r.GetHealth(ctx, instance); err != nil {
-		if err = r.UpdateCondition(ctx, instance, sentinelv1alpha1.ReadyConditionType, metav1.ConditionFalse, errors.Cause(err).Error(), err.Error()); err != nil {
-			return errors.Wrapf(err, "failed updating conditions")
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return false, err
+	}
+
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		result, err := controllerutil.CreateOrUpdate(ctx, r.Client, obj, func() error {
+			mutate()
+			return controllerutil.SetControllerReference(instance, obj, r.Scheme)
+		})
+		if err == nil {
+			logger.Info("succeed updated "+strings.ToLower(gvk.Kind), "result", result, "name", obj.GetName(), "namespace", obj.GetNamespace())
+		}
+		return err
+	}); err != nil {
+		condErr := r.UpdateCondition(ctx, instance, sentinelv1alpha1.AppliedConditionType, metav1.ConditionFalse, "Mutate"+gvk.Kind, err.Error())
+		if condErr != nil {
+			return false, errors.Wrapf(err, "failed updating %s", strings.ToLower(gvk.Kind))
 		}
-		logger.Info("maybe not ready, trying again later")
 		r.Recorder.Eventf(instance, corev1.EventTypeWarning,
-			string(event.DashboardReady), "Dashboard %s health check failed", instance.Namespace+"/"+instance.Name)
-		return errors.Wrapf(err, "not health")
+			string(event.DashboardApplied), "%s %s applied failed", gvk.Kind, obj.GetNamespace()+"/"+obj.GetName())
+		return false, nil
 	}
+	return true, nil
+}
 
-	if err := r.UpdateCondition(ctx, instance, sentinelv1alpha1.ReadyConditionType, metav1.ConditionTrue); err != nil {
-		return errors.Wrapf(err, "failed updating conditions")
+// deleteOwned deletes obj if it exists and is controlled by instance. When
+// that fails it marks the dashboard as not applied, records a warning event
+// and returns false.
+func (r *DashboardReconciler) deleteOwned(ctx context.Context, instance *sentinelv1alpha1.Dashboard,
+	obj client.Object) (bool, error) {
+	logger := log.FromContext(ctx)
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return false, err
 	}
 
-	r.Recorder.Eventf(instance, corev1.EventTypeNormal,
-		string(event.DashboardReady), "Dashboard %s is ready", instance.Namespace+"/"+instance.Name)
+	err = r.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+	if err == nil {
+		if !metav1.IsControlledBy(obj, instance) {
+			return true, nil
+		}
+		err = r.Delete(ctx, obj)
+	}
+	if apierrors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		condErr := r.UpdateCondition(ctx, instance, sentinelv1alpha1.AppliedConditionType, metav1.ConditionFalse, "Delete"+gvk.Kind, err.Error())
+		if condErr != nil {
+			return false, errors.Wrapf(err, "failed deleting %s", strings.ToLower(gvk.Kind))
+		}
+		r.Recorder.Eventf(instance, corev1.EventTypeWarning,
+			string(event.DashboardApplied), "%s %s deleted failed", gvk.Kind, obj.GetNamespace()+"/"+obj.GetName())
+		return false, nil
+	}
 
-	return nil
+	logger.Info("succeed deleted "+strings.ToLower(gvk.Kind), "name", obj.GetName(), "namespace", obj.GetNamespace())
+	return true, nil
+}
+
+// UpdateReadyStatus records the result of the health check in the Ready
+// condition. While the dashboard is not ready it returns the delay after
+// which to check again; the delay doubles with the time spent waiting, and
+// once that exceeds the readiness timeout the condition reason becomes
+// ReadinessTimeout, which marks the dashboard as Failed. The wait restarts
+// when the generation of the dashboard changes, as that rolls out new pods.
+func (r *DashboardReconciler) UpdateReadyStatus(ctx context.Context, instance *sentinelv1alpha1.Dashboard,
+	version string, healthErr error) (time.Duration, error) {
+	logger := log.FromContext(ctx)
+	previous := r.GetCondition(ctx, instance, sentinelv1alpha1.ReadyConditionType)
+	if healthErr == nil {
+		instance.Status.Version = version
+		if err := r.UpdateCondition(ctx, instance, sentinelv1alpha1.ReadyConditionType, metav1.ConditionTrue); err != nil {
+			return 0, errors.Wrapf(err, "failed updating conditions")
+		}
+		if previous.Status != metav1.ConditionTrue {
+			r.Recorder.Eventf(instance, corev1.EventTypeNormal,
+				string(event.DashboardReady), "Dashboard %s is ready", instance.Namespace+"/"+instance.Name)
+		}
+		return 0, nil
+	}
+
+	now := time.Now()
+	waitingSince := now
+	if previous.ObservedGeneration != instance.Generation {
+		// the condition is recreated so that its transition time starts the new wait
+		r.RemoveCondition(ctx, instance, sentinelv1alpha1.ReadyConditionType)
+	} else if previous.Status == metav1.ConditionFalse && !previous.LastTransitionTime.IsZero() {
+		waitingSince = previous.LastTransitionTime.Time
+	}
+	waited := now.Sub(waitingSince)
+	timeout := readinessTimeout(instance)
+
+	reason, message := "HealthCheckFailed", healthErr.Error()
+	if waited >= timeout {
+		reason = readinessTimeoutReason
+		message = fmt.Sprintf("not ready after %s: %s", timeout, message)
+	}
+	if err := r.UpdateCondition(ctx, instance, sentinelv1alpha1.ReadyConditionType, metav1.ConditionFalse, reason, message); err != nil {
+		return 0, errors.Wrapf(err, "failed updating conditions")
+	}
+
+	switch {
+	case reason == readinessTimeoutReason && previous.Reason != readinessTimeoutReason:
+		r.Recorder.Eventf(instance, corev1.EventTypeWarning,
+			string(event.DashboardReady), "Dashboard %s did not become ready within %s", instance.Namespace+"/"+instance.Name, timeout)
+	case previous.Status != metav1.ConditionFalse:
+		r.Recorder.Eventf(instance, corev1.EventTypeWarning,
+			string(event.DashboardReady), "Dashboard %s health check failed", instance.Namespace+"/"+instance.Name)
+	}
+
+	requeueAfter := readinessRequeueAfter(waited, timeout)
+	logger.Info("maybe not ready, trying again later", "waited", waited.Round(time.Second), "requeueAfter", requeueAfter)
+	return requeueAfter, nil
+}
+
+const (
+	readinessTimeoutReason = "ReadinessTimeout"
+
+	defaultReadinessTimeout = 10 * time.Minute
+	minReadinessRequeue     = 5 * time.Second
+	maxReadinessRequeue     = 2 * time.Minute
+)
+
+func readinessTimeout(instance *sentinelv1alpha1.Dashboard) time.Duration {
+	if instance.Spec.HealthCheck != nil && instance.Spec.HealthCheck.ReadinessTimeoutSeconds != nil {
+		return time.Duration(*instance.Spec.HealthCheck.ReadinessTimeoutSeconds) * time.Second
+	}
+	return defaultReadinessTimeout
+}
+
+// readinessRequeueAfter waits as long as the dashboard has been waiting
+// already, so the checks back off exponentially, within the min and max
+// requeue delay. A check is always made when the timeout is reached.
+func readinessRequeueAfter(waited, timeout time.Duration) time.Duration {
+	delay := waited
+	if delay < minReadinessRequeue {
+		delay = minReadinessRequeue
+	}
+	if delay > maxReadinessRequeue {
+		delay = maxReadinessRequeue
+	}
+	if remaining := timeout - waited; remaining > 0 && remaining < delay {
+		delay = remaining
+	}
+	return delay
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -189,9 +495,56 @@ func (r *DashboardReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.RestConfig = mgr.GetConfig()
 	r.Recorder = mgr.GetEventRecorderFor("dashboard-controller")
 
-	return ctrl.NewControllerManagedBy(mgr).
+	proxy, err := newServiceProxy(r.RestConfig, r.Scheme)
+	if err != nil {
+		return err
+	}
+	r.proxy = proxy
+	if r.APIReader == nil {
+		r.APIReader = mgr.GetAPIReader()
+	}
+	if r.HealthCheckers == nil {
+		r.HealthCheckers = newHealthCheckers(r.APIReader, proxy)
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &sentinelv1alpha1.Dashboard{},
+		datasourceSecretIndex, indexDatasourceSecret); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.Pod{},
+		injectedDashboardIndex, indexInjectedDashboard); err != nil {
+		return err
+	}
+
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&sentinelv1alpha1.Dashboard{}).
 		Owns(&corev1.Service{}).
 		Owns(&appsv1.Deployment{}).
-		Complete(r)
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&corev1.PersistentVolumeClaim{}).
+		Owns(&corev1.ServiceAccount{}).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Owns(&networkingv1.Ingress{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}},
+			handler.EnqueueRequestsFromMapFunc(r.secretToDashboards),
+			builder.OnlyMetadata).
+		Watches(&source.Kind{Type: &corev1.Pod{}},
+			handler.EnqueueRequestsFromMapFunc(injectedPodToDashboard),
+			builder.WithPredicates(injectedPodChanged))
+
+	// HTTPRoutes are only watched when the Gateway API is installed at
+	// startup; otherwise they are still applied once it is installed, but
+	// route status changes are only picked up on the next reconcile.
+	gvk, ok, err := httpRouteGVK(mgr.GetRESTMapper())
+	if err != nil {
+		return err
+	}
+	if ok {
+		route := &unstructured.Unstructured{}
+		route.SetGroupVersionKind(gvk)
+		b = b.Owns(route)
+	}
+
+	return b.Complete(r)
 }
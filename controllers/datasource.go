@@ -0,0 +1,271 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/event"
+)
+
+const (
+	// datasourceChecksumAnnotation is set on the pod template so that the
+	// deployment rolls out whenever the datasource secrets change.
+	datasourceChecksumAnnotation = "sentinel.sentinelguard.io/datasource-checksum"
+
+	// datasourceSecretIndex indexes dashboards by the Secrets their datasource references.
+	datasourceSecretIndex = ".spec.datasource.secretRef.name"
+
+	defaultUsernameKey = "username"
+	defaultPasswordKey = "password"
+)
+
+// datasourceRenderer renders a rule datasource into the env of the dashboard container.
+// Secrets are only ever referenced through env valueFrom, so the Secrets a
+// datasource depends on can be derived from the rendered env.
+type datasourceRenderer interface {
+	// Type returns the name of the datasource, e.g. nacos.
+	Type() string
+	// Env returns the env variables the dashboard reads the datasource from.
+	Env() []corev1.EnvVar
+}
+
+// newDatasourceRenderer returns the renderer of the datasource set in spec.
+// It fails unless exactly one datasource is set.
+func newDatasourceRenderer(spec *sentinelv1alpha1.DatasourceSpec) (datasourceRenderer, error) {
+	var renderers []datasourceRenderer
+	if spec.Nacos != nil {
+		renderers = append(renderers, &nacosRenderer{spec.Nacos})
+	}
+	if spec.Apollo != nil {
+		renderers = append(renderers, &apolloRenderer{spec.Apollo})
+	}
+	if spec.ZooKeeper != nil {
+		renderers = append(renderers, &zookeeperRenderer{spec.ZooKeeper})
+	}
+	if spec.Etcd != nil {
+		renderers = append(renderers, &etcdRenderer{spec.Etcd})
+	}
+	if spec.Consul != nil {
+		renderers = append(renderers, &consulRenderer{spec.Consul})
+	}
+	if spec.Redis != nil {
+		renderers = append(renderers, &redisRenderer{spec.Redis})
+	}
+
+	switch len(renderers) {
+	case 0:
+		return nil, errors.New("no datasource is set")
+	case 1:
+		return renderers[0], nil
+	default:
+		names := make([]string, 0, len(renderers))
+		for _, renderer := range renderers {
+			names = append(names, renderer.Type())
+		}
+		return nil, errors.Errorf("exactly one datasource must be set, but got %s", strings.Join(names, ", "))
+	}
+}
+
+func newDatasourceEnv(sentinel *sentinelv1alpha1.Dashboard) []corev1.EnvVar {
+	if sentinel.Spec.Datasource == nil {
+		return nil
+	}
+	renderer, err := newDatasourceRenderer(sentinel.Spec.Datasource)
+	if err != nil {
+		return nil
+	}
+	return renderer.Env()
+}
+
+// datasourceSecretKeys returns the Secret keys referenced by the datasource env.
+func datasourceSecretKeys(sentinel *sentinelv1alpha1.Dashboard) []corev1.SecretKeySelector {
+	var keys []corev1.SecretKeySelector
+	for _, env := range newDatasourceEnv(sentinel) {
+		if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+			keys = append(keys, *env.ValueFrom.SecretKeyRef)
+		}
+	}
+	return keys
+}
+
+func secretEnv(name string, ref corev1.LocalObjectReference, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: ref,
+				Key:                  key,
+			},
+		},
+	}
+}
+
+// credentialsEnv renders credentials into <prefix>_USERNAME and <prefix>_PASSWORD.
+func credentialsEnv(prefix string, creds *sentinelv1alpha1.SecretCredentials) []corev1.EnvVar {
+	if creds == nil {
+		return nil
+	}
+	return []corev1.EnvVar{
+		secretEnv(prefix+"_USERNAME", creds.SecretRef, credentialsKey(creds.UsernameKey, defaultUsernameKey)),
+		secretEnv(prefix+"_PASSWORD", creds.SecretRef, credentialsKey(creds.PasswordKey, defaultPasswordKey)),
+	}
+}
+
+// tokenEnv renders a token Secret key into name.
+func tokenEnv(name string, token *corev1.SecretKeySelector) []corev1.EnvVar {
+	if token == nil {
+		return nil
+	}
+	return []corev1.EnvVar{secretEnv(name, token.LocalObjectReference, token.Key)}
+}
+
+// appendEnv appends name=value unless value is empty.
+func appendEnv(env []corev1.EnvVar, name, value string) []corev1.EnvVar {
+	if value == "" {
+		return env
+	}
+	return append(env, corev1.EnvVar{Name: name, Value: value})
+}
+
+func credentialsKey(key, defaultKey string) string {
+	if key == "" {
+		return defaultKey
+	}
+	return key
+}
+
+// errInvalidDatasource is returned by UpdateDatasourceStatus for the
+// datasources that cannot be rendered.
+var errInvalidDatasource = errors.New("invalid datasource")
+
+// UpdateDatasourceStatus validates the datasource, resolves the Secrets it
+// references and records the result in the DatasourceReady condition. It
+// returns a checksum of the referenced Secret keys, which is empty when there
+// is nothing to resolve. While a Secret or key cannot be resolved it returns
+// the checksum the workload was last rolled out with, as new pods would not
+// start without it. An invalid datasource returns errInvalidDatasource, as
+// the dashboard must not be rolled out without it.
+func (r *DashboardReconciler) UpdateDatasourceStatus(ctx context.Context, instance *sentinelv1alpha1.Dashboard) (string, error) {
+	if instance.Spec.Datasource == nil {
+		return "", nil
+	}
+
+	if _, err := newDatasourceRenderer(instance.Spec.Datasource); err != nil {
+		if err := r.UpdateCondition(ctx, instance, sentinelv1alpha1.DatasourceConditionType, metav1.ConditionFalse,
+			"InvalidDatasource", err.Error()); err != nil {
+			return "", errors.Wrap(err, "failed updating conditions")
+		}
+		r.Recorder.Eventf(instance, corev1.EventTypeWarning,
+			string(event.DashboardDatasource), "Datasource of %s is invalid: %s", instance.Namespace+"/"+instance.Name, err)
+		return "", errInvalidDatasource
+	}
+
+	h := sha256.New()
+	secrets := make(map[string]*corev1.Secret)
+	for _, ref := range datasourceSecretKeys(instance) {
+		key := types.NamespacedName{Namespace: instance.Namespace, Name: ref.Name}
+		secret, ok := secrets[ref.Name]
+		if !ok {
+			secret = &corev1.Secret{}
+			if err := r.APIReader.Get(ctx, key, secret); err != nil {
+				if !apierrors.IsNotFound(err) {
+					return "", errors.Wrapf(err, "failed getting secret %s", key)
+				}
+				if err := r.UpdateCondition(ctx, instance, sentinelv1alpha1.DatasourceConditionType, metav1.ConditionFalse,
+					"SecretNotFound", "secret "+key.String()+" not found"); err != nil {
+					return "", errors.Wrap(err, "failed updating conditions")
+				}
+				r.Recorder.Eventf(instance, corev1.EventTypeWarning,
+					string(event.DashboardDatasource), "Datasource secret %s not found", key)
+				return r.currentDatasourceChecksum(ctx, instance)
+			}
+			secrets[ref.Name] = secret
+		}
+
+		value, ok := secret.Data[ref.Key]
+		if !ok {
+			if err := r.UpdateCondition(ctx, instance, sentinelv1alpha1.DatasourceConditionType, metav1.ConditionFalse,
+				"SecretKeyNotFound", "key "+ref.Key+" not found in secret "+key.String()); err != nil {
+				return "", errors.Wrap(err, "failed updating conditions")
+			}
+			r.Recorder.Eventf(instance, corev1.EventTypeWarning,
+				string(event.DashboardDatasource), "Datasource secret %s has no key %s", key, ref.Key)
+			return r.currentDatasourceChecksum(ctx, instance)
+		}
+		h.Write(value)
+		h.Write([]byte{0})
+	}
+
+	if err := r.UpdateCondition(ctx, instance, sentinelv1alpha1.DatasourceConditionType, metav1.ConditionTrue); err != nil {
+		return "", errors.Wrap(err, "failed updating conditions")
+	}
+
+	if len(secrets) == 0 {
+		return "", nil
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// currentDatasourceChecksum returns the datasource checksum on the pod
+// template of the existing workload.
+func (r *DashboardReconciler) currentDatasourceChecksum(ctx context.Context, instance *sentinelv1alpha1.Dashboard) (string, error) {
+	workload, _ := newWorkloads(instance)
+	if err := r.Get(ctx, client.ObjectKeyFromObject(workload), workload); err != nil {
+		return "", errors.Wrap(client.IgnoreNotFound(err), "failed getting workload")
+	}
+	switch w := workload.(type) {
+	case *appsv1.Deployment:
+		return w.Spec.Template.Annotations[datasourceChecksumAnnotation], nil
+	case *appsv1.StatefulSet:
+		return w.Spec.Template.Annotations[datasourceChecksumAnnotation], nil
+	}
+	return "", nil
+}
+
+// indexDatasourceSecret is the field indexer for datasourceSecretIndex.
+func indexDatasourceSecret(obj client.Object) []string {
+	instance, ok := obj.(*sentinelv1alpha1.Dashboard)
+	if !ok {
+		return nil
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, ref := range datasourceSecretKeys(instance) {
+		if ref.Name != "" && !seen[ref.Name] {
+			seen[ref.Name] = true
+			names = append(names, ref.Name)
+		}
+	}
+	return names
+}
+
+// secretToDashboards maps a Secret to the dashboards referencing it.
+func (r *DashboardReconciler) secretToDashboards(obj client.Object) []reconcile.Request {
+	var dashboards sentinelv1alpha1.DashboardList
+	if err := r.List(context.Background(), &dashboards,
+		client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{datasourceSecretIndex: obj.GetName()}); err != nil {
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(dashboards.Items))
+	for _, item := range dashboards.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: item.Namespace, Name: item.Name},
+		})
+	}
+	return requests
+}
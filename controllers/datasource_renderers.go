@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+)
+
+type nacosRenderer struct {
+	spec *sentinelv1alpha1.NacosDatasource
+}
+
+func (n *nacosRenderer) Type() string { return "nacos" }
+
+func (n *nacosRenderer) Env() []corev1.EnvVar {
+	env := []corev1.EnvVar{
+		{
+			Name:  "NACOS_ADDRESS",
+			Value: strings.Join(n.spec.ServerAddr, ","),
+		},
+	}
+	env = appendEnv(env, "NACOS_NAMESPACE", n.spec.Namespace)
+	env = appendEnv(env, "NACOS_GROUP", n.spec.Group)
+	env = appendEnv(env, "NACOS_CONTEXT_PATH", n.spec.ContextPath)
+	return append(env, credentialsEnv("NACOS", n.spec.Credentials)...)
+}
+
+type apolloRenderer struct {
+	spec *sentinelv1alpha1.ApolloDatasource
+}
+
+func (a *apolloRenderer) Type() string { return "apollo" }
+
+func (a *apolloRenderer) Env() []corev1.EnvVar {
+	env := []corev1.EnvVar{
+		{
+			Name:  "APOLLO_PORTAL_URL",
+			Value: a.spec.PortalURL,
+		},
+		{
+			Name:  "APOLLO_APP_ID",
+			Value: a.spec.AppID,
+		},
+	}
+	env = appendEnv(env, "APOLLO_ENV", a.spec.Env)
+	env = appendEnv(env, "APOLLO_CLUSTER", a.spec.Cluster)
+	env = appendEnv(env, "APOLLO_NAMESPACE", a.spec.Namespace)
+	return append(env, tokenEnv("APOLLO_TOKEN", a.spec.Token)...)
+}
+
+type zookeeperRenderer struct {
+	spec *sentinelv1alpha1.ZooKeeperDatasource
+}
+
+func (z *zookeeperRenderer) Type() string { return "zookeeper" }
+
+func (z *zookeeperRenderer) Env() []corev1.EnvVar {
+	env := []corev1.EnvVar{
+		{
+			Name:  "ZOOKEEPER_ADDRESS",
+			Value: strings.Join(z.spec.ServerAddr, ","),
+		},
+	}
+	env = appendEnv(env, "ZOOKEEPER_ROOT_PATH", z.spec.RootPath)
+	return append(env, credentialsEnv("ZOOKEEPER", z.spec.Credentials)...)
+}
+
+type etcdRenderer struct {
+	spec *sentinelv1alpha1.EtcdDatasource
+}
+
+func (e *etcdRenderer) Type() string { return "etcd" }
+
+func (e *etcdRenderer) Env() []corev1.EnvVar {
+	env := []corev1.EnvVar{
+		{
+			Name:  "ETCD_ENDPOINTS",
+			Value: strings.Join(e.spec.Endpoints, ","),
+		},
+	}
+	env = appendEnv(env, "ETCD_PREFIX", e.spec.Prefix)
+	return append(env, credentialsEnv("ETCD", e.spec.Credentials)...)
+}
+
+type consulRenderer struct {
+	spec *sentinelv1alpha1.ConsulDatasource
+}
+
+func (c *consulRenderer) Type() string { return "consul" }
+
+func (c *consulRenderer) Env() []corev1.EnvVar {
+	env := []corev1.EnvVar{
+		{
+			Name:  "CONSUL_ADDRESS",
+			Value: c.spec.Address,
+		},
+	}
+	env = appendEnv(env, "CONSUL_PREFIX", c.spec.Prefix)
+	return append(env, tokenEnv("CONSUL_TOKEN", c.spec.Token)...)
+}
+
+type redisRenderer struct {
+	spec *sentinelv1alpha1.RedisDatasource
+}
+
+func (r *redisRenderer) Type() string { return "redis" }
+
+func (r *redisRenderer) Env() []corev1.EnvVar {
+	env := []corev1.EnvVar{
+		{
+			Name:  "REDIS_ADDRESS",
+			Value: r.spec.Address,
+		},
+		{
+			Name:  "REDIS_DATABASE",
+			Value: strconv.Itoa(int(r.spec.Database)),
+		},
+	}
+	env = appendEnv(env, "REDIS_CHANNEL", r.spec.Channel)
+	return append(env, credentialsEnv("REDIS", r.spec.Credentials)...)
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+)
+
+func TestDatasourceRenderers(t *testing.T) {
+	token := &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "token"}, Key: "value"}
+	creds := &sentinelv1alpha1.SecretCredentials{SecretRef: corev1.LocalObjectReference{Name: "creds"}}
+	tests := []struct {
+		name     string
+		spec     sentinelv1alpha1.DatasourceSpec
+		wantType string
+		want     []corev1.EnvVar
+	}{
+		{
+			name: "apollo",
+			spec: sentinelv1alpha1.DatasourceSpec{Apollo: &sentinelv1alpha1.ApolloDatasource{
+				PortalURL: "http://apollo-portal:8070",
+				AppID:     "sentinel-dashboard",
+				Cluster:   "default",
+				Token:     token,
+			}},
+			wantType: "apollo",
+			want: []corev1.EnvVar{
+				{Name: "APOLLO_PORTAL_URL", Value: "http://apollo-portal:8070"},
+				{Name: "APOLLO_APP_ID", Value: "sentinel-dashboard"},
+				{Name: "APOLLO_CLUSTER", Value: "default"},
+				secretEnv("APOLLO_TOKEN", token.LocalObjectReference, "value"),
+			},
+		},
+		{
+			name: "zookeeper",
+			spec: sentinelv1alpha1.DatasourceSpec{ZooKeeper: &sentinelv1alpha1.ZooKeeperDatasource{
+				ServerAddr: []string{"zk-0:2181", "zk-1:2181"},
+				RootPath:   "/sentinel",
+			}},
+			wantType: "zookeeper",
+			want: []corev1.EnvVar{
+				{Name: "ZOOKEEPER_ADDRESS", Value: "zk-0:2181,zk-1:2181"},
+				{Name: "ZOOKEEPER_ROOT_PATH", Value: "/sentinel"},
+			},
+		},
+		{
+			name: "etcd",
+			spec: sentinelv1alpha1.DatasourceSpec{Etcd: &sentinelv1alpha1.EtcdDatasource{
+				Endpoints:   []string{"http://etcd:2379"},
+				Credentials: creds,
+			}},
+			wantType: "etcd",
+			want: []corev1.EnvVar{
+				{Name: "ETCD_ENDPOINTS", Value: "http://etcd:2379"},
+				secretEnv("ETCD_USERNAME", creds.SecretRef, "username"),
+				secretEnv("ETCD_PASSWORD", creds.SecretRef, "password"),
+			},
+		},
+		{
+			name: "consul",
+			spec: sentinelv1alpha1.DatasourceSpec{Consul: &sentinelv1alpha1.ConsulDatasource{
+				Address: "consul:8500",
+				Prefix:  "sentinel",
+			}},
+			wantType: "consul",
+			want: []corev1.EnvVar{
+				{Name: "CONSUL_ADDRESS", Value: "consul:8500"},
+				{Name: "CONSUL_PREFIX", Value: "sentinel"},
+			},
+		},
+		{
+			name: "redis",
+			spec: sentinelv1alpha1.DatasourceSpec{Redis: &sentinelv1alpha1.RedisDatasource{
+				Address:  "redis:6379",
+				Database: 2,
+			}},
+			wantType: "redis",
+			want: []corev1.EnvVar{
+				{Name: "REDIS_ADDRESS", Value: "redis:6379"},
+				{Name: "REDIS_DATABASE", Value: "2"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			renderer, err := newDatasourceRenderer(&tt.spec)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(renderer.Type()).To(Equal(tt.wantType))
+			g.Expect(renderer.Env()).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestNewDatasourceRendererRequiresExactlyOne(t *testing.T) {
+	g := NewWithT(t)
+	_, err := newDatasourceRenderer(&sentinelv1alpha1.DatasourceSpec{})
+	g.Expect(err).To(MatchError("no datasource is set"))
+
+	_, err = newDatasourceRenderer(&sentinelv1alpha1.DatasourceSpec{
+		Nacos: &sentinelv1alpha1.NacosDatasource{ServerAddr: []string{"nacos:8848"}},
+		Redis: &sentinelv1alpha1.RedisDatasource{Address: "redis:6379"},
+	})
+	g.Expect(err).To(MatchError("exactly one datasource must be set, but got nacos, redis"))
+}
+
+func TestDatasourceSecretKeys(t *testing.T) {
+	g := NewWithT(t)
+	instance := newNacosDashboard()
+	g.Expect(datasourceSecretKeys(instance)).To(Equal([]corev1.SecretKeySelector{
+		{LocalObjectReference: corev1.LocalObjectReference{Name: "nacos"}, Key: "username"},
+		{LocalObjectReference: corev1.LocalObjectReference{Name: "nacos"}, Key: "token"},
+	}))
+	g.Expect(indexDatasourceSecret(instance)).To(Equal([]string{"nacos"}))
+}
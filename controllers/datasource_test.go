@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+)
+
+func newNacosDashboard() *sentinelv1alpha1.Dashboard {
+	return &sentinelv1alpha1.Dashboard{
+		ObjectMeta: metav1.ObjectMeta{Name: "sentinel-dashboard", Namespace: "sentinel-group"},
+		Spec: sentinelv1alpha1.DashboardSpec{
+			Datasource: &sentinelv1alpha1.DatasourceSpec{
+				Nacos: &sentinelv1alpha1.NacosDatasource{
+					ServerAddr: []string{"nacos-0:8848", "nacos-1:8848"},
+					Namespace:  "sentinel",
+					Credentials: &sentinelv1alpha1.SecretCredentials{
+						SecretRef:   corev1.LocalObjectReference{Name: "nacos"},
+						PasswordKey: "token",
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestNacosDatasourceEnv(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(newDatasourceEnv(newNacosDashboard())).To(Equal([]corev1.EnvVar{
+		{Name: "NACOS_ADDRESS", Value: "nacos-0:8848,nacos-1:8848"},
+		{Name: "NACOS_NAMESPACE", Value: "sentinel"},
+		secretEnv("NACOS_USERNAME", corev1.LocalObjectReference{Name: "nacos"}, "username"),
+		secretEnv("NACOS_PASSWORD", corev1.LocalObjectReference{Name: "nacos"}, "token"),
+	}))
+	g.Expect(newDatasourceEnv(&sentinelv1alpha1.Dashboard{})).To(BeEmpty())
+}
+
+func TestUpdateDatasourceStatus(t *testing.T) {
+	secret := func(data map[string]string) *corev1.Secret {
+		s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "nacos", Namespace: "sentinel-group"}, Data: map[string][]byte{}}
+		for k, v := range data {
+			s.Data[k] = []byte(v)
+		}
+		return s
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "sentinel-dashboard", Namespace: "sentinel-group"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{datasourceChecksumAnnotation: "previous"},
+		}}},
+	}
+	resolved := secret(map[string]string{"username": "nacos", "token": "secret"})
+	checksum := func(g *WithT, s *corev1.Secret) string {
+		sum, err := newTestDashboardReconciler(s).UpdateDatasourceStatus(context.Background(), newNacosDashboard())
+		g.Expect(err).NotTo(HaveOccurred())
+		return sum
+	}
+
+	t.Run("resolved secret", func(t *testing.T) {
+		g := NewWithT(t)
+		instance := newNacosDashboard()
+		r := newTestDashboardReconciler(resolved, deployment)
+		sum, err := r.UpdateDatasourceStatus(context.Background(), instance)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(sum).To(HaveLen(64))
+		g.Expect(r.GetCondition(context.Background(), instance, sentinelv1alpha1.DatasourceConditionType).Status).
+			To(Equal(metav1.ConditionTrue))
+
+		g.Expect(checksum(g, resolved)).To(Equal(sum), "the checksum is stable")
+		g.Expect(checksum(g, secret(map[string]string{"username": "nacos", "token": "rotated"}))).NotTo(Equal(sum))
+		g.Expect(checksum(g, secret(map[string]string{"username": "nacos", "token": "secret", "unused": "x"}))).
+			To(Equal(sum), "unreferenced keys are ignored")
+	})
+
+	t.Run("no datasource", func(t *testing.T) {
+		g := NewWithT(t)
+		sum, err := newTestDashboardReconciler().UpdateDatasourceStatus(context.Background(), &sentinelv1alpha1.Dashboard{})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(sum).To(BeEmpty())
+	})
+
+	for name, tt := range map[string]struct {
+		secret *corev1.Secret
+		reason string
+	}{
+		"missing secret": {reason: "SecretNotFound"},
+		"missing key":    {secret: secret(map[string]string{"username": "nacos"}), reason: "SecretKeyNotFound"},
+	} {
+		tt := tt
+		t.Run(name+" keeps the previous checksum", func(t *testing.T) {
+			g := NewWithT(t)
+			objs := []client.Object{deployment}
+			if tt.secret != nil {
+				objs = append(objs, tt.secret)
+			}
+			instance := newNacosDashboard()
+			r := newTestDashboardReconciler(objs...)
+			sum, err := r.UpdateDatasourceStatus(context.Background(), instance)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(sum).To(Equal("previous"))
+			cond := r.GetCondition(context.Background(), instance, sentinelv1alpha1.DatasourceConditionType)
+			g.Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+			g.Expect(cond.Reason).To(Equal(tt.reason))
+		})
+	}
+
+	t.Run("invalid datasource", func(t *testing.T) {
+		g := NewWithT(t)
+		instance := newNacosDashboard()
+		instance.Spec.Datasource.Apollo = &sentinelv1alpha1.ApolloDatasource{}
+		r := newTestDashboardReconciler(resolved)
+		_, err := r.UpdateDatasourceStatus(context.Background(), instance)
+		g.Expect(err).To(MatchError(errInvalidDatasource))
+		cond := r.GetCondition(context.Background(), instance, sentinelv1alpha1.DatasourceConditionType)
+		g.Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		g.Expect(cond.Reason).To(Equal("InvalidDatasource"))
+	})
+
+	t.Run("missing secret before the first rollout", func(t *testing.T) {
+		g := NewWithT(t)
+		sum, err := newTestDashboardReconciler().UpdateDatasourceStatus(context.Background(), newNacosDashboard())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(sum).To(BeEmpty())
+	})
+}
+
+func TestUpdateAppliedStatusInvalidDatasource(t *testing.T) {
+	g := NewWithT(t)
+	instance := newNacosDashboard()
+	instance.SetDefaults("")
+	instance.Spec.Datasource.Nacos = nil
+	r := newTestDashboardReconciler()
+
+	g.Expect(r.UpdateAppliedStatus(context.Background(), instance)).To(Succeed())
+	cond := r.GetCondition(context.Background(), instance, sentinelv1alpha1.AppliedConditionType)
+	g.Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+	g.Expect(cond.Reason).To(Equal("InvalidDatasource"))
+	var deployments appsv1.DeploymentList
+	g.Expect(r.List(context.Background(), &deployments)).To(Succeed())
+	g.Expect(deployments.Items).To(BeEmpty(), "nothing is rolled out without the datasource")
+}
@@ -0,0 +1,365 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/event"
+)
+
+const (
+	// dashboardFinalizer blocks the deletion of a Dashboard until its deletion policy is carried out.
+	dashboardFinalizer = "sentinel.sentinelguard.io/finalizer"
+
+	// backupSuffix is appended to the name of the dashboard to name the backup ConfigMap.
+	backupSuffix = "-backup"
+
+	// defaultAuthUsername and defaultAuthPassword are the login of the
+	// dashboard image when spec.server.auth is not set.
+	defaultAuthUsername = "sentinel"
+	defaultAuthPassword = "sentinel"
+)
+
+// ruleEndpoints maps the rule types of the dashboard to the machine level
+// endpoints that list them.
+var ruleEndpoints = map[string]string{
+	"flow":      "/v1/flow/rules",
+	"degrade":   "/degrade/rules.json",
+	"system":    "/system/rules.json",
+	"authority": "/authority/rules",
+	"paramflow": "/paramFlow/rules",
+}
+
+// invalidConfigMapKey matches the characters not allowed in ConfigMap keys.
+var invalidConfigMapKey = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+
+// dashboardResult is the envelope the dashboard wraps its responses in.
+type dashboardResult struct {
+	Success bool            `json:"success"`
+	Msg     string          `json:"msg"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type appInfo struct {
+	App string `json:"app"`
+}
+
+type machineInfo struct {
+	IP      string `json:"ip"`
+	Port    int    `json:"port"`
+	Healthy bool   `json:"healthy"`
+}
+
+// ownedObjects returns the resources the dashboard may own. They are named
+// after the dashboard, except for the headless Service of the StatefulSet,
+// the claim of the Deployment and the ServiceAccount.
+func ownedObjects(instance *sentinelv1alpha1.Dashboard) []client.Object {
+	objs := []client.Object{
+		&appsv1.Deployment{},
+		&appsv1.StatefulSet{},
+		&corev1.Service{},
+		&autoscalingv2.HorizontalPodAutoscaler{},
+		&policyv1.PodDisruptionBudget{},
+		&networkingv1.Ingress{},
+	}
+	for _, obj := range objs {
+		obj.SetName(instance.Name)
+		obj.SetNamespace(instance.Namespace)
+	}
+
+	headless := &corev1.Service{}
+	headless.SetName(headlessServiceName(instance))
+	headless.SetNamespace(instance.Namespace)
+	objs = append(objs, headless)
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	pvc.SetName(dataClaimName(instance))
+	pvc.SetNamespace(instance.Namespace)
+	objs = append(objs, pvc)
+	if name := serviceAccountName(instance); name != "" {
+		sa := &corev1.ServiceAccount{}
+		sa.SetName(name)
+		sa.SetNamespace(instance.Namespace)
+		objs = append(objs, sa)
+	}
+	return objs
+}
+
+// EnsureFinalizer adds the finalizer to the dashboard if it is missing and
+// the dashboard is not being deleted. It patches the finalizers only and
+// reloads the dashboard as stored, so it is called before the defaults are
+// set in memory.
+func (r *DashboardReconciler) EnsureFinalizer(ctx context.Context, instance *sentinelv1alpha1.Dashboard) error {
+	if !instance.DeletionTimestamp.IsZero() || controllerutil.ContainsFinalizer(instance, dashboardFinalizer) {
+		return nil
+	}
+	patch := client.MergeFrom(instance.DeepCopy())
+	controllerutil.AddFinalizer(instance, dashboardFinalizer)
+	return errors.Wrap(r.Patch(ctx, instance, patch), "failed adding finalizer")
+}
+
+// Finalize carries out the deletion policy of a dashboard being deleted and
+// removes the finalizer once it is done.
+func (r *DashboardReconciler) Finalize(ctx context.Context, instance *sentinelv1alpha1.Dashboard) error {
+	logger := log.FromContext(ctx)
+	if !controllerutil.ContainsFinalizer(instance, dashboardFinalizer) {
+		return nil
+	}
+
+	if err := r.UpdatePhase(ctx, instance); err != nil {
+		return errors.Wrap(err, "failed updating phase")
+	}
+	if err := r.UpdateStatus(ctx, instance); err != nil {
+		return err
+	}
+
+	switch instance.Spec.DeletionPolicy {
+	case sentinelv1alpha1.DeletionPolicyBackup:
+		if err := r.Backup(ctx, instance); err != nil {
+			var loginErr *dashboardLoginError
+			if errors.As(err, &loginErr) {
+				r.Recorder.Eventf(instance, corev1.EventTypeWarning, string(event.DashboardLoginFailed),
+					"Dashboard %s rejected the login as %q, check spec.server.auth or set spec.deletionPolicy to Delete to skip the backup: %v",
+					instance.Namespace+"/"+instance.Name, loginErr.username, loginErr.err)
+				return errors.Wrap(err, "failed backing up rules")
+			}
+			r.Recorder.Eventf(instance, corev1.EventTypeWarning, string(event.DashboardBackup),
+				"Dashboard %s backup failed, set spec.deletionPolicy to Delete to skip it: %v", instance.Namespace+"/"+instance.Name, err)
+			return errors.Wrap(err, "failed backing up rules")
+		}
+		r.Recorder.Eventf(instance, corev1.EventTypeNormal, string(event.DashboardBackup),
+			"Dashboard %s rules are backed up to ConfigMap %s", instance.Namespace+"/"+instance.Name, instance.Name+backupSuffix)
+	case sentinelv1alpha1.DeletionPolicyRetain:
+		if err := r.releaseOwned(ctx, instance); err != nil {
+			return errors.Wrap(err, "failed releasing owned resources")
+		}
+	}
+
+	policy := instance.Spec.DeletionPolicy
+	patch := client.MergeFrom(instance.DeepCopy())
+	controllerutil.RemoveFinalizer(instance, dashboardFinalizer)
+	if err := r.Patch(ctx, instance, patch); err != nil {
+		return errors.Wrap(err, "failed removing finalizer")
+	}
+	logger.Info("finalized dashboard", "deletionPolicy", policy)
+
+	r.Recorder.Eventf(instance, corev1.EventTypeNormal, string(event.DashboardDeleting),
+		"Dashboard %s is finalized with deletion policy %q", instance.Namespace+"/"+instance.Name, policy)
+	return nil
+}
+
+// releaseOwned removes the controller reference of the dashboard from the
+// owned resources so the garbage collector keeps them.
+func (r *DashboardReconciler) releaseOwned(ctx context.Context, instance *sentinelv1alpha1.Dashboard) error {
+	logger := log.FromContext(ctx)
+	objs := ownedObjects(instance)
+	gvk, ok, err := httpRouteGVK(r.RESTMapper())
+	if err != nil {
+		return err
+	}
+	if ok {
+		objs = append(objs, newHTTPRoute(instance, gvk))
+	}
+
+	for _, obj := range objs {
+		if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			if err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+				return err
+			}
+			if !metav1.IsControlledBy(obj, instance) {
+				return nil
+			}
+			var refs []metav1.OwnerReference
+			for _, ref := range obj.GetOwnerReferences() {
+				if ref.UID != instance.UID {
+					refs = append(refs, ref)
+				}
+			}
+			obj.SetOwnerReferences(refs)
+			return r.Update(ctx, obj)
+		}); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		logger.Info("released owned resource", "name", obj.GetName(), "namespace", obj.GetNamespace())
+	}
+	return nil
+}
+
+// Backup exports the apps and the rules of their first healthy machine into
+// the ConfigMap <name>-backup. The ConfigMap is not owned by the dashboard so
+// it survives the deletion.
+func (r *DashboardReconciler) Backup(ctx context.Context, instance *sentinelv1alpha1.Dashboard) error {
+	session, err := r.loginDashboard(ctx, instance)
+	if err != nil {
+		return err
+	}
+
+	var apps []appInfo
+	raw, err := session.get(ctx, "/app/briefinfos.json", nil, &apps)
+	if err != nil {
+		return errors.Wrap(err, "cannot list apps")
+	}
+
+	data := map[string]string{"apps.json": string(raw)}
+	for _, app := range apps {
+		var machines []machineInfo
+		if _, err := session.get(ctx, "/app/"+url.PathEscape(app.App)+"/machines.json", nil, &machines); err != nil {
+			return errors.Wrapf(err, "cannot list machines of app %s", app.App)
+		}
+		machine := firstHealthyMachine(machines)
+		if machine == nil {
+			continue
+		}
+
+		params := url.Values{
+			"app":  []string{app.App},
+			"ip":   []string{machine.IP},
+			"port": []string{strconv.Itoa(machine.Port)},
+		}
+		for ruleType, path := range ruleEndpoints {
+			rules, err := session.get(ctx, path, params, nil)
+			if err != nil {
+				return errors.Wrapf(err, "cannot get %s rules of app %s", ruleType, app.App)
+			}
+			data[invalidConfigMapKey.ReplaceAllString(app.App, "_")+"."+ruleType+".json"] = string(rules)
+		}
+	}
+
+	var cm corev1.ConfigMap
+	cm.Name = instance.Name + backupSuffix
+	cm.Namespace = instance.Namespace
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		_, err := controllerutil.CreateOrUpdate(ctx, r.Client, &cm, func() error {
+			if cm.Labels == nil {
+				cm.Labels = map[string]string{}
+			}
+			cm.Labels["app"] = instance.Name
+			cm.Data = data
+			return nil
+		})
+		return err
+	})
+}
+
+// dashboardLoginError is returned when the dashboard rejects the login of the operator.
+type dashboardLoginError struct {
+	username string
+	err      error
+}
+
+func (e *dashboardLoginError) Error() string {
+	return fmt.Sprintf("cannot log in as %q: %v", e.username, e.err)
+}
+
+func (e *dashboardLoginError) Unwrap() error {
+	return e.err
+}
+
+// dashboardSession requests the dashboard as a logged in user, which the
+// endpoints of the apps and the rules require, unlike the health check one.
+type dashboardSession struct {
+	proxy    *serviceProxy
+	instance *sentinelv1alpha1.Dashboard
+	cookies  []*http.Cookie
+}
+
+// loginDashboard logs in to the dashboard with spec.server.auth, or with the
+// default login of the image when it is not set.
+func (r *DashboardReconciler) loginDashboard(ctx context.Context, instance *sentinelv1alpha1.Dashboard) (*dashboardSession, error) {
+	username, password := defaultAuthUsername, defaultAuthPassword
+	if server := instance.Spec.Server; server != nil && server.Auth != nil {
+		ref := server.Auth.PasswordSecretRef
+		var secret corev1.Secret
+		if err := r.APIReader.Get(ctx, client.ObjectKey{Namespace: instance.Namespace, Name: ref.Name}, &secret); err != nil {
+			return nil, errors.Wrapf(err, "cannot get password Secret %s", ref.Name)
+		}
+		value, ok := secret.Data[ref.Key]
+		if !ok {
+			return nil, errors.Errorf("key %s not found in password Secret %s", ref.Key, ref.Name)
+		}
+		username, password = server.Auth.Username, string(value)
+	}
+
+	session := &dashboardSession{proxy: r.proxy, instance: instance}
+	params := url.Values{
+		"username": []string{username},
+		"password": []string{password},
+	}
+	if _, err := session.do(ctx, http.MethodPost, "/auth/login", params, nil); err != nil {
+		return nil, &dashboardLoginError{username: username, err: err}
+	}
+	return session, nil
+}
+
+// get gets path from the dashboard and unwraps the result envelope, decoding
+// its data into out when out is not nil.
+func (s *dashboardSession) get(ctx context.Context, path string, params url.Values, out interface{}) (json.RawMessage, error) {
+	return s.do(ctx, http.MethodGet, path, params, out)
+}
+
+// do sends a request of path to the dashboard with the cookies of the
+// session, keeps the cookies the response sets, and unwraps the result envelope.
+func (s *dashboardSession) do(ctx context.Context, method, path string, params url.Values, out interface{}) (json.RawMessage, error) {
+	body, cookies, err := s.proxy.Do(ctx, method, s.instance, dashboardPath(s.instance, path), params, s.cookies)
+	if err != nil {
+		return nil, err
+	}
+	s.setCookies(cookies)
+
+	var result dashboardResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, errors.Wrapf(err, "cannot decode response of %s", path)
+	}
+	if !result.Success {
+		return nil, errors.Errorf("request %s failed: %s", path, result.Msg)
+	}
+	if out != nil {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			return nil, errors.Wrapf(err, "cannot decode data of %s", path)
+		}
+	}
+	return result.Data, nil
+}
+
+// setCookies replaces the cookies of the session with the ones of the same name.
+func (s *dashboardSession) setCookies(cookies []*http.Cookie) {
+	for _, cookie := range cookies {
+		replaced := false
+		for i := range s.cookies {
+			if s.cookies[i].Name == cookie.Name {
+				s.cookies[i] = cookie
+				replaced = true
+			}
+		}
+		if !replaced {
+			s.cookies = append(s.cookies, cookie)
+		}
+	}
+}
+
+func firstHealthyMachine(machines []machineInfo) *machineInfo {
+	for i := range machines {
+		if machines[i].Healthy {
+			return &machines[i]
+		}
+	}
+	return nil
+}
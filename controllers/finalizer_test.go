@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+)
+
+const testSessionCookie = "JSESSIONID"
+
+// fakeDashboard emulates the endpoints of the dashboard the backup requests,
+// served through the service proxy of the API server.
+type fakeDashboard struct {
+	username, password string
+	// noSession makes the login succeed without setting the session cookie.
+	noSession bool
+}
+
+func (d *fakeDashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeResult := func(success bool, msg string, data interface{}) {
+		raw, _ := json.Marshal(data)
+		_ = json.NewEncoder(w).Encode(dashboardResult{Success: success, Msg: msg, Data: raw})
+	}
+
+	if r.URL.Path == "/auth/login" {
+		if r.Method != http.MethodPost || r.URL.Query().Get("password") != "" {
+			http.Error(w, "login expects a form", http.StatusBadRequest)
+			return
+		}
+		if r.FormValue("username") != d.username || r.FormValue("password") != d.password {
+			writeResult(false, "Invalid username or password", nil)
+			return
+		}
+		if !d.noSession {
+			http.SetCookie(w, &http.Cookie{Name: testSessionCookie, Value: "session", Path: "/"})
+		}
+		writeResult(true, "", nil)
+		return
+	}
+
+	if cookie, err := r.Cookie(testSessionCookie); err != nil || cookie.Value != "session" {
+		w.WriteHeader(http.StatusUnauthorized)
+		writeResult(false, "Not logged in", nil)
+		return
+	}
+	query := r.URL.Query()
+	switch {
+	case r.URL.Path == "/app/briefinfos.json":
+		writeResult(true, "", []appInfo{{App: "order-service"}, {App: "idle"}})
+	case r.URL.Path == "/app/order-service/machines.json":
+		writeResult(true, "", []machineInfo{{IP: "10.0.0.1", Port: 8719}, {IP: "10.0.0.2", Port: 8720, Healthy: true}})
+	case r.URL.Path == "/app/idle/machines.json":
+		writeResult(true, "", []machineInfo{})
+	case query.Get("app") == "order-service" && query.Get("ip") == "10.0.0.2" && query.Get("port") == "8720":
+		writeResult(true, "", []map[string]string{{"resource": r.URL.Path}})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newFakeDashboardServer serves dashboard through the service proxy path of instance.
+func newFakeDashboardServer(instance *sentinelv1alpha1.Dashboard, dashboard http.Handler) *httptest.Server {
+	prefix := fmt.Sprintf("/api/v1/namespaces/%s/services/%s:%d/proxy",
+		instance.Namespace, instance.Name, sentinelv1alpha1.DefaultPort)
+	return httptest.NewServer(http.StripPrefix(prefix, dashboard))
+}
+
+func TestEnsureFinalizer(t *testing.T) {
+	g := NewWithT(t)
+	stored := &sentinelv1alpha1.Dashboard{ObjectMeta: metav1.ObjectMeta{Namespace: "sentinel-group", Name: "sentinel"}}
+	r := newTestDashboardReconciler(stored)
+
+	instance := stored.DeepCopy()
+	instance.SetDefaults("sentinel-dashboard:1.8.6")
+	g.Expect(r.EnsureFinalizer(context.Background(), instance)).To(Succeed())
+
+	var got sentinelv1alpha1.Dashboard
+	g.Expect(r.Get(context.Background(), client.ObjectKeyFromObject(stored), &got)).To(Succeed())
+	g.Expect(got.Finalizers).To(ConsistOf(dashboardFinalizer))
+	g.Expect(got.Spec).To(Equal(stored.Spec), "the defaults are not persisted")
+
+	// a dashboard being deleted gets no new finalizer
+	deleting := got.DeepCopy()
+	deleting.Finalizers = nil
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	g.Expect(r.EnsureFinalizer(context.Background(), deleting)).To(Succeed())
+	g.Expect(deleting.Finalizers).To(BeEmpty())
+}
+
+func TestBackup(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "login", Namespace: "sentinel-group"},
+		Data:       map[string][]byte{"password": []byte("s3cret")},
+	}
+	withAuth := func(instance *sentinelv1alpha1.Dashboard) {
+		instance.Spec.Server = &sentinelv1alpha1.ServerSpec{Auth: &sentinelv1alpha1.DashboardAuthSpec{
+			Username: "admin",
+			PasswordSecretRef: corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "login"},
+				Key:                  "password",
+			},
+		}}
+	}
+
+	tests := []struct {
+		name      string
+		dashboard *fakeDashboard
+		mutate    func(*sentinelv1alpha1.Dashboard)
+		wantLogin bool
+		wantErr   error
+	}{
+		{
+			name:      "default login",
+			dashboard: &fakeDashboard{username: defaultAuthUsername, password: defaultAuthPassword},
+		},
+		{
+			name:      "login of spec.server.auth",
+			dashboard: &fakeDashboard{username: "admin", password: "s3cret"},
+			mutate:    withAuth,
+		},
+		{
+			name:      "rejected login",
+			dashboard: &fakeDashboard{username: "admin", password: "other"},
+			mutate:    withAuth,
+			wantLogin: true,
+		},
+		{
+			name:      "default login changed",
+			dashboard: &fakeDashboard{username: "admin", password: "s3cret"},
+			wantLogin: true,
+		},
+		{
+			name:      "unauthorized",
+			dashboard: &fakeDashboard{username: defaultAuthUsername, password: defaultAuthPassword, noSession: true},
+			wantErr:   errUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			instance := &sentinelv1alpha1.Dashboard{
+				ObjectMeta: metav1.ObjectMeta{Name: "sentinel-dashboard", Namespace: "sentinel-group"},
+			}
+			if tt.mutate != nil {
+				tt.mutate(instance)
+			}
+			srv := newFakeDashboardServer(instance, tt.dashboard)
+			defer srv.Close()
+
+			r := newTestDashboardReconciler(instance, secret.DeepCopy())
+			proxy, err := newServiceProxy(&rest.Config{Host: srv.URL}, r.Scheme)
+			g.Expect(err).NotTo(HaveOccurred())
+			r.proxy = proxy
+
+			err = r.Backup(context.Background(), instance)
+			var cm corev1.ConfigMap
+			getErr := r.Get(context.Background(), client.ObjectKey{Namespace: instance.Namespace, Name: instance.Name + backupSuffix}, &cm)
+
+			var loginErr *dashboardLoginError
+			g.Expect(errors.As(err, &loginErr)).To(Equal(tt.wantLogin))
+			if tt.wantLogin || tt.wantErr != nil {
+				g.Expect(err).To(HaveOccurred())
+				if tt.wantErr != nil {
+					g.Expect(errors.Is(err, tt.wantErr)).To(BeTrue(), "got %v", err)
+				}
+				g.Expect(apierrors.IsNotFound(getErr)).To(BeTrue())
+				return
+			}
+
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(getErr).NotTo(HaveOccurred())
+			g.Expect(cm.Labels).To(HaveKeyWithValue("app", instance.Name))
+			g.Expect(cm.Data).To(HaveLen(1 + len(ruleEndpoints)))
+			g.Expect(cm.Data["apps.json"]).To(MatchJSON(`[{"app":"order-service"},{"app":"idle"}]`))
+			for ruleType, path := range ruleEndpoints {
+				g.Expect(cm.Data["order-service."+ruleType+".json"]).To(MatchJSON(`[{"resource":"` + path + `"}]`))
+			}
+		})
+	}
+}
+
+func TestDashboardSessionSetCookies(t *testing.T) {
+	g := NewWithT(t)
+	var s dashboardSession
+	s.setCookies([]*http.Cookie{{Name: "a", Value: "1"}, {Name: "b", Value: "1"}})
+	s.setCookies([]*http.Cookie{{Name: "b", Value: "2"}})
+
+	var got []string
+	for _, cookie := range s.cookies {
+		got = append(got, cookie.String())
+	}
+	g.Expect(strings.Join(got, "; ")).To(Equal("a=1; b=2"))
+}
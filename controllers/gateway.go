@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+)
+
+const gatewayGroup = "gateway.networking.k8s.io"
+
+// httpRouteGroupKind is the kind of the route rendered from spec.gatewayRoute.
+// It is used through unstructured objects so the operator does not depend on
+// the Gateway API CRDs being installed.
+var httpRouteGroupKind = schema.GroupKind{Group: gatewayGroup, Kind: "HTTPRoute"}
+
+// httpRouteGVK returns the preferred served version of HTTPRoute, and false
+// when the Gateway API CRDs are not installed.
+func httpRouteGVK(mapper meta.RESTMapper) (schema.GroupVersionKind, bool, error) {
+	mapping, err := mapper.RESTMapping(httpRouteGroupKind, "v1", "v1beta1")
+	if meta.IsNoMatchError(err) {
+		return schema.GroupVersionKind{}, false, nil
+	}
+	if err != nil {
+		return schema.GroupVersionKind{}, false, err
+	}
+	return mapping.GroupVersionKind, true, nil
+}
+
+func newHTTPRoute(instance *sentinelv1alpha1.Dashboard, gvk schema.GroupVersionKind) *unstructured.Unstructured {
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(gvk)
+	route.SetName(instance.Name)
+	route.SetNamespace(instance.Namespace)
+	return route
+}
+
+// UpdateRouteStatus sets the RouteAccepted condition from the status the
+// Gateways reported on the HTTPRoute, and removes it when no route is set.
+func (r *DashboardReconciler) UpdateRouteStatus(ctx context.Context, instance *sentinelv1alpha1.Dashboard) error {
+	if instance.Spec.GatewayRoute == nil {
+		r.RemoveCondition(ctx, instance, sentinelv1alpha1.RouteAcceptedConditionType)
+		return nil
+	}
+
+	gvk, ok, err := httpRouteGVK(r.RESTMapper())
+	if err != nil {
+		return errors.Wrap(err, "failed discovering HTTPRoute")
+	}
+	if !ok {
+		return r.UpdateCondition(ctx, instance, sentinelv1alpha1.RouteAcceptedConditionType, metav1.ConditionFalse,
+			"GatewayAPINotInstalled", "the HTTPRoute CRD of the Gateway API is not installed")
+	}
+
+	route := newHTTPRoute(instance, gvk)
+	if err := r.Get(ctx, client.ObjectKeyFromObject(route), route); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return errors.Wrap(err, "failed getting httproute")
+		}
+		return r.UpdateCondition(ctx, instance, sentinelv1alpha1.RouteAcceptedConditionType, metav1.ConditionUnknown,
+			"RouteNotFound", "the HTTPRoute has not been created yet")
+	}
+
+	status, reason, message := routeAccepted(route)
+	return r.UpdateCondition(ctx, instance, sentinelv1alpha1.RouteAcceptedConditionType, status, reason, message)
+}
+
+// routeAccepted summarizes the Accepted conditions of the parents of route:
+// True when every parent accepted it, False when any parent rejected it and
+// Unknown while any parent has not reported yet.
+func routeAccepted(route *unstructured.Unstructured) (metav1.ConditionStatus, string, string) {
+	parents, _, _ := unstructured.NestedSlice(route.Object, "status", "parents")
+	refs, _, _ := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+
+	accepted := 0
+	var rejected []string
+	for _, p := range parents {
+		parent, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(parent, "parentRef", "name")
+		conditions, _, _ := unstructured.NestedSlice(parent, "conditions")
+		for _, c := range conditions {
+			cond, ok := c.(map[string]interface{})
+			if !ok || cond["type"] != "Accepted" {
+				continue
+			}
+			if cond["status"] == string(metav1.ConditionTrue) {
+				accepted++
+			} else {
+				rejected = append(rejected, fmt.Sprintf("%s: %v", name, cond["message"]))
+			}
+		}
+	}
+
+	if len(rejected) > 0 {
+		return metav1.ConditionFalse, "NotAccepted", strings.Join(rejected, "; ")
+	}
+	if accepted < len(refs) {
+		return metav1.ConditionUnknown, "Pending", "waiting for the Gateways to accept the HTTPRoute"
+	}
+	return metav1.ConditionTrue, "Accepted", ""
+}
@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+)
+
+func TestHTTPRouteGVK(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []string
+		want     schema.GroupVersionKind
+		wantOK   bool
+	}{
+		{name: "not installed"},
+		{
+			name:     "v1beta1",
+			versions: []string{"v1beta1"},
+			want:     httpRouteGroupKind.WithVersion("v1beta1"),
+			wantOK:   true,
+		},
+		{
+			name:     "v1 preferred",
+			versions: []string{"v1beta1", "v1"},
+			want:     httpRouteGroupKind.WithVersion("v1"),
+			wantOK:   true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			var groupVersions []schema.GroupVersion
+			for _, version := range tt.versions {
+				groupVersions = append(groupVersions, schema.GroupVersion{Group: gatewayGroup, Version: version})
+			}
+			mapper := meta.NewDefaultRESTMapper(groupVersions)
+			for _, gv := range groupVersions {
+				mapper.Add(httpRouteGroupKind.WithVersion(gv.Version), meta.RESTScopeNamespace)
+			}
+
+			gvk, ok, err := httpRouteGVK(mapper)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(ok).To(Equal(tt.wantOK))
+			g.Expect(gvk).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestRouteAccepted(t *testing.T) {
+	parent := func(name string, conditions ...map[string]interface{}) interface{} {
+		conds := make([]interface{}, 0, len(conditions))
+		for _, cond := range conditions {
+			conds = append(conds, cond)
+		}
+		return map[string]interface{}{
+			"parentRef":  map[string]interface{}{"name": name},
+			"conditions": conds,
+		}
+	}
+	accepted := map[string]interface{}{"type": "Accepted", "status": "True"}
+	rejected := map[string]interface{}{"type": "Accepted", "status": "False", "message": "no matching listener"}
+	resolved := map[string]interface{}{"type": "ResolvedRefs", "status": "True"}
+
+	tests := []struct {
+		name        string
+		parents     []interface{}
+		wantStatus  metav1.ConditionStatus
+		wantReason  string
+		wantMessage string
+	}{
+		{
+			name:        "not reported",
+			wantStatus:  metav1.ConditionUnknown,
+			wantReason:  "Pending",
+			wantMessage: "waiting for the Gateways to accept the HTTPRoute",
+		},
+		{
+			name:        "partly reported",
+			parents:     []interface{}{parent("public", accepted, resolved), parent("internal", resolved)},
+			wantStatus:  metav1.ConditionUnknown,
+			wantReason:  "Pending",
+			wantMessage: "waiting for the Gateways to accept the HTTPRoute",
+		},
+		{
+			name:       "accepted by every parent",
+			parents:    []interface{}{parent("public", accepted), parent("internal", resolved, accepted)},
+			wantStatus: metav1.ConditionTrue,
+			wantReason: "Accepted",
+		},
+		{
+			name:        "rejected by a parent",
+			parents:     []interface{}{parent("public", accepted), parent("internal", rejected)},
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  "NotAccepted",
+			wantMessage: "internal: no matching listener",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			route := &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{"parentRefs": []interface{}{
+					map[string]interface{}{"name": "public"},
+					map[string]interface{}{"name": "internal"},
+				}},
+				"status": map[string]interface{}{"parents": tt.parents},
+			}}
+
+			status, reason, message := routeAccepted(route)
+			g.Expect(status).To(Equal(tt.wantStatus))
+			g.Expect(reason).To(Equal(tt.wantReason))
+			g.Expect(message).To(Equal(tt.wantMessage))
+		})
+	}
+}
+
+func TestMutateHTTPRoute(t *testing.T) {
+	port := int32(443)
+	backendRefs := []interface{}{
+		map[string]interface{}{
+			"group":  "",
+			"kind":   "Service",
+			"name":   "sentinel",
+			"port":   int64(sentinelv1alpha1.DefaultPort),
+			"weight": int64(1),
+		},
+	}
+	rules := func(path string) []interface{} {
+		return []interface{}{
+			map[string]interface{}{
+				"matches": []interface{}{
+					map[string]interface{}{
+						"path": map[string]interface{}{"type": "PathPrefix", "value": path},
+					},
+				},
+				"backendRefs": backendRefs,
+			},
+		}
+	}
+
+	tests := []struct {
+		name string
+		spec sentinelv1alpha1.GatewayRouteSpec
+		want map[string]interface{}
+	}{
+		{
+			name: "defaults",
+			spec: sentinelv1alpha1.GatewayRouteSpec{
+				ParentRefs: []sentinelv1alpha1.GatewayParentReference{{Name: "public"}},
+			},
+			want: map[string]interface{}{
+				"parentRefs": []interface{}{
+					map[string]interface{}{"group": gatewayGroup, "kind": "Gateway", "name": "public"},
+				},
+				"rules": rules("/"),
+			},
+		},
+		{
+			name: "every field",
+			spec: sentinelv1alpha1.GatewayRouteSpec{
+				ParentRefs: []sentinelv1alpha1.GatewayParentReference{{
+					Group:       "example.com",
+					Kind:        "ListenerSet",
+					Namespace:   "gateways",
+					Name:        "public",
+					SectionName: "https",
+					Port:        &port,
+				}},
+				Hostnames: []string{"sentinel.example.com"},
+				Path:      "/sentinel",
+			},
+			want: map[string]interface{}{
+				"parentRefs": []interface{}{
+					map[string]interface{}{
+						"group":       "example.com",
+						"kind":        "ListenerSet",
+						"namespace":   "gateways",
+						"name":        "public",
+						"sectionName": "https",
+						"port":        int64(443),
+					},
+				},
+				"hostnames": []interface{}{"sentinel.example.com"},
+				"rules":     rules("/sentinel"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			instance := &sentinelv1alpha1.Dashboard{ObjectMeta: metav1.ObjectMeta{Name: "sentinel"}}
+			instance.Spec.GatewayRoute = &tt.spec
+			route := newHTTPRoute(instance, httpRouteGroupKind.WithVersion("v1"))
+
+			MutateHTTPRoute(instance, route)
+			g.Expect(route.GetLabels()).To(Equal(map[string]string{"app": "sentinel"}))
+			g.Expect(route.Object["spec"]).To(Equal(tt.want))
+			// the rendered route must be valid JSON to be sent to the API server
+			g.Expect(route.MarshalJSON()).NotTo(BeEmpty())
+		})
+	}
+}
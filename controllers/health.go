@@ -2,34 +2,298 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
 )
 
-func (r *DashboardReconciler) GetHealth(ctx context.Context, instance *sentinelv1alpha1.Dashboard) error {
-	config := rest.CopyConfig(r.RestConfig)
+const (
+	defaultHealthCheckPath    = "/version"
+	defaultHealthCheckTimeout = 3 * time.Second
+
+	// contextPathEnv is the env Spring Boot reads the servlet context path of the dashboard from.
+	contextPathEnv = "SERVER_SERVLET_CONTEXT_PATH"
+)
+
+// errUnauthorized is returned by serviceProxy.Do when the dashboard requires a login.
+var errUnauthorized = errors.New("unauthorized")
+
+// HealthChecker checks the health of a dashboard and returns the version it reports.
+type HealthChecker interface {
+	Check(ctx context.Context, instance *sentinelv1alpha1.Dashboard) (string, error)
+}
+
+// newHealthCheckers returns the health checkers of every method. The clients
+// are built once and shared by all the dashboards.
+func newHealthCheckers(reader client.Reader, proxy *serviceProxy) map[sentinelv1alpha1.HealthCheckMethod]HealthChecker {
+	httpClient := &http.Client{Transport: http.DefaultTransport}
+	return map[sentinelv1alpha1.HealthCheckMethod]HealthChecker{
+		sentinelv1alpha1.HealthCheckProxy:  &proxyHealthChecker{proxy: proxy},
+		sentinelv1alpha1.HealthCheckDirect: &directHealthChecker{client: httpClient},
+		sentinelv1alpha1.HealthCheckPod:    &podHealthChecker{reader: reader, client: httpClient},
+	}
+}
+
+// GetHealth checks the health of the dashboard with the method selected in
+// spec.healthCheck and returns the version it reports.
+func (r *DashboardReconciler) GetHealth(ctx context.Context, instance *sentinelv1alpha1.Dashboard) (string, error) {
+	method := sentinelv1alpha1.HealthCheckProxy
+	if instance.Spec.HealthCheck != nil && instance.Spec.HealthCheck.Method != "" {
+		method = instance.Spec.HealthCheck.Method
+	}
+	checker, ok := r.HealthCheckers[method]
+	if !ok {
+		return "", errors.Errorf("unsupported health check method %q", method)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout(instance))
+	defer cancel()
+	version, err := checker.Check(ctx, instance)
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot get health response")
+	}
+	return version, nil
+}
+
+// proxyHealthChecker requests the Service through the service proxy of the API server.
+type proxyHealthChecker struct {
+	proxy *serviceProxy
+}
+
+func (p *proxyHealthChecker) Check(ctx context.Context, instance *sentinelv1alpha1.Dashboard) (string, error) {
+	body, err := p.proxy.Get(ctx, instance, healthCheckPath(instance), nil)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// directHealthChecker requests the Service from the operator.
+type directHealthChecker struct {
+	client *http.Client
+}
+
+func (d *directHealthChecker) Check(ctx context.Context, instance *sentinelv1alpha1.Dashboard) (string, error) {
+	return httpGetVersion(ctx, d.client, dashboardServiceAddress(instance), healthCheckPath(instance))
+}
+
+// podHealthChecker requests every running pod of the dashboard from the
+// operator. It fails when any pod fails, and reports the version of the first one.
+type podHealthChecker struct {
+	reader client.Reader
+	client *http.Client
+}
+
+func (p *podHealthChecker) Check(ctx context.Context, instance *sentinelv1alpha1.Dashboard) (string, error) {
+	var pods corev1.PodList
+	if err := p.reader.List(ctx, &pods, client.InNamespace(instance.Namespace),
+		client.MatchingLabels(dashboardPodLabels(instance))); err != nil {
+		return "", errors.Wrap(err, "cannot list pods")
+	}
+
+	port := strconv.Itoa(int(dashboardContainerPort(instance)))
+	var version string
+	var failed []string
+	checked := 0
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" || !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		checked++
+		v, err := httpGetVersion(ctx, p.client, net.JoinHostPort(pod.Status.PodIP, port), healthCheckPath(instance))
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", pod.Name, err))
+			continue
+		}
+		if version == "" {
+			version = v
+		}
+	}
+	if checked == 0 {
+		return "", errors.New("no running pods")
+	}
+	if len(failed) > 0 {
+		return "", errors.Errorf("%d of %d pods failed: %s", len(failed), checked, strings.Join(failed, "; "))
+	}
+	return version, nil
+}
+
+func httpGetVersion(ctx context.Context, httpClient *http.Client, host, path string) (string, error) {
+	u := url.URL{Scheme: "http", Host: host, Path: path}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("unexpected status %s from %s", resp.Status, u.String())
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// serviceProxy requests the Service of a dashboard through the service proxy of the API server.
+type serviceProxy struct {
+	client *rest.RESTClient
+}
+
+func newServiceProxy(config *rest.Config, scheme *runtime.Scheme) (*serviceProxy, error) {
+	config = rest.CopyConfig(config)
 	config.APIPath = "api"
-	config.NegotiatedSerializer = serializer.NewCodecFactory(r.Scheme)
+	config.NegotiatedSerializer = serializer.NewCodecFactory(scheme)
 	config.GroupVersion = &corev1.SchemeGroupVersion
 	client, err := rest.UnversionedRESTClientFor(config)
 	if err != nil {
-		return errors.Wrap(err, "cannot get rest client")
+		return nil, errors.Wrap(err, "cannot get rest client")
 	}
+	return &serviceProxy{client: client}, nil
+}
 
-	if _, err := client.Get().
+// request builds a request of path, relative to the servlet context path, of the dashboard.
+func (s *serviceProxy) request(verb string, instance *sentinelv1alpha1.Dashboard,
+	path string, params url.Values) *rest.Request {
+	req := s.client.Verb(verb).
 		Resource("services").
 		Namespace(instance.GetNamespace()).
-		Name(instance.Name + ":8080").
+		Name(instance.Name + ":" + strconv.Itoa(int(dashboardServicePort(instance)))).
 		SubResource("proxy").
-		Suffix("/version").
-		DoRaw(ctx); err != nil {
-		return errors.Wrap(err, "cannot get health response")
+		Suffix(path)
+	for key, values := range params {
+		for _, value := range values {
+			req = req.Param(key, value)
+		}
+	}
+	return req
+}
+
+// Get gets path, relative to the servlet context path, from the dashboard.
+func (s *serviceProxy) Get(ctx context.Context, instance *sentinelv1alpha1.Dashboard,
+	path string, params url.Values) ([]byte, error) {
+	return s.request(http.MethodGet, instance, path, params).DoRaw(ctx)
+}
+
+// Do sends a request of path, relative to the servlet context path, to the
+// dashboard with cookies, and returns the body of the response and the
+// cookies it sets. The params of a POST are sent as a form rather than in
+// the URL, which the API server may log. Unlike Get, Do fails with
+// errUnauthorized when the dashboard requires a login.
+func (s *serviceProxy) Do(ctx context.Context, method string, instance *sentinelv1alpha1.Dashboard,
+	path string, params url.Values, cookies []*http.Cookie) ([]byte, []*http.Cookie, error) {
+	var body io.Reader
+	if method == http.MethodPost {
+		body = strings.NewReader(params.Encode())
+		params = nil
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.request(method, instance, path, params).URL().String(), body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	resp, err := s.client.Client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
 	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, nil, errors.Wrapf(errUnauthorized, "request %s", path)
+	case resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices:
+		return nil, nil, errors.Errorf("unexpected status %s from %s", resp.Status, path)
+	}
+	return data, resp.Cookies(), nil
+}
+
+// dashboardServicePort returns the Service port the dashboard is reached at.
+func dashboardServicePort(instance *sentinelv1alpha1.Dashboard) int32 {
+	if len(instance.Spec.Ports) == 0 {
+		return sentinelv1alpha1.DefaultPort
+	}
+	return instance.Spec.Ports[0].Port
+}
 
-	return nil
+// dashboardServiceAddress returns the host:port the dashboard is reached at
+// inside the cluster.
+func dashboardServiceAddress(instance *sentinelv1alpha1.Dashboard) string {
+	host := fmt.Sprintf("%s.%s.svc", instance.Name, instance.Namespace)
+	return net.JoinHostPort(host, strconv.Itoa(int(dashboardServicePort(instance))))
+}
+
+// dashboardContainerPort returns the port the dashboard container listens on.
+func dashboardContainerPort(instance *sentinelv1alpha1.Dashboard) int32 {
+	if ports := newContainerPorts(instance); len(ports) > 0 {
+		return ports[0].ContainerPort
+	}
+	return sentinelv1alpha1.DefaultPort
+}
+
+// dashboardContextPath returns the servlet context path of the dashboard,
+// without the trailing slash.
+func dashboardContextPath(instance *sentinelv1alpha1.Dashboard) string {
+	var contextPath string
+	if instance.Spec.Server != nil {
+		contextPath = instance.Spec.Server.ContextPath
+	}
+	for _, env := range newEnv(instance) {
+		if env.Name == contextPathEnv && env.ValueFrom == nil {
+			contextPath = env.Value
+		}
+	}
+	contextPath = strings.TrimSuffix(contextPath, "/")
+	if contextPath != "" && !strings.HasPrefix(contextPath, "/") {
+		contextPath = "/" + contextPath
+	}
+	return contextPath
+}
+
+// dashboardPath prefixes path with the servlet context path of the dashboard.
+func dashboardPath(instance *sentinelv1alpha1.Dashboard, path string) string {
+	return dashboardContextPath(instance) + path
+}
+
+func healthCheckPath(instance *sentinelv1alpha1.Dashboard) string {
+	path := defaultHealthCheckPath
+	if instance.Spec.HealthCheck != nil && instance.Spec.HealthCheck.Path != "" {
+		path = instance.Spec.HealthCheck.Path
+	}
+	return dashboardPath(instance, path)
+}
+
+func healthCheckTimeout(instance *sentinelv1alpha1.Dashboard) time.Duration {
+	if instance.Spec.HealthCheck != nil && instance.Spec.HealthCheck.TimeoutSeconds != nil {
+		return time.Duration(*instance.Spec.HealthCheck.TimeoutSeconds) * time.Second
+	}
+	return defaultHealthCheckTimeout
 }
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+)
+
+func newTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = sentinelv1alpha1.AddToScheme(scheme)
+	return scheme
+}
+
+// newTestDashboardReconciler returns a reconciler backed by a fake client
+// holding objs.
+func newTestDashboardReconciler(objs ...client.Object) *DashboardReconciler {
+	scheme := newTestScheme()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	return &DashboardReconciler{
+		Client:    c,
+		Scheme:    scheme,
+		Recorder:  record.NewFakeRecorder(100),
+		APIReader: c,
+	}
+}
+
+// newTestTokenServerReconciler returns a reconciler backed by a fake client
+// holding objs.
+func newTestTokenServerReconciler(objs ...client.Object) *TokenServerReconciler {
+	scheme := newTestScheme()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	return &TokenServerReconciler{
+		Client:       c,
+		Scheme:       scheme,
+		Recorder:     record.NewFakeRecorder(100),
+		HTTPClient:   http.DefaultClient,
+		SecretReader: c,
+	}
+}
+
+// newTestRulePublisher returns a publisher backed by a fake client holding
+// objs, which lists the rules by their indexes.
+func newTestRulePublisher(objs ...client.Object) *RulePublisher {
+	c := fake.NewClientBuilder().WithScheme(newTestScheme()).WithObjects(objs...).Build()
+	return &RulePublisher{
+		Client: &indexedClient{
+			Client: c,
+			indexers: map[string]client.IndexerFunc{
+				ruleTargetIndex:    indexRuleTarget,
+				ruleDashboardIndex: indexRuleDashboard,
+			},
+		},
+		Recorder:     record.NewFakeRecorder(100),
+		HTTPClient:   http.DefaultClient,
+		SecretReader: c,
+	}
+}
+
+// indexedClient filters the lists matching fields with indexers, as the
+// cache of the manager does while the fake client ignores the fields.
+type indexedClient struct {
+	client.Client
+	indexers map[string]client.IndexerFunc
+}
+
+func (c *indexedClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := (&client.ListOptions{}).ApplyOptions(opts)
+	selector := listOpts.FieldSelector
+	listOpts.FieldSelector = nil
+	if err := c.Client.List(ctx, list, listOpts); err != nil || selector == nil {
+		return err
+	}
+
+	objs, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	matched := make([]runtime.Object, 0, len(objs))
+	for _, obj := range objs {
+		if c.matches(obj.(client.Object), selector.Requirements()) {
+			matched = append(matched, obj)
+		}
+	}
+	return meta.SetList(list, matched)
+}
+
+func (c *indexedClient) matches(obj client.Object, requirements fields.Requirements) bool {
+	for _, req := range requirements {
+		indexer, ok := c.indexers[req.Field]
+		if !ok {
+			return false
+		}
+		found := false
+		for _, value := range indexer(obj) {
+			found = found || value == req.Value
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
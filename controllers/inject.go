@@ -0,0 +1,302 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+)
+
+const (
+	// PodInjectorPath is the path the PodInjector is served at.
+	PodInjectorPath = "/mutate--v1-pod"
+
+	// injectedDashboardIndex indexes the injected pods by the namespace/name
+	// of their dashboard.
+	injectedDashboardIndex = ".metadata.annotations.injected-dashboard"
+
+	// transportPortName names the port the Sentinel clients serve the
+	// transport API the dashboard and token servers call on.
+	transportPortName = "sentinel-api"
+
+	// transportPortProperty is the system property setting the transport port.
+	transportPortProperty = "csp.sentinel.api.port"
+)
+
+//+kubebuilder:webhook:path=/mutate--v1-pod,mutating=true,failurePolicy=ignore,sideEffects=None,groups="",resources=pods,verbs=create,versions=v1,name=mpod.sentinel.sentinelguard.io,admissionReviewVersions=v1
+
+// PodInjector injects the Sentinel client configuration of a dashboard into
+// the pods annotated with sentinelv1alpha1.InjectAnnotation, or created in a
+// namespace so annotated. Pods that cannot be injected are admitted as they
+// are, with a warning, as the webhook sees every pod outside of the operator
+// and system namespaces.
+type PodInjector struct {
+	Client client.Client
+
+	decoder *admission.Decoder
+}
+
+// InjectDecoder implements admission.DecoderInjector.
+func (i *PodInjector) InjectDecoder(decoder *admission.Decoder) error {
+	i.decoder = decoder
+	return nil
+}
+
+// Handle implements admission.Handler.
+func (i *PodInjector) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var pod corev1.Pod
+	if err := i.decoder.Decode(req, &pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if pod.Labels[sentinelv1alpha1.InjectedLabel] == "true" {
+		return admission.Allowed("already injected")
+	}
+
+	// the namespace of the pod may only be set in the request on creation
+	key, err := i.dashboardKey(ctx, req.Namespace, &pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if key == nil {
+		return admission.Allowed("injection not requested")
+	}
+
+	var dashboard sentinelv1alpha1.Dashboard
+	if err := i.Client.Get(ctx, *key, &dashboard); err != nil {
+		if apierrors.IsNotFound(err) {
+			return admission.Allowed("").WithWarnings(
+				fmt.Sprintf("dashboard %s not found, Sentinel client configuration not injected", key))
+		}
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if err := injectPod(&dashboard, &pod); err != nil {
+		return admission.Allowed("").WithWarnings(
+			fmt.Sprintf("Sentinel client configuration of dashboard %s not injected: %s", key, err))
+	}
+
+	marshaled, err := json.Marshal(&pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+// dashboardKey returns the dashboard named by the inject annotation of the
+// pod, or of its namespace when the pod has none, or nil when the pod is not
+// to be injected.
+func (i *PodInjector) dashboardKey(ctx context.Context, namespace string, pod *corev1.Pod) (*types.NamespacedName, error) {
+	value, ok := pod.Annotations[sentinelv1alpha1.InjectAnnotation]
+	if !ok {
+		var ns corev1.Namespace
+		if err := i.Client.Get(ctx, types.NamespacedName{Name: namespace}, &ns); err != nil {
+			return nil, errors.Wrap(client.IgnoreNotFound(err), "failed getting namespace")
+		}
+		value = ns.Annotations[sentinelv1alpha1.InjectAnnotation]
+	}
+	if value == "" || value == sentinelv1alpha1.InjectDisabled {
+		return nil, nil
+	}
+	if dashboardNamespace, name, ok := strings.Cut(value, "/"); ok {
+		return &types.NamespacedName{Namespace: dashboardNamespace, Name: name}, nil
+	}
+	return &types.NamespacedName{Namespace: namespace, Name: value}, nil
+}
+
+// injectPod renders the Sentinel client options reporting to the dashboard
+// into JAVA_TOOL_OPTIONS of the injected container, ahead of the options set
+// by the pod so that those win, and declares the transport port in effect.
+// The pod is left unchanged when an error is returned.
+func injectPod(dashboard *sentinelv1alpha1.Dashboard, pod *corev1.Pod) error {
+	container := injectedContainer(pod)
+	if container == nil {
+		return errors.Errorf("container %q not found", pod.Annotations[sentinelv1alpha1.InjectContainerAnnotation])
+	}
+	app := podAppName(pod)
+	if app == "" {
+		return errors.Errorf("app name not found, set label %s", sentinelv1alpha1.AppLabel)
+	}
+	for _, env := range container.Env {
+		if env.Name == javaToolOptionsEnv && env.ValueFrom != nil {
+			return errors.Errorf("%s of container %s is set from a source", javaToolOptionsEnv, container.Name)
+		}
+	}
+
+	port, err := transportPort(container)
+	if err != nil {
+		return err
+	}
+
+	options := strings.Join(newSentinelClientProperties(dashboard, app, port), " ")
+	injected := false
+	for j := range container.Env {
+		if env := &container.Env[j]; env.Name == javaToolOptionsEnv {
+			env.Value = strings.TrimSpace(options + " " + env.Value)
+			injected = true
+		}
+	}
+	if !injected {
+		container.Env = append(container.Env, corev1.EnvVar{Name: javaToolOptionsEnv, Value: options})
+	}
+	if !hasContainerPort(container, port) {
+		container.Ports = append(container.Ports, corev1.ContainerPort{
+			Name:          transportPortName,
+			ContainerPort: port,
+			Protocol:      corev1.ProtocolTCP,
+		})
+	}
+
+	if pod.Labels == nil {
+		pod.Labels = map[string]string{}
+	}
+	pod.Labels[sentinelv1alpha1.AppLabel] = app
+	pod.Labels[sentinelv1alpha1.InjectedLabel] = "true"
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[sentinelv1alpha1.InjectedDashboardAnnotation] = dashboard.Namespace + "/" + dashboard.Name
+	return nil
+}
+
+// injectedContainer returns the container named by the inject container
+// annotation of the pod, the first container by default.
+func injectedContainer(pod *corev1.Pod) *corev1.Container {
+	name, ok := pod.Annotations[sentinelv1alpha1.InjectContainerAnnotation]
+	for j := range pod.Spec.Containers {
+		if !ok || pod.Spec.Containers[j].Name == name {
+			return &pod.Spec.Containers[j]
+		}
+	}
+	return nil
+}
+
+// transportPort returns the transport port the Sentinel client of the
+// container serves on: the last csp.sentinel.api.port set in its
+// JAVA_TOOL_OPTIONS, which wins over the injected one, or the default.
+func transportPort(container *corev1.Container) (int32, error) {
+	prefix := systemProperty(transportPortProperty, "")
+	port := int32(sentinelv1alpha1.DefaultTransportPort)
+	for _, env := range container.Env {
+		if env.Name != javaToolOptionsEnv {
+			continue
+		}
+		for _, option := range strings.Fields(env.Value) {
+			if !strings.HasPrefix(option, prefix) {
+				continue
+			}
+			value := strings.TrimPrefix(option, prefix)
+			parsed, err := strconv.ParseInt(value, 10, 32)
+			if err != nil || parsed < 1 || parsed > 65535 {
+				return 0, errors.Errorf("%s of container %s sets an invalid %s %q",
+					javaToolOptionsEnv, container.Name, transportPortProperty, value)
+			}
+			port = int32(parsed)
+		}
+	}
+	return port, nil
+}
+
+func hasContainerPort(container *corev1.Container, port int32) bool {
+	for _, p := range container.Ports {
+		if p.ContainerPort == port {
+			return true
+		}
+	}
+	return false
+}
+
+// podAppName returns the app name of the pod: its app label, or else the
+// name of the workload owning it, which for the ReplicaSets of a Deployment
+// is the Deployment. Only workloads keeping their name across their pods are
+// named after; the pods of other owners, such as the Jobs of a CronJob whose
+// names change on every run, must be labeled.
+func podAppName(pod *corev1.Pod) string {
+	if app := pod.Labels[sentinelv1alpha1.AppLabel]; app != "" {
+		return app
+	}
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		switch owner.Kind {
+		case "ReplicaSet":
+			if hash := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; hash != "" {
+				return strings.TrimSuffix(owner.Name, "-"+hash)
+			}
+			return owner.Name
+		case "StatefulSet", "DaemonSet":
+			return owner.Name
+		default:
+			return ""
+		}
+	}
+	if name := pod.Labels["app.kubernetes.io/name"]; name != "" {
+		return name
+	}
+	return pod.Name
+}
+
+// indexInjectedDashboard is the field indexer for injectedDashboardIndex.
+func indexInjectedDashboard(obj client.Object) []string {
+	if obj.GetLabels()[sentinelv1alpha1.InjectedLabel] != "true" {
+		return nil
+	}
+	if key := obj.GetAnnotations()[sentinelv1alpha1.InjectedDashboardAnnotation]; key != "" {
+		return []string{key}
+	}
+	return nil
+}
+
+// injectedPodToDashboard maps an injected pod to its dashboard.
+func injectedPodToDashboard(obj client.Object) []reconcile.Request {
+	keys := indexInjectedDashboard(obj)
+	if len(keys) == 0 {
+		return nil
+	}
+	namespace, name, ok := strings.Cut(keys[0], "/")
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}}}
+}
+
+// injectedPodChanged filters the pod events down to those that may change
+// the count of the running injected pods.
+var injectedPodChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldPod, ok := e.ObjectOld.(*corev1.Pod)
+		if !ok {
+			return false
+		}
+		newPod, ok := e.ObjectNew.(*corev1.Pod)
+		if !ok {
+			return false
+		}
+		return oldPod.Status.Phase != newPod.Status.Phase ||
+			oldPod.DeletionTimestamp.IsZero() != newPod.DeletionTimestamp.IsZero()
+	},
+	GenericFunc: func(event.GenericEvent) bool { return false },
+}
+
+// countInjectedPods counts the running pods among the injected ones.
+func countInjectedPods(pods []corev1.Pod) int32 {
+	var count int32
+	for _, pod := range pods {
+		if pod.DeletionTimestamp.IsZero() && pod.Status.Phase == corev1.PodRunning {
+			count++
+		}
+	}
+	return count
+}
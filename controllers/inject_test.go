@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+)
+
+func newTestInjectedPod(annotations map[string]string, env ...corev1.EnvVar) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "shop",
+			Name:        "order-service-0",
+			Labels:      map[string]string{sentinelv1alpha1.AppLabel: "order-service"},
+			Annotations: annotations,
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "istio-proxy"},
+			{Name: "app", Env: env},
+		}},
+	}
+}
+
+func TestPodAppName(t *testing.T) {
+	owned := func(kind, name string, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:   name + "-x7k2p",
+			Labels: labels,
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: kind, Name: name, Controller: pointer.Bool(true)},
+			},
+		}}
+	}
+
+	tests := []struct {
+		name string
+		pod  *corev1.Pod
+		want string
+	}{
+		{
+			name: "app label",
+			pod:  owned("Job", "report-27801240", map[string]string{sentinelv1alpha1.AppLabel: "report"}),
+			want: "report",
+		},
+		{
+			name: "deployment",
+			pod:  owned("ReplicaSet", "order-service-5d8f7c9b6", map[string]string{"pod-template-hash": "5d8f7c9b6"}),
+			want: "order-service",
+		},
+		{name: "bare replica set", pod: owned("ReplicaSet", "order-service", nil), want: "order-service"},
+		{name: "stateful set", pod: owned("StatefulSet", "order-service", nil), want: "order-service"},
+		{name: "daemon set", pod: owned("DaemonSet", "node-agent", nil), want: "node-agent"},
+		{name: "job", pod: owned("Job", "report-27801240", nil)},
+		{name: "other owner", pod: owned("Rollout", "order-service", nil)},
+		{
+			name: "app.kubernetes.io/name label",
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:   "debug",
+				Labels: map[string]string{"app.kubernetes.io/name": "order-service"},
+			}},
+			want: "order-service",
+		},
+		{name: "pod name", pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "debug"}}, want: "debug"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(podAppName(tt.pod)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestInjectPod(t *testing.T) {
+	dashboard := &sentinelv1alpha1.Dashboard{ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "sentinel"}}
+	dashboard.SetDefaults("")
+	app := map[string]string{sentinelv1alpha1.InjectContainerAnnotation: "app"}
+	options := "-Dproject.name=order-service -Dcsp.sentinel.dashboard.server=sentinel.monitoring.svc:8080"
+
+	tests := []struct {
+		name        string
+		pod         *corev1.Pod
+		wantOptions string
+		wantPort    int32
+		wantErr     string
+	}{
+		{
+			name:        "first container",
+			pod:         newTestInjectedPod(nil),
+			wantOptions: options + " -Dcsp.sentinel.api.port=8719",
+			wantPort:    8719,
+		},
+		{
+			name:        "options of the pod win",
+			pod:         newTestInjectedPod(app, corev1.EnvVar{Name: javaToolOptionsEnv, Value: "-Xmx512m -Dcsp.sentinel.api.port=8720"}),
+			wantOptions: options + " -Dcsp.sentinel.api.port=8720 -Xmx512m -Dcsp.sentinel.api.port=8720",
+			wantPort:    8720,
+		},
+		{
+			name:    "container not found",
+			pod:     newTestInjectedPod(map[string]string{sentinelv1alpha1.InjectContainerAnnotation: "web"}),
+			wantErr: `container "web" not found`,
+		},
+		{
+			name: "options set from a source",
+			pod: newTestInjectedPod(app, corev1.EnvVar{
+				Name:      javaToolOptionsEnv,
+				ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{Key: "options"}},
+			}),
+			wantErr: "JAVA_TOOL_OPTIONS of container app is set from a source",
+		},
+		{
+			name:    "invalid port",
+			pod:     newTestInjectedPod(app, corev1.EnvVar{Name: javaToolOptionsEnv, Value: "-Dcsp.sentinel.api.port=api"}),
+			wantErr: `sets an invalid csp.sentinel.api.port "api"`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			original := tt.pod.DeepCopy()
+
+			err := injectPod(dashboard, tt.pod)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				g.Expect(tt.pod).To(Equal(original))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			container := injectedContainer(tt.pod)
+			g.Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: javaToolOptionsEnv, Value: tt.wantOptions}))
+			g.Expect(container.Ports).To(ConsistOf(corev1.ContainerPort{
+				Name:          transportPortName,
+				ContainerPort: tt.wantPort,
+				Protocol:      corev1.ProtocolTCP,
+			}))
+			g.Expect(tt.pod.Labels).To(HaveKeyWithValue(sentinelv1alpha1.InjectedLabel, "true"))
+			g.Expect(tt.pod.Annotations).To(HaveKeyWithValue(sentinelv1alpha1.InjectedDashboardAnnotation, "monitoring/sentinel"))
+		})
+	}
+}
+
+func TestDashboardKey(t *testing.T) {
+	injector := &PodInjector{Client: newTestDashboardReconciler(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        "shop",
+			Annotations: map[string]string{sentinelv1alpha1.InjectAnnotation: "monitoring/sentinel"},
+		}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "billing"}},
+	).Client}
+
+	tests := []struct {
+		name       string
+		namespace  string
+		annotation *string
+		want       *types.NamespacedName
+	}{
+		{
+			name:       "dashboard in the namespace of the pod",
+			namespace:  "billing",
+			annotation: pointer.String("sentinel"),
+			want:       &types.NamespacedName{Namespace: "billing", Name: "sentinel"},
+		},
+		{
+			name:       "dashboard in another namespace",
+			namespace:  "billing",
+			annotation: pointer.String("monitoring/sentinel"),
+			want:       &types.NamespacedName{Namespace: "monitoring", Name: "sentinel"},
+		},
+		{
+			name:      "namespace annotation",
+			namespace: "shop",
+			want:      &types.NamespacedName{Namespace: "monitoring", Name: "sentinel"},
+		},
+		{name: "disabled on the pod", namespace: "shop", annotation: pointer.String(sentinelv1alpha1.InjectDisabled)},
+		{name: "not requested", namespace: "billing"},
+		{name: "namespace not found", namespace: "unknown"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "order-service-0"}}
+			if tt.annotation != nil {
+				pod.Annotations = map[string]string{sentinelv1alpha1.InjectAnnotation: *tt.annotation}
+			}
+
+			key, err := injector.dashboardKey(context.Background(), tt.namespace, pod)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(key).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestInjectedPodChanged(t *testing.T) {
+	running := &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}}
+	deleting := running.DeepCopy()
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	relabeled := running.DeepCopy()
+	relabeled.Labels = map[string]string{"version": "2"}
+	succeeded := running.DeepCopy()
+	succeeded.Status.Phase = corev1.PodSucceeded
+
+	g := NewWithT(t)
+	g.Expect(injectedPodChanged.Create(event.CreateEvent{Object: running})).To(BeTrue())
+	g.Expect(injectedPodChanged.Delete(event.DeleteEvent{Object: running})).To(BeTrue())
+	g.Expect(injectedPodChanged.Update(event.UpdateEvent{ObjectOld: running, ObjectNew: succeeded})).To(BeTrue())
+	g.Expect(injectedPodChanged.Update(event.UpdateEvent{ObjectOld: running, ObjectNew: deleting})).To(BeTrue())
+	g.Expect(injectedPodChanged.Update(event.UpdateEvent{ObjectOld: running, ObjectNew: relabeled})).To(BeFalse())
+	g.Expect(injectedPodChanged.Generic(event.GenericEvent{Object: running})).To(BeFalse())
+}
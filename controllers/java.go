@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+)
+
+const (
+	// javaToolOptionsEnv is read by the JVM whatever the entrypoint of the image is.
+	javaToolOptionsEnv = "JAVA_TOOL_OPTIONS"
+
+	// authPasswordEnv holds the login password. Spring resolves
+	// sentinel.dashboard.auth.password from it, so the password is not
+	// rendered into JAVA_TOOL_OPTIONS, which the JVM prints on startup.
+	authPasswordEnv = "SENTINEL_DASHBOARD_AUTH_PASSWORD"
+)
+
+// garbageCollectorOptions maps spec.jvm.garbageCollector to the JVM option selecting it.
+var garbageCollectorOptions = map[string]string{
+	"G1":       "-XX:+UseG1GC",
+	"Parallel": "-XX:+UseParallelGC",
+	"Serial":   "-XX:+UseSerialGC",
+	"Z":        "-XX:+UseZGC",
+}
+
+// newJavaEnv renders spec.jvm and spec.server into JAVA_TOOL_OPTIONS. The
+// log directory is pointed at the data volume, as the root filesystem is
+// read-only by default, and server.port at the container port.
+func newJavaEnv(sentinel *sentinelv1alpha1.Dashboard) []corev1.EnvVar {
+	var env []corev1.EnvVar
+	if server := sentinel.Spec.Server; server != nil && server.Auth != nil {
+		ref := server.Auth.PasswordSecretRef
+		env = append(env, secretEnv(authPasswordEnv, ref.LocalObjectReference, ref.Key))
+	}
+	return append(env, corev1.EnvVar{
+		Name:  javaToolOptionsEnv,
+		Value: strings.Join(append(newJVMOptions(sentinel), newSystemProperties(sentinel)...), " "),
+	})
+}
+
+// newJVMOptions renders spec.jvm. The maximum heap size defaults to
+// heapPercentage of the memory limit of the container.
+func newJVMOptions(sentinel *sentinelv1alpha1.Dashboard) []string {
+	jvm := sentinel.Spec.JVM
+	if jvm == nil {
+		jvm = &sentinelv1alpha1.JVMSpec{}
+	}
+
+	var options []string
+	if maxHeap, ok := sentinel.MaxHeapBytes(); ok {
+		options = append(options, "-Xmx"+heapSize(maxHeap))
+	}
+	if jvm.InitialHeapSize != nil {
+		options = append(options, "-Xms"+heapSize(jvm.InitialHeapSize.Value()))
+	}
+	if option, ok := garbageCollectorOptions[jvm.GarbageCollector]; ok {
+		options = append(options, option)
+	}
+	return append(options, jvm.Options...)
+}
+
+// heapSize formats bytes in the unit of the JVM heap options, rounding down
+// to the mebibyte.
+func heapSize(bytes int64) string {
+	mebibytes := bytes / (1 << 20)
+	if mebibytes < 1 {
+		mebibytes = 1
+	}
+	return fmt.Sprintf("%dm", mebibytes)
+}
+
+// newSystemProperties renders spec.server into -D options, followed by the
+// extra properties sorted by key.
+func newSystemProperties(sentinel *sentinelv1alpha1.Dashboard) []string {
+	properties := []string{
+		systemProperty("server.port", fmt.Sprint(dashboardContainerPort(sentinel))),
+		systemProperty("csp.sentinel.log.dir", path.Join(dataMountPath(sentinel), "logs")),
+	}
+
+	server := sentinel.Spec.Server
+	if server == nil {
+		return properties
+	}
+	if server.ContextPath != "" {
+		properties = append(properties, systemProperty("server.servlet.context-path", server.ContextPath))
+	}
+	if server.SessionTimeout != nil {
+		seconds := int64(server.SessionTimeout.Duration.Seconds())
+		properties = append(properties, systemProperty("server.servlet.session.timeout", fmt.Sprintf("%ds", seconds)))
+	}
+	if server.Auth != nil {
+		properties = append(properties, systemProperty("sentinel.dashboard.auth.username", server.Auth.Username))
+	}
+
+	keys := make([]string, 0, len(server.Properties))
+	for key := range server.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		properties = append(properties, systemProperty(key, server.Properties[key]))
+	}
+	return properties
+}
+
+func systemProperty(key, value string) string {
+	return "-D" + key + "=" + value
+}
+
+// newSentinelClientProperties renders the -D options of a Sentinel client
+// named project, reporting to the dashboard and serving the transport API on
+// apiPort.
+func newSentinelClientProperties(instance *sentinelv1alpha1.Dashboard, project string, apiPort int32) []string {
+	properties := []string{
+		systemProperty("project.name", project),
+		systemProperty("csp.sentinel.dashboard.server", dashboardServiceAddress(instance)),
+		systemProperty(transportPortProperty, fmt.Sprint(apiPort)),
+	}
+	if contextPath := dashboardContextPath(instance); contextPath != "" {
+		properties = append(properties, systemProperty("csp.sentinel.heartbeat.api.path", contextPath+"/registry/machine"))
+	}
+	return properties
+}
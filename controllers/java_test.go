@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+)
+
+func TestHeapSize(t *testing.T) {
+	tests := []struct {
+		bytes int64
+		want  string
+	}{
+		{bytes: 0, want: "1m"},
+		{bytes: 1 << 10, want: "1m"},
+		{bytes: 1 << 20, want: "1m"},
+		{bytes: 768 << 20, want: "768m"},
+		{bytes: 1<<30 + 1<<19, want: "1024m"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.want, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(heapSize(tt.bytes)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestNewJVMOptions(t *testing.T) {
+	quantity := func(s string) *resource.Quantity {
+		q := resource.MustParse(s)
+		return &q
+	}
+	percentage := int32(50)
+	limited := corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")}}
+
+	tests := []struct {
+		name      string
+		resources corev1.ResourceRequirements
+		jvm       *sentinelv1alpha1.JVMSpec
+		want      []string
+	}{
+		{name: "no memory limit"},
+		{
+			name:      "default percentage of the memory limit",
+			resources: limited,
+			want:      []string{"-Xmx768m"},
+		},
+		{
+			name:      "heap percentage",
+			resources: limited,
+			jvm:       &sentinelv1alpha1.JVMSpec{HeapPercentage: &percentage},
+			want:      []string{"-Xmx512m"},
+		},
+		{
+			name:      "max heap size wins",
+			resources: limited,
+			jvm:       &sentinelv1alpha1.JVMSpec{HeapPercentage: &percentage, MaxHeapSize: quantity("900Mi")},
+			want:      []string{"-Xmx900m"},
+		},
+		{
+			name:      "every option",
+			resources: limited,
+			jvm: &sentinelv1alpha1.JVMSpec{
+				InitialHeapSize:  quantity("256Mi"),
+				GarbageCollector: "G1",
+				Options:          []string{"-XX:+ExitOnOutOfMemoryError"},
+			},
+			want: []string{"-Xmx768m", "-Xms256m", "-XX:+UseG1GC", "-XX:+ExitOnOutOfMemoryError"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			instance := &sentinelv1alpha1.Dashboard{ObjectMeta: metav1.ObjectMeta{Name: "sentinel"}}
+			instance.Spec.Resources = tt.resources
+			instance.Spec.JVM = tt.jvm
+			g.Expect(newJVMOptions(instance)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestNewSystemProperties(t *testing.T) {
+	defaults := []string{"-Dserver.port=8080", "-Dcsp.sentinel.log.dir=/var/lib/sentinel/logs"}
+
+	tests := []struct {
+		name    string
+		storage *sentinelv1alpha1.StorageSpec
+		server  *sentinelv1alpha1.ServerSpec
+		want    []string
+	}{
+		{name: "defaults", want: defaults},
+		{
+			name:    "log dir on the storage mount path",
+			storage: &sentinelv1alpha1.StorageSpec{MountPath: "/data"},
+			want:    []string{"-Dserver.port=8080", "-Dcsp.sentinel.log.dir=/data/logs"},
+		},
+		{
+			name: "server",
+			server: &sentinelv1alpha1.ServerSpec{
+				ContextPath:    "/sentinel",
+				SessionTimeout: &metav1.Duration{Duration: 30 * time.Minute},
+				Auth:           &sentinelv1alpha1.DashboardAuthSpec{Username: "admin"},
+				Properties: map[string]string{
+					"sentinel.dashboard.app.hideAppNoMachineMillis": "60000",
+					"logging.level.root":                            "warn",
+				},
+			},
+			want: append(defaults,
+				"-Dserver.servlet.context-path=/sentinel",
+				"-Dserver.servlet.session.timeout=1800s",
+				"-Dsentinel.dashboard.auth.username=admin",
+				"-Dlogging.level.root=warn",
+				"-Dsentinel.dashboard.app.hideAppNoMachineMillis=60000",
+			),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			instance := &sentinelv1alpha1.Dashboard{ObjectMeta: metav1.ObjectMeta{Name: "sentinel"}}
+			instance.SetDefaults("")
+			instance.Spec.Storage = tt.storage
+			instance.Spec.Server = tt.server
+			g.Expect(newSystemProperties(instance)).To(Equal(tt.want))
+		})
+	}
+}
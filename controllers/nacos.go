@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/nacos"
+)
+
+// errUnsupportedDatasource is returned for the dashboards whose rules the
+// operator cannot publish, because they have no Nacos datasource.
+var errUnsupportedDatasource = errors.New("the dashboard has no nacos datasource")
+
+// newNacosClient returns a client of the Nacos datasource of the dashboard,
+// logging in with the credentials of its Secret.
+func newNacosClient(ctx context.Context, reader client.Reader, httpClient *http.Client,
+	instance *sentinelv1alpha1.Dashboard) (*nacos.Client, error) {
+	if instance.Spec.Datasource == nil || instance.Spec.Datasource.Nacos == nil {
+		return nil, errUnsupportedDatasource
+	}
+
+	spec := instance.Spec.Datasource.Nacos
+	config := nacos.Config{
+		ServerAddr:  spec.ServerAddr,
+		Namespace:   spec.Namespace,
+		Group:       spec.Group,
+		ContextPath: spec.ContextPath,
+	}
+	if creds := spec.Credentials; creds != nil {
+		var secret corev1.Secret
+		key := types.NamespacedName{Namespace: instance.Namespace, Name: creds.SecretRef.Name}
+		if err := reader.Get(ctx, key, &secret); err != nil {
+			return nil, errors.Wrapf(err, "failed getting secret %s", key)
+		}
+		config.Username = string(secret.Data[credentialsKey(creds.UsernameKey, defaultUsernameKey)])
+		config.Password = string(secret.Data[credentialsKey(creds.PasswordKey, defaultPasswordKey)])
+	}
+	return nacos.NewClient(httpClient, config), nil
+}
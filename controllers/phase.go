@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/event"
+)
+
+// UpdatePhase derives the phase from the conditions and the rollout state of
+// the owned workload, and records an event when the phase changes.
+func (r *DashboardReconciler) UpdatePhase(ctx context.Context, instance *sentinelv1alpha1.Dashboard) error {
+	logger := log.FromContext(ctx)
+
+	workload, err := r.getWorkloadState(ctx, instance)
+	if err != nil {
+		return err
+	}
+
+	previous := instance.Status.Phase
+	phase := r.nextPhase(ctx, instance, workload)
+	if phase == previous {
+		return nil
+	}
+	instance.Status.Phase = phase
+
+	logger.Info("phase changed", "from", previous, "to", phase)
+	eventType := corev1.EventTypeNormal
+	if phase == sentinelv1alpha1.PhaseFailed || phase == sentinelv1alpha1.PhaseNotReady {
+		eventType = corev1.EventTypeWarning
+	}
+	r.Recorder.Eventf(instance, eventType,
+		string(event.DashboardPhase), "Dashboard %s phase changed from %q to %q", instance.Namespace+"/"+instance.Name, previous, phase)
+	return nil
+}
+
+// nextPhase implements the phase state machine:
+//
+//	any                          -> Deleting   the dashboard is being deleted
+//	any                          -> Failed     resources failed to apply, the dashboard was not ready
+//	                                           within the readiness timeout, or the rollout exceeded its deadline
+//	""                           -> Waiting    the workload has not been created yet
+//	Running/NotReady/Upgrading   -> Upgrading  a new revision is rolling out
+//	""/Waiting/Failed            -> Waiting    the first revision is rolling out
+//	any                          -> Running    rolled out and the health check passed
+//	Running/NotReady/Upgrading   -> NotReady   rolled out but the health check failed
+//	""/Waiting/Failed            -> Waiting    rolled out but never became healthy
+func (r *DashboardReconciler) nextPhase(ctx context.Context, instance *sentinelv1alpha1.Dashboard, workload workloadState) sentinelv1alpha1.Phase {
+	if !instance.DeletionTimestamp.IsZero() {
+		return sentinelv1alpha1.PhaseDeleting
+	}
+	if r.GetCondition(ctx, instance, sentinelv1alpha1.AppliedConditionType).Status == metav1.ConditionFalse {
+		return sentinelv1alpha1.PhaseFailed
+	}
+	if r.GetCondition(ctx, instance, sentinelv1alpha1.ReadyConditionType).Reason == readinessTimeoutReason {
+		return sentinelv1alpha1.PhaseFailed
+	}
+	if !workload.exists {
+		return sentinelv1alpha1.PhaseWaiting
+	}
+	if workload.progressDeadlineExceeded {
+		return sentinelv1alpha1.PhaseFailed
+	}
+
+	wasUp := false
+	switch instance.Status.Phase {
+	case sentinelv1alpha1.PhaseRunning, sentinelv1alpha1.PhaseNotReady, sentinelv1alpha1.PhaseUpgrading:
+		wasUp = true
+	}
+
+	if !workload.rolledOut {
+		if wasUp {
+			return sentinelv1alpha1.PhaseUpgrading
+		}
+		return sentinelv1alpha1.PhaseWaiting
+	}
+	if r.GetCondition(ctx, instance, sentinelv1alpha1.ReadyConditionType).Status == metav1.ConditionTrue {
+		return sentinelv1alpha1.PhaseRunning
+	}
+	if wasUp {
+		return sentinelv1alpha1.PhaseNotReady
+	}
+	return sentinelv1alpha1.PhaseWaiting
+}
+
+// deploymentRolledOut reports whether the latest revision of the deployment
+// is fully rolled out, in the same way as kubectl rollout status.
+func deploymentRolledOut(deploy *appsv1.Deployment) bool {
+	if deploy.Generation > deploy.Status.ObservedGeneration {
+		return false
+	}
+	replicas := int32(1)
+	if deploy.Spec.Replicas != nil {
+		replicas = *deploy.Spec.Replicas
+	}
+	return deploy.Status.UpdatedReplicas >= replicas &&
+		deploy.Status.Replicas <= deploy.Status.UpdatedReplicas &&
+		deploy.Status.AvailableReplicas >= deploy.Status.UpdatedReplicas
+}
+
+func deploymentProgressDeadlineExceeded(deploy *appsv1.Deployment) bool {
+	for _, cond := range deploy.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing {
+			return cond.Status == corev1.ConditionFalse && cond.Reason == "ProgressDeadlineExceeded"
+		}
+	}
+	return false
+}
@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+)
+
+func TestNextPhase(t *testing.T) {
+	rolledOut := workloadState{exists: true, rolledOut: true}
+	rollingOut := workloadState{exists: true}
+	condition := func(conditionType sentinelv1alpha1.DashboardConditionType, status metav1.ConditionStatus,
+		reason string) sentinelv1alpha1.DashboardCondition {
+		return sentinelv1alpha1.DashboardCondition{Type: string(conditionType), Status: status, Reason: reason}
+	}
+	applied := condition(sentinelv1alpha1.AppliedConditionType, metav1.ConditionTrue, "")
+	ready := condition(sentinelv1alpha1.ReadyConditionType, metav1.ConditionTrue, "")
+	notReady := condition(sentinelv1alpha1.ReadyConditionType, metav1.ConditionFalse, "HealthCheckFailed")
+
+	tests := []struct {
+		name       string
+		deleting   bool
+		previous   sentinelv1alpha1.Phase
+		conditions []sentinelv1alpha1.DashboardCondition
+		workload   workloadState
+		want       sentinelv1alpha1.Phase
+	}{
+		{
+			name:     "deleting",
+			deleting: true,
+			previous: sentinelv1alpha1.PhaseRunning,
+			workload: rolledOut,
+			want:     sentinelv1alpha1.PhaseDeleting,
+		},
+		{
+			name:       "not applied",
+			previous:   sentinelv1alpha1.PhaseRunning,
+			conditions: []sentinelv1alpha1.DashboardCondition{condition(sentinelv1alpha1.AppliedConditionType, metav1.ConditionFalse, "MutateService")},
+			workload:   rolledOut,
+			want:       sentinelv1alpha1.PhaseFailed,
+		},
+		{
+			name:       "readiness timeout",
+			previous:   sentinelv1alpha1.PhaseWaiting,
+			conditions: []sentinelv1alpha1.DashboardCondition{applied, condition(sentinelv1alpha1.ReadyConditionType, metav1.ConditionFalse, readinessTimeoutReason)},
+			workload:   rolledOut,
+			want:       sentinelv1alpha1.PhaseFailed,
+		},
+		{
+			name:       "progress deadline exceeded",
+			previous:   sentinelv1alpha1.PhaseUpgrading,
+			conditions: []sentinelv1alpha1.DashboardCondition{applied},
+			workload:   workloadState{exists: true, progressDeadlineExceeded: true},
+			want:       sentinelv1alpha1.PhaseFailed,
+		},
+		{
+			name:       "workload not created",
+			conditions: []sentinelv1alpha1.DashboardCondition{applied},
+			want:       sentinelv1alpha1.PhaseWaiting,
+		},
+		{
+			name:       "first revision rolling out",
+			previous:   sentinelv1alpha1.PhaseWaiting,
+			conditions: []sentinelv1alpha1.DashboardCondition{applied},
+			workload:   rollingOut,
+			want:       sentinelv1alpha1.PhaseWaiting,
+		},
+		{
+			name:       "new revision rolling out",
+			previous:   sentinelv1alpha1.PhaseRunning,
+			conditions: []sentinelv1alpha1.DashboardCondition{applied, ready},
+			workload:   rollingOut,
+			want:       sentinelv1alpha1.PhaseUpgrading,
+		},
+		{
+			name:       "rolled out and ready",
+			previous:   sentinelv1alpha1.PhaseUpgrading,
+			conditions: []sentinelv1alpha1.DashboardCondition{applied, ready},
+			workload:   rolledOut,
+			want:       sentinelv1alpha1.PhaseRunning,
+		},
+		{
+			name:       "recovered from a failure",
+			previous:   sentinelv1alpha1.PhaseFailed,
+			conditions: []sentinelv1alpha1.DashboardCondition{applied, ready},
+			workload:   rolledOut,
+			want:       sentinelv1alpha1.PhaseRunning,
+		},
+		{
+			name:       "health check failing after running",
+			previous:   sentinelv1alpha1.PhaseRunning,
+			conditions: []sentinelv1alpha1.DashboardCondition{applied, notReady},
+			workload:   rolledOut,
+			want:       sentinelv1alpha1.PhaseNotReady,
+		},
+		{
+			name:       "never became healthy",
+			previous:   sentinelv1alpha1.PhaseWaiting,
+			conditions: []sentinelv1alpha1.DashboardCondition{applied, notReady},
+			workload:   rolledOut,
+			want:       sentinelv1alpha1.PhaseWaiting,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			instance := &sentinelv1alpha1.Dashboard{}
+			if tt.deleting {
+				now := metav1.Now()
+				instance.DeletionTimestamp = &now
+			}
+			instance.Status.Phase = tt.previous
+			instance.Status.Conditions = tt.conditions
+			r := &DashboardReconciler{}
+			g.Expect(r.nextPhase(context.Background(), instance, tt.workload)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestDeploymentRolledOut(t *testing.T) {
+	two := int32(2)
+	tests := []struct {
+		name   string
+		deploy appsv1.Deployment
+		want   bool
+	}{
+		{
+			name: "rolled out",
+			deploy: appsv1.Deployment{
+				Spec:   appsv1.DeploymentSpec{Replicas: &two},
+				Status: appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+			},
+			want: true,
+		},
+		{
+			name: "spec not observed yet",
+			deploy: appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+			},
+		},
+		{
+			name: "old pods still running",
+			deploy: appsv1.Deployment{
+				Spec:   appsv1.DeploymentSpec{Replicas: &two},
+				Status: appsv1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 2, AvailableReplicas: 3},
+			},
+		},
+		{
+			name: "updated pods not available",
+			deploy: appsv1.Deployment{
+				Status: appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			NewWithT(t).Expect(deploymentRolledOut(&tt.deploy)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestDeploymentProgressDeadlineExceeded(t *testing.T) {
+	g := NewWithT(t)
+	deploy := &appsv1.Deployment{}
+	g.Expect(deploymentProgressDeadlineExceeded(deploy)).To(BeFalse())
+
+	deploy.Status.Conditions = []appsv1.DeploymentCondition{{
+		Type:   appsv1.DeploymentProgressing,
+		Status: corev1.ConditionFalse,
+		Reason: "ProgressDeadlineExceeded",
+	}}
+	g.Expect(deploymentProgressDeadlineExceeded(deploy)).To(BeTrue())
+}
+
+func TestReadinessRequeueAfter(t *testing.T) {
+	tests := []struct {
+		name    string
+		waited  time.Duration
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{name: "just started", waited: 0, timeout: 10 * time.Minute, want: minReadinessRequeue},
+		{name: "doubles the wait", waited: 30 * time.Second, timeout: 10 * time.Minute, want: 30 * time.Second},
+		{name: "capped", waited: 5 * time.Minute, timeout: 10 * time.Minute, want: maxReadinessRequeue},
+		{name: "checks at the timeout", waited: 9 * time.Minute, timeout: 10 * time.Minute, want: time.Minute},
+		{name: "timed out", waited: 11 * time.Minute, timeout: 10 * time.Minute, want: maxReadinessRequeue},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(readinessRequeueAfter(tt.waited, tt.timeout)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestUpdateReadyStatus(t *testing.T) {
+	longAgo := metav1.NewTime(time.Now().Add(-time.Hour))
+	notReady := func(generation int64, reason string) sentinelv1alpha1.DashboardCondition {
+		return sentinelv1alpha1.DashboardCondition{
+			Type:               string(sentinelv1alpha1.ReadyConditionType),
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: generation,
+			LastTransitionTime: longAgo,
+			Reason:             reason,
+		}
+	}
+
+	tests := []struct {
+		name       string
+		conditions []sentinelv1alpha1.DashboardCondition
+		healthErr  error
+		wantReason string
+		wantRetry  bool
+	}{
+		{
+			name:       "healthy",
+			conditions: []sentinelv1alpha1.DashboardCondition{notReady(2, "HealthCheckFailed")},
+		},
+		{
+			name:       "starts waiting",
+			healthErr:  errors.New("connection refused"),
+			wantReason: "HealthCheckFailed",
+			wantRetry:  true,
+		},
+		{
+			name:       "times out",
+			conditions: []sentinelv1alpha1.DashboardCondition{notReady(2, "HealthCheckFailed")},
+			healthErr:  errors.New("connection refused"),
+			wantReason: readinessTimeoutReason,
+			wantRetry:  true,
+		},
+		{
+			name:       "new generation restarts the wait",
+			conditions: []sentinelv1alpha1.DashboardCondition{notReady(1, readinessTimeoutReason)},
+			healthErr:  errors.New("connection refused"),
+			wantReason: "HealthCheckFailed",
+			wantRetry:  true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			instance := &sentinelv1alpha1.Dashboard{ObjectMeta: metav1.ObjectMeta{Name: "sentinel", Generation: 2}}
+			instance.Status.Conditions = tt.conditions
+			r := newTestDashboardReconciler()
+
+			requeueAfter, err := r.UpdateReadyStatus(context.Background(), instance, "1.8.6", tt.healthErr)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(requeueAfter > 0).To(Equal(tt.wantRetry))
+
+			cond := r.GetCondition(context.Background(), instance, sentinelv1alpha1.ReadyConditionType)
+			g.Expect(cond.ObservedGeneration).To(Equal(instance.Generation))
+			g.Expect(cond.Reason).To(Equal(tt.wantReason))
+			if tt.healthErr == nil {
+				g.Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+				g.Expect(instance.Status.Version).To(Equal("1.8.6"))
+			} else {
+				g.Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+			}
+		})
+	}
+}
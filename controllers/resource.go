@@ -1,11 +1,17 @@
 package controllers
 
 import (
+	"fmt"
 	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
 )
@@ -17,18 +23,20 @@ func MutateService(instance *sentinelv1alpha1.Dashboard, svc *corev1.Service) {
 
 	ports := []corev1.ServicePort{
 		{
-			Name:     "http",
-			Port:     instance.Spec.Ports[0].Port,
-			Protocol: "TCP",
+			Name:       "http",
+			Port:       instance.Spec.Ports[0].Port,
+			Protocol:   "TCP",
+			TargetPort: intstr.FromString("http"),
 		},
 	}
 	if strings.EqualFold(string(instance.Spec.Type), "NodePort") || strings.EqualFold(string(instance.Spec.Type), "LoadBalancer") {
 		ports = []corev1.ServicePort{
 			{
-				Name:     "http",
-				Port:     instance.Spec.Ports[0].Port,
-				Protocol: "TCP",
-				NodePort: instance.Spec.Ports[0].NodePort,
+				Name:       "http",
+				Port:       instance.Spec.Ports[0].Port,
+				Protocol:   "TCP",
+				NodePort:   instance.Spec.Ports[0].NodePort,
+				TargetPort: intstr.FromString("http"),
 			},
 		}
 	}
@@ -42,45 +50,618 @@ func MutateService(instance *sentinelv1alpha1.Dashboard, svc *corev1.Service) {
 	}
 }
 
+// headlessServiceSuffix is appended to the name of the dashboard to name the
+// headless Service governing the pods of a StatefulSet.
+const headlessServiceSuffix = "-headless"
+
+func headlessServiceName(instance *sentinelv1alpha1.Dashboard) string {
+	return instance.Name + headlessServiceSuffix
+}
+
+// MutateHeadlessService renders the Service governing the pods of a
+// StatefulSet, which gives every pod a stable DNS name.
+func MutateHeadlessService(instance *sentinelv1alpha1.Dashboard, svc *corev1.Service) {
+	svc.Labels = map[string]string{
+		"app": instance.Name,
+	}
+	svc.Spec = corev1.ServiceSpec{
+		ClusterIP: corev1.ClusterIPNone,
+		Selector: map[string]string{
+			"app": instance.Name,
+		},
+		Ports: []corev1.ServicePort{
+			{
+				Name:       "http",
+				Port:       instance.Spec.Ports[0].Port,
+				Protocol:   "TCP",
+				TargetPort: intstr.FromString("http"),
+			},
+		},
+	}
+}
+
 func MutateDeployment(instance *sentinelv1alpha1.Dashboard, deploy *appsv1.Deployment) {
 	labels := map[string]string{"app": instance.Name}
+	replicas := instance.Spec.Replicas
+	if instance.Spec.Autoscaling != nil && deploy.Spec.Replicas != nil {
+		// the HorizontalPodAutoscaler owns the replicas once the deployment exists
+		replicas = deploy.Spec.Replicas
+	}
+	var strategy appsv1.DeploymentStrategy
+	if storage := instance.Spec.Storage; storage != nil && !storage.HasAccessMode(corev1.ReadWriteMany) {
+		// the old pod has to release the volume before the new one can mount it
+		strategy.Type = appsv1.RecreateDeploymentStrategyType
+	}
+	template := newPodTemplate(instance)
+	if instance.Spec.Storage != nil {
+		template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+			Name: dataVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: dataClaimName(instance)},
+			},
+		})
+	}
 	deploy.Spec = appsv1.DeploymentSpec{
-		Replicas: instance.Spec.Replicas,
-		Template: corev1.PodTemplateSpec{
-			ObjectMeta: metav1.ObjectMeta{
-				Labels: labels,
+		Replicas: replicas,
+		Template: template,
+		Selector: &metav1.LabelSelector{MatchLabels: labels},
+		Strategy: strategy,
+	}
+}
+
+// MutateStatefulSet renders the StatefulSet of the dashboard. The volume claim
+// templates of an existing StatefulSet are immutable, so they are only set on
+// creation.
+func MutateStatefulSet(instance *sentinelv1alpha1.Dashboard, sts *appsv1.StatefulSet) {
+	labels := map[string]string{"app": instance.Name}
+	replicas := instance.Spec.Replicas
+	if instance.Spec.Autoscaling != nil && sts.Spec.Replicas != nil {
+		// the HorizontalPodAutoscaler owns the replicas once the statefulset exists
+		replicas = sts.Spec.Replicas
+	}
+	// the claim templates and the service name of a statefulset are immutable,
+	// see statefulSetMissesDataClaim and storageDrift
+	claims := sts.Spec.VolumeClaimTemplates
+	serviceName := sts.Spec.ServiceName
+	if sts.CreationTimestamp.IsZero() {
+		serviceName = headlessServiceName(instance)
+		if instance.Spec.Storage != nil {
+			claims = []corev1.PersistentVolumeClaim{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: dataVolumeName, Labels: labels},
+					Spec:       newPersistentVolumeClaimSpec(instance.Spec.Storage),
+				},
+			}
+		}
+	}
+	sts.Spec = appsv1.StatefulSetSpec{
+		Replicas:             replicas,
+		Template:             newPodTemplate(instance),
+		Selector:             &metav1.LabelSelector{MatchLabels: labels},
+		ServiceName:          serviceName,
+		PodManagementPolicy:  appsv1.ParallelPodManagement,
+		VolumeClaimTemplates: claims,
+	}
+}
+
+// MutatePersistentVolumeClaim renders the claim shared by the pods of a
+// Deployment. Only the requested size of an existing claim can be changed,
+// and only to expand it.
+func MutatePersistentVolumeClaim(instance *sentinelv1alpha1.Dashboard, pvc *corev1.PersistentVolumeClaim) {
+	pvc.Labels = map[string]string{
+		"app": instance.Name,
+	}
+	storage := instance.Spec.Storage
+	if pvc.CreationTimestamp.IsZero() {
+		pvc.Spec = newPersistentVolumeClaimSpec(storage)
+		return
+	}
+	if current := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; storage.Size.Cmp(current) > 0 {
+		pvc.Spec.Resources.Requests[corev1.ResourceStorage] = storage.Size
+	}
+}
+
+const (
+	dataVolumeName = "data"
+	tmpVolumeName  = "tmp"
+)
+
+// dataClaimName is the name of the claim of the Deployment. It is the claim
+// the StatefulSet creates for its first pod, so that the data is kept when
+// switching spec.workloadKind.
+func dataClaimName(instance *sentinelv1alpha1.Dashboard) string {
+	return fmt.Sprintf("%s-%s-0", dataVolumeName, instance.Name)
+}
+
+func newPersistentVolumeClaimSpec(storage *sentinelv1alpha1.StorageSpec) corev1.PersistentVolumeClaimSpec {
+	accessModes := storage.AccessModes
+	if len(accessModes) == 0 {
+		accessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	}
+	return corev1.PersistentVolumeClaimSpec{
+		AccessModes:      accessModes,
+		StorageClassName: storage.StorageClassName,
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceStorage: storage.Size},
+		},
+	}
+}
+
+// dashboardPodLabels returns the labels of the pods of the dashboard. The
+// workload and the Service select the pods by the app label alone, as the
+// selector of a workload cannot be changed once created, but the operator
+// looks the pods up by all of them, since app is a label other workloads of
+// the namespace commonly use too.
+func dashboardPodLabels(instance *sentinelv1alpha1.Dashboard) map[string]string {
+	return map[string]string{
+		"app":                          instance.Name,
+		"app.kubernetes.io/name":       "sentinel-dashboard",
+		"app.kubernetes.io/instance":   instance.Name,
+		"app.kubernetes.io/managed-by": "sentinel-dashboard-k8s-operator",
+	}
+}
+
+func newPodTemplate(instance *sentinelv1alpha1.Dashboard) corev1.PodTemplateSpec {
+	volumes := []corev1.Volume{
+		{
+			Name:         tmpVolumeName,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		},
+	}
+	if instance.Spec.Storage == nil {
+		volumes = append(volumes, corev1.Volume{
+			Name:         dataVolumeName,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		})
+	}
+	automountServiceAccountToken := false
+	if instance.Spec.AutomountServiceAccountToken != nil {
+		automountServiceAccountToken = *instance.Spec.AutomountServiceAccountToken
+	}
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: dashboardPodLabels(instance),
+		},
+		Spec: corev1.PodSpec{
+			Containers:                   newContainers(instance),
+			Affinity:                     newAffinity(instance),
+			SecurityContext:              newPodSecurityContext(instance),
+			Volumes:                      volumes,
+			ImagePullSecrets:             instance.Spec.ImagePullSecrets,
+			ServiceAccountName:           serviceAccountName(instance),
+			AutomountServiceAccountToken: &automountServiceAccountToken,
+		},
+	}
+	if scheduling := instance.Spec.Scheduling; scheduling != nil {
+		template.Spec.NodeSelector = scheduling.NodeSelector
+		template.Spec.Tolerations = scheduling.Tolerations
+		template.Spec.PriorityClassName = scheduling.PriorityClassName
+		template.Spec.TopologySpreadConstraints = scheduling.TopologySpreadConstraints
+	}
+	return template
+}
+
+// newAffinity returns the affinity of spec.scheduling. When the dashboard may
+// run more than one replica and no pod anti-affinity is set, it adds a
+// preferred anti-affinity that spreads the pods across zones, then nodes.
+func newAffinity(instance *sentinelv1alpha1.Dashboard) *corev1.Affinity {
+	var affinity *corev1.Affinity
+	if instance.Spec.Scheduling != nil && instance.Spec.Scheduling.Affinity != nil {
+		affinity = instance.Spec.Scheduling.Affinity.DeepCopy()
+	}
+	if !instance.ScalesOut() || (affinity != nil && affinity.PodAntiAffinity != nil) {
+		return affinity
+	}
+
+	if affinity == nil {
+		affinity = &corev1.Affinity{}
+	}
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": instance.Name}}
+	affinity.PodAntiAffinity = &corev1.PodAntiAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+			{
+				Weight: 100,
+				PodAffinityTerm: corev1.PodAffinityTerm{
+					LabelSelector: selector,
+					TopologyKey:   corev1.LabelTopologyZone,
+				},
 			},
-			Spec: corev1.PodSpec{
-				Containers: newContainers(instance),
+			{
+				Weight: 50,
+				PodAffinityTerm: corev1.PodAffinityTerm{
+					LabelSelector: selector,
+					TopologyKey:   corev1.LabelHostname,
+				},
 			},
 		},
-		Selector: &metav1.LabelSelector{MatchLabels: labels},
 	}
+	return affinity
 }
 
-func newContainers(sentinel *sentinelv1alpha1.Dashboard) []corev1.Container {
-	env := []corev1.EnvVar{
-		{
-			Name:  "NACOS_ADDRESS",
-			Value: sentinel.Name + "." + sentinel.Namespace + ":" + "8848",
+func MutateHorizontalPodAutoscaler(instance *sentinelv1alpha1.Dashboard, hpa *autoscalingv2.HorizontalPodAutoscaler) {
+	hpa.Labels = map[string]string{
+		"app": instance.Name,
+	}
+
+	autoscaling := instance.Spec.Autoscaling
+	var metrics []autoscalingv2.MetricSpec
+	if autoscaling.TargetCPUUtilizationPercentage != nil {
+		metrics = append(metrics, resourceMetric(corev1.ResourceCPU, *autoscaling.TargetCPUUtilizationPercentage))
+	}
+	if autoscaling.TargetMemoryUtilizationPercentage != nil {
+		metrics = append(metrics, resourceMetric(corev1.ResourceMemory, *autoscaling.TargetMemoryUtilizationPercentage))
+	}
+	if len(metrics) == 0 {
+		metrics = append(metrics, resourceMetric(corev1.ResourceCPU, defaultTargetCPUUtilizationPercentage))
+	}
+
+	hpa.Spec = autoscalingv2.HorizontalPodAutoscalerSpec{
+		ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       string(workloadKind(instance)),
+			Name:       instance.Name,
 		},
-		{
-			Name:  "NACOS_USERNAME",
-			Value: "nacos",
+		MinReplicas: autoscaling.MinReplicas,
+		MaxReplicas: autoscaling.MaxReplicas,
+		Metrics:     metrics,
+	}
+}
+
+func MutateIngress(instance *sentinelv1alpha1.Dashboard, ing *networkingv1.Ingress) {
+	spec := instance.Spec.Ingress
+	ing.Labels = map[string]string{
+		"app": instance.Name,
+	}
+	ing.Annotations = spec.Annotations
+
+	pathType := networkingv1.PathTypePrefix
+	if spec.PathType != nil {
+		pathType = *spec.PathType
+	}
+	paths := spec.Paths
+	if len(paths) == 0 {
+		paths = []string{"/"}
+	}
+	httpPaths := make([]networkingv1.HTTPIngressPath, 0, len(paths))
+	for _, path := range paths {
+		httpPaths = append(httpPaths, networkingv1.HTTPIngressPath{
+			Path:     path,
+			PathType: &pathType,
+			Backend: networkingv1.IngressBackend{
+				Service: &networkingv1.IngressServiceBackend{
+					Name: instance.Name,
+					Port: networkingv1.ServiceBackendPort{Name: "http"},
+				},
+			},
+		})
+	}
+
+	hosts := spec.Hosts
+	if len(hosts) == 0 {
+		hosts = []string{""}
+	}
+	rules := make([]networkingv1.IngressRule, 0, len(hosts))
+	for _, host := range hosts {
+		rules = append(rules, networkingv1.IngressRule{
+			Host: host,
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{Paths: httpPaths},
+			},
+		})
+	}
+
+	var tls []networkingv1.IngressTLS
+	if spec.TLSSecretName != "" {
+		tls = []networkingv1.IngressTLS{{Hosts: spec.Hosts, SecretName: spec.TLSSecretName}}
+	}
+
+	ing.Spec = networkingv1.IngressSpec{
+		IngressClassName: spec.IngressClassName,
+		Rules:            rules,
+		TLS:              tls,
+	}
+}
+
+// MutateHTTPRoute renders spec.gatewayRoute into route. The fields the Gateway
+// API defaults are set explicitly, so that the route is not updated on every
+// reconcile.
+func MutateHTTPRoute(instance *sentinelv1alpha1.Dashboard, route *unstructured.Unstructured) {
+	spec := instance.Spec.GatewayRoute
+	route.SetLabels(map[string]string{
+		"app": instance.Name,
+	})
+	route.SetAnnotations(spec.Annotations)
+
+	parentRefs := make([]interface{}, 0, len(spec.ParentRefs))
+	for _, ref := range spec.ParentRefs {
+		parentRef := map[string]interface{}{
+			"group": gatewayGroup,
+			"kind":  "Gateway",
+			"name":  ref.Name,
+		}
+		if ref.Group != "" {
+			parentRef["group"] = ref.Group
+		}
+		if ref.Kind != "" {
+			parentRef["kind"] = ref.Kind
+		}
+		if ref.Namespace != "" {
+			parentRef["namespace"] = ref.Namespace
+		}
+		if ref.SectionName != "" {
+			parentRef["sectionName"] = ref.SectionName
+		}
+		if ref.Port != nil {
+			parentRef["port"] = int64(*ref.Port)
+		}
+		parentRefs = append(parentRefs, parentRef)
+	}
+
+	path := spec.Path
+	if path == "" {
+		path = "/"
+	}
+	routeSpec := map[string]interface{}{
+		"parentRefs": parentRefs,
+		"rules": []interface{}{
+			map[string]interface{}{
+				"matches": []interface{}{
+					map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":  "PathPrefix",
+							"value": path,
+						},
+					},
+				},
+				"backendRefs": []interface{}{
+					map[string]interface{}{
+						"group":  "",
+						"kind":   "Service",
+						"name":   instance.Name,
+						"port":   int64(dashboardServicePort(instance)),
+						"weight": int64(1),
+					},
+				},
+			},
 		},
-		{
-			Name:  "NACOS_PASSWORD",
-			Value: "nacos",
+	}
+	if len(spec.Hostnames) > 0 {
+		hostnames := make([]interface{}, 0, len(spec.Hostnames))
+		for _, hostname := range spec.Hostnames {
+			hostnames = append(hostnames, hostname)
+		}
+		routeSpec["hostnames"] = hostnames
+	}
+	route.Object["spec"] = routeSpec
+}
+
+func MutatePodDisruptionBudget(instance *sentinelv1alpha1.Dashboard, pdb *policyv1.PodDisruptionBudget) {
+	pdb.Labels = map[string]string{
+		"app": instance.Name,
+	}
+
+	var minAvailable, maxUnavailable *intstr.IntOrString
+	if spec := instance.Spec.PodDisruptionBudget; spec != nil {
+		minAvailable, maxUnavailable = spec.MinAvailable, spec.MaxUnavailable
+	}
+	if minAvailable == nil && maxUnavailable == nil {
+		one := intstr.FromInt(1)
+		maxUnavailable = &one
+	}
+	pdb.Spec = policyv1.PodDisruptionBudgetSpec{
+		MinAvailable:   minAvailable,
+		MaxUnavailable: maxUnavailable,
+		Selector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": instance.Name}},
+	}
+}
+
+// needsPodDisruptionBudget reports whether the dashboard gets a PodDisruptionBudget.
+func needsPodDisruptionBudget(instance *sentinelv1alpha1.Dashboard) bool {
+	return instance.Spec.PodDisruptionBudget != nil || instance.ScalesOut()
+}
+
+func MutateServiceAccount(instance *sentinelv1alpha1.Dashboard, sa *corev1.ServiceAccount) {
+	sa.Labels = map[string]string{
+		"app": instance.Name,
+	}
+	automountServiceAccountToken := instance.Spec.AutomountServiceAccountToken
+	if automountServiceAccountToken == nil {
+		automount := false
+		automountServiceAccountToken = &automount
+	}
+	sa.AutomountServiceAccountToken = automountServiceAccountToken
+}
+
+// serviceAccountName returns the ServiceAccount the dashboard runs as, empty
+// for the default one of the namespace.
+func serviceAccountName(instance *sentinelv1alpha1.Dashboard) string {
+	if instance.Spec.ServiceAccountName == "" && instance.Spec.CreateServiceAccount {
+		return instance.Name
+	}
+	return instance.Spec.ServiceAccountName
+}
+
+const defaultTargetCPUUtilizationPercentage = 80
+
+func resourceMetric(name corev1.ResourceName, utilization int32) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name: name,
+			Target: autoscalingv2.MetricTarget{
+				Type:               autoscalingv2.UtilizationMetricType,
+				AverageUtilization: &utilization,
+			},
 		},
 	}
+}
+
+func newContainers(sentinel *sentinelv1alpha1.Dashboard) []corev1.Container {
 	return []corev1.Container{
 		{
-			Name:  sentinel.Name,
-			Image: sentinel.Spec.Image,
-			//Ports:           containerPorts,
+			Name:            sentinel.Name,
+			Image:           sentinel.Spec.Image,
+			Ports:           newContainerPorts(sentinel),
 			ImagePullPolicy: corev1.PullIfNotPresent,
 			Resources:       sentinel.Spec.Resources,
-			Env:             env,
+			Env:             newEnv(sentinel),
+			LivenessProbe:   newProbe(sentinel, func(p *sentinelv1alpha1.ProbesSpec) *corev1.Probe { return p.Liveness }, 10, 3),
+			ReadinessProbe:  newProbe(sentinel, func(p *sentinelv1alpha1.ProbesSpec) *corev1.Probe { return p.Readiness }, 5, 3),
+			StartupProbe:    newProbe(sentinel, func(p *sentinelv1alpha1.ProbesSpec) *corev1.Probe { return p.Startup }, 10, 30),
+			VolumeMounts:    newVolumeMounts(sentinel),
+			SecurityContext: newSecurityContext(sentinel),
+		},
+	}
+}
+
+// newContainerPorts derives the container ports from spec.ports. The first
+// port is named http and is the one the Service and the probes target.
+func newContainerPorts(sentinel *sentinelv1alpha1.Dashboard) []corev1.ContainerPort {
+	ports := make([]corev1.ContainerPort, 0, len(sentinel.Spec.Ports))
+	for i, port := range sentinel.Spec.Ports {
+		name := port.Name
+		if i == 0 {
+			name = "http"
+		} else if name == "" || name == "http" {
+			name = fmt.Sprintf("port-%d", port.Port)
+		}
+		containerPort := port.Port
+		if port.TargetPort.Type == intstr.Int && port.TargetPort.IntVal != 0 {
+			containerPort = port.TargetPort.IntVal
+		}
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = corev1.ProtocolTCP
+		}
+		ports = append(ports, corev1.ContainerPort{
+			Name:          name,
+			ContainerPort: containerPort,
+			Protocol:      protocol,
+		})
+	}
+	return ports
+}
+
+func newVolumeMounts(sentinel *sentinelv1alpha1.Dashboard) []corev1.VolumeMount {
+	return []corev1.VolumeMount{
+		{
+			Name:      dataVolumeName,
+			MountPath: dataMountPath(sentinel),
 		},
+		{
+			Name:      tmpVolumeName,
+			MountPath: "/tmp",
+		},
+	}
+}
+
+// dataMountPath returns the path of the data volume, which is the volume of
+// spec.storage, or an emptyDir volume when it is not set.
+func dataMountPath(sentinel *sentinelv1alpha1.Dashboard) string {
+	if sentinel.Spec.Storage == nil || sentinel.Spec.Storage.MountPath == "" {
+		return sentinelv1alpha1.DefaultStorageMountPath
+	}
+	return sentinel.Spec.Storage.MountPath
+}
+
+// defaultUserID is the user and group the dashboard runs as by default.
+const defaultUserID = 1000
+
+// newPodSecurityContext returns spec.podSecurityContext, or a default
+// satisfying the restricted Pod Security Standard.
+func newPodSecurityContext(sentinel *sentinelv1alpha1.Dashboard) *corev1.PodSecurityContext {
+	if sentinel.Spec.PodSecurityContext != nil {
+		return sentinel.Spec.PodSecurityContext.DeepCopy()
+	}
+	return restrictedPodSecurityContext()
+}
+
+// restrictedPodSecurityContext returns a pod security context satisfying the
+// restricted Pod Security Standard.
+func restrictedPodSecurityContext() *corev1.PodSecurityContext {
+	runAsNonRoot := true
+	userID := int64(defaultUserID)
+	return &corev1.PodSecurityContext{
+		RunAsNonRoot: &runAsNonRoot,
+		RunAsUser:    &userID,
+		RunAsGroup:   &userID,
+		FSGroup:      &userID,
+		SeccompProfile: &corev1.SeccompProfile{
+			Type: corev1.SeccompProfileTypeRuntimeDefault,
+		},
+	}
+}
+
+// newSecurityContext returns spec.securityContext, or a default satisfying
+// the restricted Pod Security Standard with a read-only root filesystem.
+func newSecurityContext(sentinel *sentinelv1alpha1.Dashboard) *corev1.SecurityContext {
+	if sentinel.Spec.SecurityContext != nil {
+		return sentinel.Spec.SecurityContext.DeepCopy()
+	}
+	return restrictedSecurityContext()
+}
+
+// restrictedSecurityContext returns a container security context satisfying
+// the restricted Pod Security Standard with a read-only root filesystem.
+func restrictedSecurityContext() *corev1.SecurityContext {
+	allowPrivilegeEscalation := false
+	readOnlyRootFilesystem := true
+	return &corev1.SecurityContext{
+		AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+		ReadOnlyRootFilesystem:   &readOnlyRootFilesystem,
+		Capabilities: &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
+		},
+	}
+}
+
+// newProbe returns the probe selected from spec.probes, or the default HTTP
+// probe against the health check path with the given period and failure threshold.
+func newProbe(sentinel *sentinelv1alpha1.Dashboard, override func(*sentinelv1alpha1.ProbesSpec) *corev1.Probe,
+	periodSeconds, failureThreshold int32) *corev1.Probe {
+	if sentinel.Spec.Probes != nil {
+		if probe := override(sentinel.Spec.Probes); probe != nil {
+			return probe.DeepCopy()
+		}
+	}
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   healthCheckPath(sentinel),
+				Port:   intstr.FromString("http"),
+				Scheme: corev1.URISchemeHTTP,
+			},
+		},
+		TimeoutSeconds:   3,
+		PeriodSeconds:    periodSeconds,
+		SuccessThreshold: 1,
+		FailureThreshold: failureThreshold,
+	}
+}
+
+// newEnv merges the env rendered from spec.datasource, spec.jvm and spec.server
+// with the env declared in spec.env. A variable declared in spec.env replaces
+// the rendered one of the same name in place, keeping the rendered ordering,
+// and the remaining user variables are appended in the order they were
+// declared. When spec.env declares the same name more than once, the last
+// declaration wins.
+func newEnv(sentinel *sentinelv1alpha1.Dashboard) []corev1.EnvVar {
+	return mergeEnv(append(newDatasourceEnv(sentinel), newJavaEnv(sentinel)...), sentinel.Spec.Env)
+}
+
+func mergeEnv(defaults, overrides []corev1.EnvVar) []corev1.EnvVar {
+	index := make(map[string]int, len(defaults)+len(overrides))
+	env := make([]corev1.EnvVar, 0, len(defaults)+len(overrides))
+	// defaults is not appended to, as it may have spare capacity shared with the caller
+	for _, vars := range [][]corev1.EnvVar{defaults, overrides} {
+		for _, e := range vars {
+			if i, ok := index[e.Name]; ok {
+				env[i] = *e.DeepCopy()
+				continue
+			}
+			index[e.Name] = len(env)
+			env = append(env, *e.DeepCopy())
+		}
 	}
+	return env
 }
@@ -0,0 +1,344 @@
+package controllers
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+)
+
+func TestMergeEnv(t *testing.T) {
+	secretRef := &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "nacos"},
+		Key:                  "password",
+	}}
+	tests := []struct {
+		name      string
+		defaults  []corev1.EnvVar
+		overrides []corev1.EnvVar
+		want      []corev1.EnvVar
+	}{
+		{
+			name: "no overrides",
+			defaults: []corev1.EnvVar{
+				{Name: "NACOS_ADDRESS", Value: "nacos:8848"},
+				{Name: "NACOS_GROUP", Value: "SENTINEL_GROUP"},
+			},
+			want: []corev1.EnvVar{
+				{Name: "NACOS_ADDRESS", Value: "nacos:8848"},
+				{Name: "NACOS_GROUP", Value: "SENTINEL_GROUP"},
+			},
+		},
+		{
+			name: "overrides replace in place and extra ones are appended",
+			defaults: []corev1.EnvVar{
+				{Name: "NACOS_ADDRESS", Value: "nacos:8848"},
+				{Name: "NACOS_GROUP", Value: "SENTINEL_GROUP"},
+			},
+			overrides: []corev1.EnvVar{
+				{Name: "TZ", Value: "Asia/Shanghai"},
+				{Name: "NACOS_ADDRESS", Value: "nacos.nacos-group:8848"},
+			},
+			want: []corev1.EnvVar{
+				{Name: "NACOS_ADDRESS", Value: "nacos.nacos-group:8848"},
+				{Name: "NACOS_GROUP", Value: "SENTINEL_GROUP"},
+				{Name: "TZ", Value: "Asia/Shanghai"},
+			},
+		},
+		{
+			name:     "the last duplicate override wins",
+			defaults: []corev1.EnvVar{{Name: "NACOS_PASSWORD", ValueFrom: secretRef}},
+			overrides: []corev1.EnvVar{
+				{Name: "TZ", Value: "UTC"},
+				{Name: "NACOS_PASSWORD", Value: "nacos"},
+				{Name: "TZ", Value: "Asia/Shanghai"},
+			},
+			want: []corev1.EnvVar{
+				{Name: "NACOS_PASSWORD", Value: "nacos"},
+				{Name: "TZ", Value: "Asia/Shanghai"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(mergeEnv(tt.defaults, tt.overrides)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestMergeEnvDoesNotAlias(t *testing.T) {
+	g := NewWithT(t)
+	defaults := make([]corev1.EnvVar, 1, 2)
+	defaults[0] = corev1.EnvVar{Name: "NACOS_ADDRESS", Value: "nacos:8848"}
+	overrides := []corev1.EnvVar{{Name: "TZ", Value: "UTC"}}
+
+	env := mergeEnv(defaults, overrides)
+	env[0].Value = "changed"
+	g.Expect(defaults[0].Value).To(Equal("nacos:8848"))
+	g.Expect(defaults[:2][1]).To(Equal(corev1.EnvVar{}), "defaults must not be appended to in place")
+}
+
+func TestNewContainerPorts(t *testing.T) {
+	g := NewWithT(t)
+	instance := &sentinelv1alpha1.Dashboard{Spec: sentinelv1alpha1.DashboardSpec{Ports: []corev1.ServicePort{
+		{Name: "web", Port: 80, TargetPort: intstr.FromInt(8080)},
+		{Name: "http", Port: 8719, Protocol: corev1.ProtocolTCP},
+		{Port: 9090, Protocol: corev1.ProtocolUDP},
+		{Name: "metrics", Port: 9100, TargetPort: intstr.FromString("metrics")},
+	}}}
+	g.Expect(newContainerPorts(instance)).To(Equal([]corev1.ContainerPort{
+		{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+		{Name: "port-8719", ContainerPort: 8719, Protocol: corev1.ProtocolTCP},
+		{Name: "port-9090", ContainerPort: 9090, Protocol: corev1.ProtocolUDP},
+		{Name: "metrics", ContainerPort: 9100, Protocol: corev1.ProtocolTCP},
+	}))
+	g.Expect(dashboardContainerPort(instance)).To(BeEquivalentTo(8080))
+
+	g.Expect(newContainerPorts(&sentinelv1alpha1.Dashboard{})).To(BeEmpty())
+	g.Expect(dashboardContainerPort(&sentinelv1alpha1.Dashboard{})).To(BeEquivalentTo(sentinelv1alpha1.DefaultPort))
+}
+
+func TestNewProbe(t *testing.T) {
+	g := NewWithT(t)
+	instance := &sentinelv1alpha1.Dashboard{}
+	liveness := func(p *sentinelv1alpha1.ProbesSpec) *corev1.Probe { return p.Liveness }
+
+	probe := newProbe(instance, liveness, 10, 3)
+	g.Expect(probe.HTTPGet).NotTo(BeNil())
+	g.Expect(probe.HTTPGet.Port).To(Equal(intstr.FromString("http")))
+	g.Expect(probe.HTTPGet.Path).To(Equal(healthCheckPath(instance)))
+	g.Expect(probe.PeriodSeconds).To(BeEquivalentTo(10))
+	g.Expect(probe.FailureThreshold).To(BeEquivalentTo(3))
+
+	override := &corev1.Probe{ProbeHandler: corev1.ProbeHandler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(8080)}}}
+	instance.Spec.Probes = &sentinelv1alpha1.ProbesSpec{Liveness: override}
+	g.Expect(newProbe(instance, liveness, 10, 3)).To(Equal(override))
+	g.Expect(newProbe(instance, liveness, 10, 3)).NotTo(BeIdenticalTo(override))
+	g.Expect(newProbe(instance, func(p *sentinelv1alpha1.ProbesSpec) *corev1.Probe { return p.Readiness }, 5, 3).HTTPGet).
+		NotTo(BeNil(), "the probes that are not overridden keep the default")
+}
+
+func TestMutateIngress(t *testing.T) {
+	className := "nginx"
+	exact := networkingv1.PathTypeExact
+	prefix := networkingv1.PathTypePrefix
+	backend := networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+		Name: "sentinel",
+		Port: networkingv1.ServiceBackendPort{Name: "http"},
+	}}
+	rule := func(host string, paths ...networkingv1.HTTPIngressPath) networkingv1.IngressRule {
+		return networkingv1.IngressRule{
+			Host:             host,
+			IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{Paths: paths}},
+		}
+	}
+
+	tests := []struct {
+		name string
+		spec sentinelv1alpha1.IngressSpec
+		want networkingv1.IngressSpec
+	}{
+		{
+			name: "defaults",
+			want: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{
+				rule("", networkingv1.HTTPIngressPath{Path: "/", PathType: &prefix, Backend: backend}),
+			}},
+		},
+		{
+			name: "every path of every host",
+			spec: sentinelv1alpha1.IngressSpec{
+				IngressClassName: &className,
+				Hosts:            []string{"a.example.com", "b.example.com"},
+				Paths:            []string{"/sentinel", "/dashboard"},
+				PathType:         &exact,
+				TLSSecretName:    "tls",
+			},
+			want: networkingv1.IngressSpec{
+				IngressClassName: &className,
+				Rules: []networkingv1.IngressRule{
+					rule("a.example.com",
+						networkingv1.HTTPIngressPath{Path: "/sentinel", PathType: &exact, Backend: backend},
+						networkingv1.HTTPIngressPath{Path: "/dashboard", PathType: &exact, Backend: backend}),
+					rule("b.example.com",
+						networkingv1.HTTPIngressPath{Path: "/sentinel", PathType: &exact, Backend: backend},
+						networkingv1.HTTPIngressPath{Path: "/dashboard", PathType: &exact, Backend: backend}),
+				},
+				TLS: []networkingv1.IngressTLS{{Hosts: []string{"a.example.com", "b.example.com"}, SecretName: "tls"}},
+			},
+		},
+		{
+			name: "tls of any host",
+			spec: sentinelv1alpha1.IngressSpec{TLSSecretName: "tls"},
+			want: networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{
+					rule("", networkingv1.HTTPIngressPath{Path: "/", PathType: &prefix, Backend: backend}),
+				},
+				TLS: []networkingv1.IngressTLS{{SecretName: "tls"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			instance := &sentinelv1alpha1.Dashboard{ObjectMeta: metav1.ObjectMeta{Name: "sentinel"}}
+			instance.Spec.Ingress = &tt.spec
+			instance.Spec.Ingress.Annotations = map[string]string{"nginx.ingress.kubernetes.io/ssl-redirect": "true"}
+			ing := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"stale": "true"}}}
+
+			MutateIngress(instance, ing)
+			g.Expect(ing.Labels).To(Equal(map[string]string{"app": "sentinel"}))
+			g.Expect(ing.Annotations).To(Equal(instance.Spec.Ingress.Annotations))
+			g.Expect(ing.Spec).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestNewAffinity(t *testing.T) {
+	one, three := int32(1), int32(3)
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "sentinel"}}
+	spread := &corev1.PodAntiAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+			{Weight: 100, PodAffinityTerm: corev1.PodAffinityTerm{LabelSelector: selector, TopologyKey: corev1.LabelTopologyZone}},
+			{Weight: 50, PodAffinityTerm: corev1.PodAffinityTerm{LabelSelector: selector, TopologyKey: corev1.LabelHostname}},
+		},
+	}
+	nodeAffinity := &corev1.NodeAffinity{RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+		NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: []corev1.NodeSelectorRequirement{
+			{Key: "kubernetes.io/arch", Operator: corev1.NodeSelectorOpIn, Values: []string{"amd64"}},
+		}}},
+	}}
+	antiAffinity := &corev1.PodAntiAffinity{RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+		{LabelSelector: selector, TopologyKey: corev1.LabelHostname},
+	}}
+
+	tests := []struct {
+		name        string
+		replicas    *int32
+		autoscaling *sentinelv1alpha1.AutoscalingSpec
+		affinity    *corev1.Affinity
+		want        *corev1.Affinity
+	}{
+		{name: "single replica", replicas: &one},
+		{
+			name:     "single replica keeps the affinity",
+			replicas: &one,
+			affinity: &corev1.Affinity{NodeAffinity: nodeAffinity},
+			want:     &corev1.Affinity{NodeAffinity: nodeAffinity},
+		},
+		{
+			name:     "replicas spread",
+			replicas: &three,
+			want:     &corev1.Affinity{PodAntiAffinity: spread},
+		},
+		{
+			name:        "autoscaling spreads",
+			replicas:    &one,
+			autoscaling: &sentinelv1alpha1.AutoscalingSpec{MaxReplicas: 3},
+			want:        &corev1.Affinity{PodAntiAffinity: spread},
+		},
+		{
+			name:        "autoscaling to one replica",
+			replicas:    &three,
+			autoscaling: &sentinelv1alpha1.AutoscalingSpec{MaxReplicas: 1},
+		},
+		{
+			name:     "spread added to the affinity",
+			replicas: &three,
+			affinity: &corev1.Affinity{NodeAffinity: nodeAffinity},
+			want:     &corev1.Affinity{NodeAffinity: nodeAffinity, PodAntiAffinity: spread},
+		},
+		{
+			name:     "pod anti-affinity wins",
+			replicas: &three,
+			affinity: &corev1.Affinity{PodAntiAffinity: antiAffinity},
+			want:     &corev1.Affinity{PodAntiAffinity: antiAffinity},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			instance := &sentinelv1alpha1.Dashboard{ObjectMeta: metav1.ObjectMeta{Name: "sentinel"}}
+			instance.Spec.Replicas = tt.replicas
+			instance.Spec.Autoscaling = tt.autoscaling
+			if tt.affinity != nil {
+				instance.Spec.Scheduling = &sentinelv1alpha1.SchedulingSpec{Affinity: tt.affinity}
+			}
+			spec := instance.Spec.DeepCopy()
+
+			g.Expect(newAffinity(instance)).To(Equal(tt.want))
+			g.Expect(instance.Spec).To(Equal(*spec), "the spec must not be modified")
+		})
+	}
+}
+
+func TestMutatePodDisruptionBudget(t *testing.T) {
+	one, three := int32(1), int32(3)
+	maxOne, minTwo, maxHalf := intstr.FromInt(1), intstr.FromInt(2), intstr.FromString("50%")
+
+	tests := []struct {
+		name               string
+		replicas           *int32
+		spec               *sentinelv1alpha1.PodDisruptionBudgetSpec
+		wantNeeded         bool
+		wantMinAvailable   *intstr.IntOrString
+		wantMaxUnavailable *intstr.IntOrString
+	}{
+		{name: "single replica", replicas: &one},
+		{
+			name:               "replicas default to one unavailable",
+			replicas:           &three,
+			wantNeeded:         true,
+			wantMaxUnavailable: &maxOne,
+		},
+		{
+			name:               "empty spec defaults to one unavailable",
+			replicas:           &one,
+			spec:               &sentinelv1alpha1.PodDisruptionBudgetSpec{},
+			wantNeeded:         true,
+			wantMaxUnavailable: &maxOne,
+		},
+		{
+			name:             "min available",
+			replicas:         &three,
+			spec:             &sentinelv1alpha1.PodDisruptionBudgetSpec{MinAvailable: &minTwo},
+			wantNeeded:       true,
+			wantMinAvailable: &minTwo,
+		},
+		{
+			name:               "max unavailable percentage",
+			replicas:           &three,
+			spec:               &sentinelv1alpha1.PodDisruptionBudgetSpec{MaxUnavailable: &maxHalf},
+			wantNeeded:         true,
+			wantMaxUnavailable: &maxHalf,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			instance := &sentinelv1alpha1.Dashboard{ObjectMeta: metav1.ObjectMeta{Name: "sentinel"}}
+			instance.Spec.Replicas = tt.replicas
+			instance.Spec.PodDisruptionBudget = tt.spec
+			g.Expect(needsPodDisruptionBudget(instance)).To(Equal(tt.wantNeeded))
+			if !tt.wantNeeded {
+				return
+			}
+
+			var pdb policyv1.PodDisruptionBudget
+			MutatePodDisruptionBudget(instance, &pdb)
+			g.Expect(pdb.Labels).To(Equal(map[string]string{"app": "sentinel"}))
+			g.Expect(pdb.Spec.MinAvailable).To(Equal(tt.wantMinAvailable))
+			g.Expect(pdb.Spec.MaxUnavailable).To(Equal(tt.wantMaxUnavailable))
+			g.Expect(pdb.Spec.Selector).To(Equal(&metav1.LabelSelector{MatchLabels: map[string]string{"app": "sentinel"}}))
+		})
+	}
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+)
+
+// RuleReconciler reconciles the rules of a kind, e.g. FlowRule objects.
+type RuleReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Rule is an empty object of the kind reconciled.
+	Rule sentinelv1alpha1.Rule
+
+	// Publisher publishes the rule sets, and is shared by the reconcilers
+	// of every kind.
+	Publisher *RulePublisher
+}
+
+//+kubebuilder:rbac:groups=sentinel.sentinelguard.io,resources=flowrules;degraderules;systemrules;authorityrules;paramflowrules,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=sentinel.sentinelguard.io,resources=flowrules/status;degraderules/status;systemrules/status;authorityrules/status;paramflowrules/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=sentinel.sentinelguard.io,resources=flowrules/finalizers;degraderules/finalizers;systemrules/finalizers;authorityrules/finalizers;paramflowrules/finalizers,verbs=update
+//+kubebuilder:rbac:groups=sentinel.sentinelguard.io,resources=dashboards,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile publishes the rules of every kind of the app of the rule to the
+// datasource of its dashboard, and reports the result on every rule of the app.
+// The oldest rule of the app is reconciled again at the rule sync interval of
+// the dashboard, to read back the rule sets of the app once per interval
+// rather than once per rule.
+func (r *RuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	rule := r.Rule.DeepCopyObject().(sentinelv1alpha1.Rule)
+	if err := r.Get(ctx, req.NamespacedName, rule); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "failed to get rule")
+		return ctrl.Result{}, err
+	}
+	target := rule.GetTarget()
+
+	if !rule.GetDeletionTimestamp().IsZero() {
+		if !controllerutil.ContainsFinalizer(rule, ruleFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		if _, err := r.Publisher.Publish(ctx, rule.GetNamespace(), target.DashboardRef.Name, target.App); err != nil {
+			return ctrl.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(rule, ruleFinalizer)
+		return ctrl.Result{}, errors.Wrap(client.IgnoreNotFound(r.Update(ctx, rule)), "failed removing finalizer")
+	}
+	if !controllerutil.ContainsFinalizer(rule, ruleFinalizer) {
+		controllerutil.AddFinalizer(rule, ruleFinalizer)
+		if err := r.Update(ctx, rule); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed adding finalizer")
+		}
+	}
+
+	interval, err := r.Publisher.Publish(ctx, rule.GetNamespace(), target.DashboardRef.Name, target.App)
+	if err != nil || interval == 0 {
+		return ctrl.Result{RequeueAfter: interval}, err
+	}
+	oldest, err := oldestRules(ctx, r, rule.GetNamespace(),
+		client.MatchingFields{ruleTargetIndex: ruleTarget(target.DashboardRef.Name, target.App)})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if o, ok := oldest[target.App]; !ok || o.GetUID() != rule.GetUID() {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// oldestRules returns the oldest rule of any kind, not being deleted, of each
+// app of the rules matching fields.
+func oldestRules(ctx context.Context, c client.Reader, namespace string,
+	fields client.MatchingFields) (map[string]sentinelv1alpha1.Rule, error) {
+	oldest := make(map[string]sentinelv1alpha1.Rule)
+	for _, kind := range ruleKinds {
+		list := kind.newList()
+		if err := c.List(ctx, list, client.InNamespace(namespace), fields); err != nil {
+			return nil, errors.Wrapf(err, "failed listing %s rules", kind.name)
+		}
+		objs, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			rule, ok := obj.(sentinelv1alpha1.Rule)
+			if !ok || !rule.GetDeletionTimestamp().IsZero() {
+				continue
+			}
+			app := rule.GetTarget().App
+			if o, ok := oldest[app]; !ok || ruleOlder(rule, o) {
+				oldest[app] = rule
+			}
+		}
+	}
+	return oldest, nil
+}
+
+// indexRuleTarget is the field indexer for ruleTargetIndex.
+func indexRuleTarget(obj client.Object) []string {
+	rule, ok := obj.(sentinelv1alpha1.Rule)
+	if !ok {
+		return nil
+	}
+	target := rule.GetTarget()
+	return []string{ruleTarget(target.DashboardRef.Name, target.App)}
+}
+
+// indexRuleDashboard is the field indexer for ruleDashboardIndex.
+func indexRuleDashboard(obj client.Object) []string {
+	rule, ok := obj.(sentinelv1alpha1.Rule)
+	if !ok {
+		return nil
+	}
+	return []string{rule.GetTarget().DashboardRef.Name}
+}
+
+// dashboardToRules returns the function mapping a Dashboard to the oldest
+// rule of each app published to it, when that rule is of kind, so every app
+// is published once whatever its number of rules and kinds.
+func (r *RuleReconciler) dashboardToRules(kind *ruleKind) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		return r.oldestRuleRequests(kind, obj.GetNamespace(), client.MatchingFields{ruleDashboardIndex: obj.GetName()})
+	}
+}
+
+// deletedRuleToOldest returns the function mapping a deleted rule of any kind
+// to the oldest rule left of its app, when that rule is of kind, so the app
+// keeps being read back after the deletion of its oldest rule.
+func (r *RuleReconciler) deletedRuleToOldest(kind *ruleKind) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		rule, ok := obj.(sentinelv1alpha1.Rule)
+		if !ok {
+			return nil
+		}
+		target := rule.GetTarget()
+		return r.oldestRuleRequests(kind, obj.GetNamespace(),
+			client.MatchingFields{ruleTargetIndex: ruleTarget(target.DashboardRef.Name, target.App)})
+	}
+}
+
+// oldestRuleRequests returns the requests for the oldest rules of kind of the
+// apps of the rules matching fields.
+func (r *RuleReconciler) oldestRuleRequests(kind *ruleKind, namespace string,
+	fields client.MatchingFields) []reconcile.Request {
+	oldest, err := oldestRules(context.Background(), r, namespace, fields)
+	if err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, rule := range oldest {
+		if k, err := ruleKindOf(rule); err != nil || k != kind {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: rule.GetNamespace(), Name: rule.GetName()},
+		})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RuleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	kind, err := ruleKindOf(r.Rule)
+	if err != nil {
+		return err
+	}
+	if r.Publisher == nil {
+		r.Publisher = NewRulePublisher(mgr)
+	}
+
+	indexer := mgr.GetFieldIndexer()
+	if err := indexer.IndexField(context.Background(), r.Rule, ruleTargetIndex, indexRuleTarget); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(context.Background(), r.Rule, ruleDashboardIndex, indexRuleDashboard); err != nil {
+		return err
+	}
+
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(r.Rule).
+		Watches(&source.Kind{Type: &sentinelv1alpha1.Dashboard{}},
+			handler.EnqueueRequestsFromMapFunc(r.dashboardToRules(kind)),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	deleted := predicate.Funcs{
+		CreateFunc:  func(event.CreateEvent) bool { return false },
+		UpdateFunc:  func(event.UpdateEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+	}
+	for _, k := range ruleKinds {
+		b = b.Watches(&source.Kind{Type: k.object},
+			handler.EnqueueRequestsFromMapFunc(r.deletedRuleToOldest(kind)),
+			builder.WithPredicates(deleted))
+	}
+	return b.Complete(r)
+}
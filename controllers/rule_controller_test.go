@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/nacos/nacostest"
+)
+
+var ruleCreated = time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+
+// newRuleMeta returns the metadata of a rule created age after ruleCreated.
+func newRuleMeta(name string, age time.Duration) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:              name,
+		Namespace:         "sentinel-group",
+		UID:               types.UID(name + "-uid"),
+		CreationTimestamp: metav1.NewTime(ruleCreated.Add(age)),
+		Finalizers:        []string{ruleFinalizer},
+	}
+}
+
+func newRuleTarget(app string) sentinelv1alpha1.RuleTarget {
+	return sentinelv1alpha1.RuleTarget{DashboardRef: corev1.LocalObjectReference{Name: "sentinel-dashboard"}, App: app}
+}
+
+func newFlowRule(name, app, resource string, age time.Duration) *sentinelv1alpha1.FlowRule {
+	return &sentinelv1alpha1.FlowRule{
+		ObjectMeta: newRuleMeta(name, age),
+		Spec:       sentinelv1alpha1.FlowRuleSpec{RuleTarget: newRuleTarget(app), Resource: resource},
+	}
+}
+
+func newDegradeRule(name, app, resource string, age time.Duration) *sentinelv1alpha1.DegradeRule {
+	return &sentinelv1alpha1.DegradeRule{
+		ObjectMeta: newRuleMeta(name, age),
+		Spec:       sentinelv1alpha1.DegradeRuleSpec{RuleTarget: newRuleTarget(app), Resource: resource, TimeWindow: 10},
+	}
+}
+
+// newTestRules returns the rules of two apps: the oldest rule of order-service
+// is a degrade rule, once its older degrade rule being deleted is left out.
+func newTestRules() []client.Object {
+	deleting := newDegradeRule("order-deleted", "order-service", "GET:/orders/old", 0)
+	deleting.DeletionTimestamp = &metav1.Time{Time: ruleCreated}
+	return []client.Object{
+		newFlowRule("order-flow", "order-service", "GET:/orders", 2*time.Hour),
+		newDegradeRule("order-degrade", "order-service", "GET:/orders", time.Hour),
+		deleting,
+		newFlowRule("pay-flow", "pay-service", "POST:/pay", 3*time.Hour),
+		newFlowRule("pay-flow-copy", "pay-service", "POST:/pay/copy", 3*time.Hour),
+	}
+}
+
+func TestOldestRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields client.MatchingFields
+		want   map[string]string
+	}{
+		{
+			name:   "apps of a dashboard",
+			fields: client.MatchingFields{ruleDashboardIndex: "sentinel-dashboard"},
+			want:   map[string]string{"order-service": "order-degrade", "pay-service": "pay-flow"},
+		},
+		{
+			name:   "one app",
+			fields: client.MatchingFields{ruleTargetIndex: ruleTarget("sentinel-dashboard", "pay-service")},
+			want:   map[string]string{"pay-service": "pay-flow"},
+		},
+		{
+			name:   "other dashboard",
+			fields: client.MatchingFields{ruleDashboardIndex: "other"},
+			want:   map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			p := newTestRulePublisher(newTestRules()...)
+
+			oldest, err := oldestRules(context.Background(), p, "sentinel-group", tt.fields)
+			g.Expect(err).NotTo(HaveOccurred())
+			names := make(map[string]string)
+			for app, rule := range oldest {
+				names[app] = rule.GetName()
+			}
+			g.Expect(names).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestOldestRuleRequests(t *testing.T) {
+	request := func(name string) reconcile.Request {
+		return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "sentinel-group", Name: name}}
+	}
+	dashboard := &sentinelv1alpha1.Dashboard{ObjectMeta: metav1.ObjectMeta{Name: "sentinel-dashboard", Namespace: "sentinel-group"}}
+
+	tests := []struct {
+		name        string
+		rule        sentinelv1alpha1.Rule
+		wantChanged []reconcile.Request
+		wantDeleted []reconcile.Request
+	}{
+		{
+			name:        "flow rules",
+			rule:        &sentinelv1alpha1.FlowRule{},
+			wantChanged: []reconcile.Request{request("pay-flow")},
+			wantDeleted: []reconcile.Request{request("pay-flow")},
+		},
+		{
+			name:        "degrade rules",
+			rule:        &sentinelv1alpha1.DegradeRule{},
+			wantChanged: []reconcile.Request{request("order-degrade")},
+		},
+		{name: "system rules", rule: &sentinelv1alpha1.SystemRule{}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			kind, err := ruleKindOf(tt.rule)
+			g.Expect(err).NotTo(HaveOccurred())
+			p := newTestRulePublisher(newTestRules()...)
+			r := &RuleReconciler{Client: p.Client, Rule: tt.rule, Publisher: p}
+
+			g.Expect(r.dashboardToRules(kind)(dashboard)).To(ConsistOf(tt.wantChanged))
+			deleted := newFlowRule("pay-flow-deleted", "pay-service", "POST:/pay", 0)
+			g.Expect(r.deletedRuleToOldest(kind)(deleted)).To(ConsistOf(tt.wantDeleted))
+		})
+	}
+}
+
+func TestReconcileRequeuesOldestRule(t *testing.T) {
+	server := nacostest.NewServer()
+	defer server.Close()
+	dashboard := &sentinelv1alpha1.Dashboard{
+		ObjectMeta: metav1.ObjectMeta{Name: "sentinel-dashboard", Namespace: "sentinel-group"},
+		Spec: sentinelv1alpha1.DashboardSpec{
+			Datasource: &sentinelv1alpha1.DatasourceSpec{
+				Nacos: &sentinelv1alpha1.NacosDatasource{ServerAddr: []string{server.Addr()}},
+			},
+			RuleSync: &sentinelv1alpha1.RuleSyncSpec{Interval: &metav1.Duration{Duration: time.Minute}},
+		},
+	}
+
+	tests := []struct {
+		name string
+		rule sentinelv1alpha1.Rule
+		key  string
+		want ctrl.Result
+	}{
+		{name: "oldest rule of the app", rule: &sentinelv1alpha1.DegradeRule{}, key: "order-degrade", want: ctrl.Result{RequeueAfter: time.Minute}},
+		{name: "other rule of the app", rule: &sentinelv1alpha1.FlowRule{}, key: "order-flow"},
+		{name: "oldest rule of the other app", rule: &sentinelv1alpha1.FlowRule{}, key: "pay-flow", want: ctrl.Result{RequeueAfter: time.Minute}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			p := newTestRulePublisher(append(newTestRules(), dashboard.DeepCopy())...)
+			r := &RuleReconciler{Client: p.Client, Rule: tt.rule, Publisher: p}
+
+			result, err := r.Reconcile(context.Background(), ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: "sentinel-group", Name: tt.key},
+			})
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(result).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,705 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/event"
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/nacos"
+)
+
+const (
+	// ruleFinalizer blocks the deletion of a rule until the rule set of its
+	// app is published without it.
+	ruleFinalizer = "sentinel.sentinelguard.io/rules"
+
+	// ruleTargetIndex indexes rules by the dashboard and app they are published to.
+	ruleTargetIndex = ".spec.target"
+
+	// ruleDashboardIndex indexes rules by the dashboard they are published to.
+	ruleDashboardIndex = ".spec.dashboardRef.name"
+
+	// defaultPublishTimeout bounds the requests to the datasources.
+	defaultPublishTimeout = 10 * time.Second
+)
+
+// Reasons of the Published condition of the rules.
+const (
+	rulePublishedReason             = "Published"
+	ruleConflictReason              = "Conflict"
+	ruleDashboardNotFoundReason     = "DashboardNotFound"
+	ruleUnsupportedDatasourceReason = "UnsupportedDatasource"
+	rulePublishFailedReason         = "PublishFailed"
+	ruleDriftedReason               = "Drifted"
+)
+
+// Reasons of the Drifted condition of the rules.
+const (
+	ruleInSyncReason   = "InSync"
+	ruleEnforcedReason = "Enforced"
+	ruleAdoptedReason  = "Adopted"
+)
+
+// errDashboardNotFound is returned when publishing the rules of a missing dashboard.
+var errDashboardNotFound = errors.New("dashboard not found")
+
+// ruleKind describes how the rules of a kind are published.
+type ruleKind struct {
+	// name of the kind in the messages, e.g. flow.
+	name string
+
+	// dataIDSuffix is appended to the app name to name the rules of the
+	// kind in the datasource.
+	dataIDSuffix string
+
+	// object is an empty object of the kind.
+	object sentinelv1alpha1.Rule
+
+	newList func() client.ObjectList
+
+	// render returns the JSON form of rule read by the Sentinel clients,
+	// and the keys on which it conflicts with the other rules of the kind.
+	render func(rule sentinelv1alpha1.Rule) (interface{}, []string)
+
+	// parse returns the rules of target held in the JSON form by a
+	// datasource, as new objects of the kind.
+	parse func(content []byte, target sentinelv1alpha1.RuleTarget) ([]sentinelv1alpha1.Rule, error)
+}
+
+// ruleKindsByType maps the object types of the rule kinds to the kinds.
+var ruleKindsByType = func() map[reflect.Type]*ruleKind {
+	kinds := make(map[reflect.Type]*ruleKind, len(ruleKinds))
+	for _, kind := range ruleKinds {
+		kinds[reflect.TypeOf(kind.object)] = kind
+	}
+	return kinds
+}()
+
+// ruleKindOf returns the kind of rule.
+func ruleKindOf(rule sentinelv1alpha1.Rule) (*ruleKind, error) {
+	if kind, ok := ruleKindsByType[reflect.TypeOf(rule)]; ok {
+		return kind, nil
+	}
+	return nil, errors.Errorf("unknown rule kind %T", rule)
+}
+
+// ruleSet is the rules of a kind of an app.
+type ruleSet struct {
+	kind      *ruleKind
+	namespace string
+	target    sentinelv1alpha1.RuleTarget
+
+	// rules are the rules of the app, from the oldest to the newest.
+	rules []sentinelv1alpha1.Rule
+
+	// published is the JSON form of the rules without conflicts.
+	published []interface{}
+
+	// conflicts maps the names of the rules left out to the reason why.
+	conflicts map[string]string
+
+	// entries maps the keys of the published rules to their JSON form.
+	entries map[string]string
+
+	// synced is the hashes of the rule set last synced with the datasource,
+	// recorded by the rules including the ones being deleted.
+	synced map[string]bool
+}
+
+// ruleSyncResult is the outcome of syncing a rule set with the datasource.
+type ruleSyncResult struct {
+	// hash of the rule set the datasource holds once synced, or empty when
+	// it still holds another one.
+	hash string
+
+	// rules is the number of rules the datasource holds once synced.
+	rules int
+
+	// drift describes the changes of the rule set made in the datasource,
+	// or is empty when it did not drift.
+	drift string
+}
+
+// RulePublisher publishes the rule sets of the apps to the datasources of
+// the dashboards. It is shared by the controllers of every rule kind, so the
+// rules of all the kinds of an app are published together, from the same
+// state of the dashboard, and never concurrently.
+type RulePublisher struct {
+	client.Client
+	Recorder record.EventRecorder
+
+	// HTTPClient talks to the datasources.
+	HTTPClient *http.Client
+
+	// SecretReader reads the datasource credentials, bypassing the cache.
+	SecretReader client.Reader
+
+	mu    sync.Mutex
+	locks map[string]*publishLock
+}
+
+// publishLock locks the rule sets of an app. It is dropped from the locks of
+// the publisher once no one holds or waits for it.
+type publishLock struct {
+	sync.Mutex
+	refs int
+}
+
+// NewRulePublisher returns a publisher using the client of the manager.
+func NewRulePublisher(mgr ctrl.Manager) *RulePublisher {
+	return &RulePublisher{
+		Client:       mgr.GetClient(),
+		Recorder:     mgr.GetEventRecorderFor("rule-controller"),
+		HTTPClient:   &http.Client{Transport: http.DefaultTransport, Timeout: defaultPublishTimeout},
+		SecretReader: mgr.GetAPIReader(),
+	}
+}
+
+// lock locks the rule sets of key and returns the function unlocking them.
+func (p *RulePublisher) lock(key string) func() {
+	p.mu.Lock()
+	if p.locks == nil {
+		p.locks = make(map[string]*publishLock)
+	}
+	l, ok := p.locks[key]
+	if !ok {
+		l = &publishLock{}
+		p.locks[key] = l
+	}
+	l.refs++
+	p.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		p.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(p.locks, key)
+		}
+		p.mu.Unlock()
+	}
+}
+
+// Publish publishes the rules of every kind of app, except the ones being
+// deleted, to the datasource of the dashboard, and reports the result on
+// every rule. When several rules of a kind conflict, the oldest one is
+// published and the others are reported as conflicting. A missing dashboard
+// or a dashboard without a Nacos datasource is only reported, as retrying
+// would not help.
+//
+// The rule sets the datasource holds are read back first. When one was
+// changed since it was last synced, e.g. in the dashboard UI, it is handled
+// according to the drift policy of the dashboard. Publish returns how long
+// until the rule sets should be read back again.
+func (p *RulePublisher) Publish(ctx context.Context, namespace, dashboard, app string) (time.Duration, error) {
+	defer p.lock(namespace + "/" + ruleTarget(dashboard, app))()
+
+	target := sentinelv1alpha1.RuleTarget{DashboardRef: corev1.LocalObjectReference{Name: dashboard}, App: app}
+	sets := make([]*ruleSet, 0, len(ruleKinds))
+	for _, kind := range ruleKinds {
+		set, err := p.newRuleSet(ctx, kind, namespace, target)
+		if err != nil {
+			return 0, err
+		}
+		sets = append(sets, set)
+	}
+
+	var instance sentinelv1alpha1.Dashboard
+	nacosClient, clientErr := p.nacosClient(ctx, types.NamespacedName{Namespace: namespace, Name: dashboard}, &instance)
+	policy, interval := ruleSyncSettings(&instance)
+	var errs []error
+	for _, set := range sets {
+		dataID := app + set.kind.dataIDSuffix
+		result := &ruleSyncResult{}
+		syncErr := clientErr
+		if syncErr == nil {
+			result, syncErr = p.syncRules(ctx, nacosClient, set, dataID, policy)
+		}
+
+		cond := metav1.Condition{
+			Status:  metav1.ConditionTrue,
+			Reason:  rulePublishedReason,
+			Message: fmt.Sprintf("published %d %s rules of app %s to %s", result.rules, set.kind.name, app, dataID),
+		}
+		var driftCond *metav1.Condition
+		switch {
+		case errors.Is(syncErr, errDashboardNotFound):
+			cond = metav1.Condition{Status: metav1.ConditionFalse, Reason: ruleDashboardNotFoundReason,
+				Message: fmt.Sprintf("dashboard %s not found", dashboard)}
+		case errors.Is(syncErr, errUnsupportedDatasource):
+			cond = metav1.Condition{Status: metav1.ConditionFalse, Reason: ruleUnsupportedDatasourceReason,
+				Message: fmt.Sprintf("dashboard %s has no nacos datasource", dashboard)}
+		case syncErr != nil:
+			cond = metav1.Condition{Status: metav1.ConditionFalse, Reason: rulePublishFailedReason,
+				Message: fmt.Sprintf("failed publishing %s rules of app %s: %v", set.kind.name, app, syncErr)}
+			errs = append(errs, errors.Wrapf(syncErr, "failed publishing %s rules of app %s", set.kind.name, app))
+		case result.drift == "":
+			driftCond = &metav1.Condition{Status: metav1.ConditionFalse, Reason: ruleInSyncReason,
+				Message: fmt.Sprintf("%s matches the %s rules of app %s", dataID, set.kind.name, app)}
+		default:
+			driftCond = &metav1.Condition{Status: metav1.ConditionFalse}
+			switch policy {
+			case sentinelv1alpha1.DriftPolicyAdopt:
+				driftCond.Reason = ruleAdoptedReason
+				driftCond.Message = fmt.Sprintf("adopted the rules changed in %s: %s", dataID, result.drift)
+				cond.Message = fmt.Sprintf("adopted %d %s rules of app %s from %s", result.rules, set.kind.name, app, dataID)
+			case sentinelv1alpha1.DriftPolicyReport:
+				driftCond.Status = metav1.ConditionTrue
+				driftCond.Reason = ruleDriftedReason
+				driftCond.Message = fmt.Sprintf("rules changed in %s: %s", dataID, result.drift)
+				cond = metav1.Condition{Status: metav1.ConditionFalse, Reason: ruleDriftedReason,
+					Message: fmt.Sprintf("not published, %s was changed outside of the rules", dataID)}
+			default:
+				driftCond.Reason = ruleEnforcedReason
+				driftCond.Message = fmt.Sprintf("overwrote the rules changed in %s: %s", dataID, result.drift)
+			}
+		}
+
+		for _, rule := range set.rules {
+			ruleCond := cond
+			if message, ok := set.conflicts[rule.GetName()]; ok && cond.Status == metav1.ConditionTrue {
+				ruleCond = metav1.Condition{Status: metav1.ConditionFalse, Reason: ruleConflictReason, Message: message}
+			}
+			if err := p.updateRuleStatus(ctx, rule, dataID, result, ruleCond, driftCond); err != nil {
+				errs = append(errs, errors.Wrapf(err, "failed updating status of %s rule %s", set.kind.name, rule.GetName()))
+			}
+		}
+	}
+	if clientErr != nil {
+		interval = 0
+	}
+	return interval, utilerrors.NewAggregate(errs)
+}
+
+// newRuleSet lists the rules of kind of app, and settles their conflicts.
+func (p *RulePublisher) newRuleSet(ctx context.Context, kind *ruleKind, namespace string,
+	target sentinelv1alpha1.RuleTarget) (*ruleSet, error) {
+	list := kind.newList()
+	if err := p.List(ctx, list, client.InNamespace(namespace),
+		client.MatchingFields{ruleTargetIndex: ruleTarget(target.DashboardRef.Name, target.App)}); err != nil {
+		return nil, errors.Wrapf(err, "failed listing %s rules", kind.name)
+	}
+	objs, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &ruleSet{
+		kind:      kind,
+		namespace: namespace,
+		target:    target,
+		published: []interface{}{},
+		conflicts: make(map[string]string),
+		entries:   make(map[string]string),
+		synced:    make(map[string]bool),
+	}
+	for _, obj := range objs {
+		rule, ok := obj.(sentinelv1alpha1.Rule)
+		if !ok {
+			continue
+		}
+		if hash := rule.GetRuleStatus().SyncedHash; hash != "" {
+			set.synced[hash] = true
+		}
+		if rule.GetDeletionTimestamp().IsZero() {
+			set.rules = append(set.rules, rule)
+		}
+	}
+	sortRules(set.rules)
+
+	declared := make(map[string]string)
+	for _, rule := range set.rules {
+		out, keys := kind.render(rule)
+		conflict := ""
+		for _, key := range keys {
+			if owner, ok := declared[key]; ok {
+				conflict = fmt.Sprintf("%s is already declared by %s", key, owner)
+				break
+			}
+		}
+		if conflict != "" {
+			set.conflicts[rule.GetName()] = conflict
+			continue
+		}
+		entry, err := json.Marshal(out)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			declared[key] = rule.GetName()
+			set.entries[key] = string(entry)
+		}
+		set.published = append(set.published, out)
+	}
+	return set, nil
+}
+
+// nacosClient gets the dashboard of key into instance, and returns a
+// client of its datasource.
+func (p *RulePublisher) nacosClient(ctx context.Context, key types.NamespacedName,
+	instance *sentinelv1alpha1.Dashboard) (*nacos.Client, error) {
+	if err := p.Get(ctx, key, instance); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, errDashboardNotFound
+		}
+		return nil, err
+	}
+	return newNacosClient(ctx, p.SecretReader, p.HTTPClient, instance)
+}
+
+// ruleSyncSettings returns the drift policy of the dashboard and how often
+// its rule sets are read back.
+func ruleSyncSettings(instance *sentinelv1alpha1.Dashboard) (sentinelv1alpha1.DriftPolicy, time.Duration) {
+	policy, interval := sentinelv1alpha1.DriftPolicyEnforce, sentinelv1alpha1.DefaultRuleSyncInterval
+	if sync := instance.Spec.RuleSync; sync != nil {
+		if sync.DriftPolicy != "" {
+			policy = sync.DriftPolicy
+		}
+		if sync.Interval != nil {
+			interval = sync.Interval.Duration
+		}
+	}
+	return policy, interval
+}
+
+// syncRules reads back the rule set the datasource holds in dataID, and
+// publishes the rules of set unless it already holds them. A rule set of a
+// kind without any rule is not written until it exists.
+//
+// The datasource drifted when it holds another rule set than the one last
+// synced. A drifted rule set is overwritten, adopted by the rules, or kept as
+// is, according to policy, while a missing one is always published again.
+func (p *RulePublisher) syncRules(ctx context.Context, nacosClient *nacos.Client, set *ruleSet,
+	dataID string, policy sentinelv1alpha1.DriftPolicy) (*ruleSyncResult, error) {
+	desired, err := json.Marshal(set.published)
+	if err != nil {
+		return nil, err
+	}
+	desiredHash := contentHash(desired)
+	published := &ruleSyncResult{hash: desiredHash, rules: len(set.published)}
+
+	content, found, err := nacosClient.GetConfig(ctx, dataID)
+	if err != nil {
+		return nil, err
+	}
+	if !found && len(set.published) == 0 {
+		return published, nil
+	}
+
+	var current []sentinelv1alpha1.Rule
+	var parseErr error
+	if found {
+		current, parseErr = set.kind.parse([]byte(content), set.target)
+	}
+	currentHash := contentHash([]byte(content))
+	if parseErr == nil {
+		normalized := make([]interface{}, 0, len(current))
+		for _, rule := range current {
+			out, _ := set.kind.render(rule)
+			normalized = append(normalized, out)
+		}
+		if normalizedContent, err := json.Marshal(normalized); err == nil {
+			currentHash = contentHash(normalizedContent)
+		}
+	}
+	if currentHash == desiredHash {
+		return published, nil
+	}
+
+	logger := log.FromContext(ctx)
+	if !found || len(set.synced) == 0 || set.synced[currentHash] {
+		logger.Info("publishing rules", "dataId", dataID, "rules", len(set.published))
+		return published, nacosClient.PublishConfig(ctx, dataID, string(desired))
+	}
+
+	drift := set.diff(current, parseErr)
+	logger.Info("rules drifted", "dataId", dataID, "policy", policy, "drift", drift)
+	switch policy {
+	case sentinelv1alpha1.DriftPolicyAdopt:
+		if parseErr != nil {
+			return nil, errors.Wrapf(parseErr, "failed adopting the rules of %s", dataID)
+		}
+		if err := p.adoptRules(ctx, set, current); err != nil {
+			return nil, err
+		}
+		return &ruleSyncResult{hash: currentHash, rules: len(current), drift: drift}, nil
+	case sentinelv1alpha1.DriftPolicyReport:
+		return &ruleSyncResult{drift: drift}, nil
+	default:
+		published.drift = drift
+		return published, nacosClient.PublishConfig(ctx, dataID, string(desired))
+	}
+}
+
+// diff describes how the rules the datasource holds differ from the
+// published rules of set, by their keys.
+func (set *ruleSet) diff(current []sentinelv1alpha1.Rule, parseErr error) string {
+	if parseErr != nil {
+		return fmt.Sprintf("unreadable rules: %v", parseErr)
+	}
+
+	entries := make(map[string]string)
+	for _, rule := range current {
+		out, keys := set.kind.render(rule)
+		entry, _ := json.Marshal(out)
+		for _, key := range keys {
+			entries[key] = string(entry)
+		}
+	}
+
+	var added, changed, removed []string
+	for key, entry := range entries {
+		declared, ok := set.entries[key]
+		switch {
+		case !ok:
+			added = append(added, key)
+		case declared != entry:
+			changed = append(changed, key)
+		}
+	}
+	for key := range set.entries {
+		if _, ok := entries[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+
+	var parts []string
+	for _, part := range []struct {
+		verb string
+		keys []string
+	}{{"added", added}, {"changed", changed}, {"removed", removed}} {
+		if len(part.keys) > 0 {
+			sort.Strings(part.keys)
+			parts = append(parts, fmt.Sprintf("%s %s", part.verb, strings.Join(part.keys, ", ")))
+		}
+	}
+	if len(parts) == 0 {
+		return "reordered rules"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// adoptRules writes the rules the datasource holds back to the rules of set:
+// the published rules declaring the same keys are updated, the missing ones
+// are created and the others are deleted. The conflicting rules are left as
+// they are.
+func (p *RulePublisher) adoptRules(ctx context.Context, set *ruleSet, current []sentinelv1alpha1.Rule) error {
+	owners := make(map[string]sentinelv1alpha1.Rule)
+	for _, rule := range set.rules {
+		if _, ok := set.conflicts[rule.GetName()]; ok {
+			continue
+		}
+		_, keys := set.kind.render(rule)
+		for _, key := range keys {
+			owners[key] = rule
+		}
+	}
+
+	logger := log.FromContext(ctx)
+	adopted := make(map[string]bool)
+	var errs []error
+	for _, rule := range current {
+		out, keys := set.kind.render(rule)
+		var owner sentinelv1alpha1.Rule
+		for _, key := range keys {
+			if o, ok := owners[key]; ok && !adopted[o.GetName()] {
+				owner = o
+				break
+			}
+		}
+
+		if owner == nil {
+			rule.SetNamespace(set.namespace)
+			rule.SetName(adoptedRuleName(set, keys))
+			logger.Info("creating adopted rule", "kind", set.kind.name, "name", rule.GetName())
+			if err := p.Create(ctx, rule); err != nil {
+				errs = append(errs, errors.Wrapf(err, "failed creating %s rule %s", set.kind.name, rule.GetName()))
+			}
+			continue
+		}
+
+		adopted[owner.GetName()] = true
+		if declared, _ := set.kind.render(owner); equality.Semantic.DeepEqual(declared, out) {
+			continue
+		}
+		updated, err := withSpecOf(owner, rule)
+		if err != nil {
+			return err
+		}
+		logger.Info("updating adopted rule", "kind", set.kind.name, "name", owner.GetName())
+		if err := p.Update(ctx, updated); err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed updating %s rule %s", set.kind.name, owner.GetName()))
+		}
+	}
+
+	for _, rule := range set.rules {
+		if _, ok := set.conflicts[rule.GetName()]; ok || adopted[rule.GetName()] {
+			continue
+		}
+		logger.Info("deleting rule removed from the datasource", "kind", set.kind.name, "name", rule.GetName())
+		if err := p.Delete(ctx, rule); client.IgnoreNotFound(err) != nil {
+			errs = append(errs, errors.Wrapf(err, "failed deleting %s rule %s", set.kind.name, rule.GetName()))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// withSpecOf returns a copy of rule with the spec of source.
+func withSpecOf(rule, source sentinelv1alpha1.Rule) (sentinelv1alpha1.Rule, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(rule)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := runtime.DefaultUnstructuredConverter.ToUnstructured(source)
+	if err != nil {
+		return nil, err
+	}
+	obj["spec"] = spec["spec"]
+
+	updated := rule.DeepCopyObject().(sentinelv1alpha1.Rule)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// adoptedRuleName names the rule created for a rule of set found in the
+// datasource, from the app and the keys of the rule.
+func adoptedRuleName(set *ruleSet, keys []string) string {
+	h := fnv.New32a()
+	h.Write([]byte(strings.Join(keys, "\x00")))
+	kind := strings.ReplaceAll(set.kind.name, " ", "")
+
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '-'
+	}, set.target.App)
+	name = strings.Trim(name, "-.")
+	if len(name) > 200 {
+		name = name[:200]
+	}
+	return fmt.Sprintf("%s-%s-%08x", name, kind, h.Sum32())
+}
+
+// contentHash returns the hash recorded for a rule set.
+func contentHash(content []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(content))
+}
+
+// updateRuleStatus sets the Published condition of a rule and, unless nil,
+// its Drifted condition, and records an event when the Published condition
+// changes, or when the Drifted condition changes to report a drift.
+func (p *RulePublisher) updateRuleStatus(ctx context.Context, rule sentinelv1alpha1.Rule,
+	dataID string, result *ruleSyncResult, cond metav1.Condition, driftCond *metav1.Condition) error {
+
+	status := rule.GetRuleStatus()
+	previous := meta.FindStatusCondition(status.Conditions, sentinelv1alpha1.PublishedConditionType)
+	changed := previous == nil || previous.Status != cond.Status || previous.Reason != cond.Reason
+	previousDrift := meta.FindStatusCondition(status.Conditions, sentinelv1alpha1.DriftedConditionType)
+	drifted := driftCond != nil && driftCond.Reason != ruleInSyncReason && (previousDrift == nil ||
+		previousDrift.Status != driftCond.Status || previousDrift.Reason != driftCond.Reason ||
+		previousDrift.Message != driftCond.Message)
+
+	next := status.DeepCopy()
+	next.ObservedGeneration = rule.GetGeneration()
+	next.DataID = dataID
+	if cond.Status == metav1.ConditionTrue {
+		next.PublishedRules = int32(result.rules)
+	}
+	if result.hash != "" {
+		next.SyncedHash = result.hash
+	}
+	cond.Type = sentinelv1alpha1.PublishedConditionType
+	cond.ObservedGeneration = rule.GetGeneration()
+	meta.SetStatusCondition(&next.Conditions, cond)
+	if driftCond != nil {
+		drift := *driftCond
+		drift.Type = sentinelv1alpha1.DriftedConditionType
+		drift.ObservedGeneration = rule.GetGeneration()
+		meta.SetStatusCondition(&next.Conditions, drift)
+	}
+	if equality.Semantic.DeepEqual(next, status) {
+		return nil
+	}
+
+	patch := client.MergeFrom(rule.DeepCopyObject().(client.Object))
+	*status = *next
+	if err := p.Status().Patch(ctx, rule, patch); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if changed {
+		eventType, reason := corev1.EventTypeNormal, event.RulePublish
+		if cond.Status != metav1.ConditionTrue {
+			eventType = corev1.EventTypeWarning
+		}
+		if cond.Reason == ruleConflictReason {
+			reason = event.RuleConflict
+		}
+		p.Recorder.Event(rule, eventType, string(reason), cond.Message)
+	}
+	if drifted {
+		p.Recorder.Event(rule, corev1.EventTypeWarning, string(event.RuleDrift), driftCond.Message)
+	}
+	return nil
+}
+
+// ruleTarget is the value of ruleTargetIndex of the rules of app published to dashboard.
+func ruleTarget(dashboard, app string) string {
+	return dashboard + "/" + app
+}
+
+// sortRules orders rules from the oldest to the newest, which is the order
+// they are published in and the order conflicts are settled in.
+func sortRules(rules []sentinelv1alpha1.Rule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return ruleOlder(rules[i], rules[j])
+	})
+}
+
+// ruleOlder reports whether a was created before b, or at the same time with
+// a name ordered first.
+func ruleOlder(a, b sentinelv1alpha1.Rule) bool {
+	ta, tb := a.GetCreationTimestamp(), b.GetCreationTimestamp()
+	if !ta.Equal(&tb) {
+		return ta.Before(&tb)
+	}
+	return a.GetName() < b.GetName()
+}
+
+// ruleID derives a positive id from the uid of a rule. It is kept below
+// 2^53 so the dashboard UI does not round it.
+func ruleID(uid types.UID) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(uid))
+	return int64(h.Sum64()&(1<<53-1)) + 1
+}
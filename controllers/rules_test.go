@@ -0,0 +1,355 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/event"
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/nacos"
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/nacos/nacostest"
+)
+
+var flowRulesKey = nacostest.Key{Group: nacos.DefaultGroup, DataID: "order-service-flow-rules"}
+
+// newCountedFlowRule returns a flow rule of order-service with a threshold of
+// count. It has no creation timestamp, like the rules the fake client creates,
+// so the rules are ordered by name.
+func newCountedFlowRule(name, res, count string) *sentinelv1alpha1.FlowRule {
+	rule := newFlowRule(name, "order-service", res, 0)
+	rule.CreationTimestamp = metav1.Time{}
+	rule.Spec.Count = resource.MustParse(count)
+	return rule
+}
+
+// flowRulesContent returns the rule set of rules held by the datasource.
+func flowRulesContent(rules ...*sentinelv1alpha1.FlowRule) string {
+	out := make([]interface{}, 0, len(rules))
+	for _, rule := range rules {
+		rendered, _ := renderFlowRule(rule)
+		out = append(out, rendered)
+	}
+	content, _ := json.Marshal(out)
+	return string(content)
+}
+
+// eventReasons drains the events recorded by recorder and returns their reasons.
+func eventReasons(recorder record.EventRecorder) []string {
+	events := recorder.(*record.FakeRecorder).Events
+	var reasons []string
+	for {
+		select {
+		case e := <-events:
+			reasons = append(reasons, strings.Fields(e)[1])
+		default:
+			return reasons
+		}
+	}
+}
+
+func TestPublishDrift(t *testing.T) {
+	get100 := newCountedFlowRule("order-flow", "GET:/orders", "100")
+	post10 := newCountedFlowRule("order-flow-post", "POST:/orders", "10")
+	get50 := newCountedFlowRule("order-flow", "GET:/orders", "50")
+	put5 := newCountedFlowRule("order-flow-put", "PUT:/orders", "5")
+	desired := []*sentinelv1alpha1.FlowRule{get100, post10}
+	adopted := adoptedRuleName(&ruleSet{kind: ruleKinds[0], target: newRuleTarget("order-service")},
+		[]string{"resource PUT:/orders of origin default"})
+	publish, drift := string(event.RulePublish), string(event.RuleDrift)
+
+	tests := []struct {
+		name          string
+		policy        sentinelv1alpha1.DriftPolicy
+		synced        []*sentinelv1alpha1.FlowRule
+		current       []*sentinelv1alpha1.FlowRule
+		wantContent   []*sentinelv1alpha1.FlowRule
+		wantSynced    []*sentinelv1alpha1.FlowRule
+		wantRules     map[string]string
+		wantPublished metav1.ConditionStatus
+		wantReason    string
+		wantDrift     string
+		wantEvents    []string
+	}{
+		{
+			name:          "rules never synced overwrite the datasource",
+			current:       []*sentinelv1alpha1.FlowRule{get50},
+			wantContent:   desired,
+			wantSynced:    desired,
+			wantRules:     map[string]string{"order-flow": "100", "order-flow-post": "10"},
+			wantPublished: metav1.ConditionTrue,
+			wantReason:    rulePublishedReason,
+			wantDrift:     ruleInSyncReason,
+			wantEvents:    []string{publish, publish},
+		},
+		{
+			name:          "missing rule set is published again",
+			synced:        desired,
+			wantContent:   desired,
+			wantSynced:    desired,
+			wantRules:     map[string]string{"order-flow": "100", "order-flow-post": "10"},
+			wantPublished: metav1.ConditionTrue,
+			wantReason:    rulePublishedReason,
+			wantDrift:     ruleInSyncReason,
+			wantEvents:    []string{publish, publish},
+		},
+		{
+			name:          "rule set last synced is updated",
+			policy:        sentinelv1alpha1.DriftPolicyReport,
+			synced:        []*sentinelv1alpha1.FlowRule{get50},
+			current:       []*sentinelv1alpha1.FlowRule{get50},
+			wantContent:   desired,
+			wantSynced:    desired,
+			wantRules:     map[string]string{"order-flow": "100", "order-flow-post": "10"},
+			wantPublished: metav1.ConditionTrue,
+			wantReason:    rulePublishedReason,
+			wantDrift:     ruleInSyncReason,
+			wantEvents:    []string{publish, publish},
+		},
+		{
+			name:          "enforce",
+			policy:        sentinelv1alpha1.DriftPolicyEnforce,
+			synced:        desired,
+			current:       []*sentinelv1alpha1.FlowRule{get50},
+			wantContent:   desired,
+			wantSynced:    desired,
+			wantRules:     map[string]string{"order-flow": "100", "order-flow-post": "10"},
+			wantPublished: metav1.ConditionTrue,
+			wantReason:    rulePublishedReason,
+			wantDrift:     ruleEnforcedReason,
+			wantEvents:    []string{publish, drift, publish, drift},
+		},
+		{
+			name:          "adopt changed and removed rules",
+			policy:        sentinelv1alpha1.DriftPolicyAdopt,
+			synced:        desired,
+			current:       []*sentinelv1alpha1.FlowRule{get50},
+			wantContent:   []*sentinelv1alpha1.FlowRule{get50},
+			wantSynced:    []*sentinelv1alpha1.FlowRule{get50},
+			wantRules:     map[string]string{"order-flow": "50"},
+			wantPublished: metav1.ConditionTrue,
+			wantReason:    rulePublishedReason,
+			wantDrift:     ruleAdoptedReason,
+			wantEvents:    []string{publish, drift, publish, drift},
+		},
+		{
+			name:          "adopt added rules",
+			policy:        sentinelv1alpha1.DriftPolicyAdopt,
+			synced:        desired,
+			current:       []*sentinelv1alpha1.FlowRule{get100, post10, put5},
+			wantContent:   []*sentinelv1alpha1.FlowRule{get100, post10, put5},
+			wantSynced:    []*sentinelv1alpha1.FlowRule{get100, post10, put5},
+			wantRules:     map[string]string{"order-flow": "100", "order-flow-post": "10", adopted: "5"},
+			wantPublished: metav1.ConditionTrue,
+			wantReason:    rulePublishedReason,
+			wantDrift:     ruleAdoptedReason,
+			wantEvents:    []string{publish, drift, publish, drift},
+		},
+		{
+			name:          "report",
+			policy:        sentinelv1alpha1.DriftPolicyReport,
+			synced:        desired,
+			current:       []*sentinelv1alpha1.FlowRule{get50},
+			wantContent:   []*sentinelv1alpha1.FlowRule{get50},
+			wantSynced:    desired,
+			wantRules:     map[string]string{"order-flow": "100", "order-flow-post": "10"},
+			wantPublished: metav1.ConditionFalse,
+			wantReason:    ruleDriftedReason,
+			wantDrift:     ruleDriftedReason,
+			wantEvents:    []string{publish, drift, publish, drift},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			server := nacostest.NewServer()
+			defer server.Close()
+			if tt.current != nil {
+				server.SetConfig(flowRulesKey, flowRulesContent(tt.current...))
+			}
+
+			dashboard := &sentinelv1alpha1.Dashboard{
+				ObjectMeta: metav1.ObjectMeta{Name: "sentinel-dashboard", Namespace: "sentinel-group"},
+				Spec: sentinelv1alpha1.DashboardSpec{
+					Datasource: &sentinelv1alpha1.DatasourceSpec{
+						Nacos: &sentinelv1alpha1.NacosDatasource{ServerAddr: []string{server.Addr()}},
+					},
+					RuleSync: &sentinelv1alpha1.RuleSyncSpec{DriftPolicy: tt.policy},
+				},
+			}
+			objs := []client.Object{dashboard}
+			for _, rule := range desired {
+				rule := rule.DeepCopy()
+				if tt.synced != nil {
+					rule.Status.SyncedHash = contentHash([]byte(flowRulesContent(tt.synced...)))
+				}
+				objs = append(objs, rule)
+			}
+			p := newTestRulePublisher(objs...)
+
+			_, err := p.Publish(context.Background(), "sentinel-group", "sentinel-dashboard", "order-service")
+			g.Expect(err).NotTo(HaveOccurred())
+			content, _ := server.Config(flowRulesKey)
+			g.Expect(content).To(Equal(flowRulesContent(tt.wantContent...)))
+			g.Expect(eventReasons(p.Recorder)).To(Equal(tt.wantEvents))
+
+			var list sentinelv1alpha1.FlowRuleList
+			g.Expect(p.List(context.Background(), &list)).To(Succeed())
+			counts := make(map[string]string)
+			for _, rule := range list.Items {
+				if rule.DeletionTimestamp.IsZero() {
+					counts[rule.Name] = rule.Spec.Count.String()
+				}
+			}
+			g.Expect(counts).To(Equal(tt.wantRules))
+
+			var rule sentinelv1alpha1.FlowRule
+			g.Expect(p.Get(context.Background(), client.ObjectKeyFromObject(get100), &rule)).To(Succeed())
+			g.Expect(rule.Status.SyncedHash).To(Equal(contentHash([]byte(flowRulesContent(tt.wantSynced...)))))
+			published := meta.FindStatusCondition(rule.Status.Conditions, sentinelv1alpha1.PublishedConditionType)
+			g.Expect(published.Status).To(Equal(tt.wantPublished))
+			g.Expect(published.Reason).To(Equal(tt.wantReason))
+			drifted := meta.FindStatusCondition(rule.Status.Conditions, sentinelv1alpha1.DriftedConditionType)
+			g.Expect(drifted.Reason).To(Equal(tt.wantDrift))
+
+			// a drift is only reported once, not on every read back
+			_, err = p.Publish(context.Background(), "sentinel-group", "sentinel-dashboard", "order-service")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(eventReasons(p.Recorder)).NotTo(ContainElement(drift))
+		})
+	}
+}
+
+func TestRuleSetDiff(t *testing.T) {
+	get100 := newCountedFlowRule("order-flow", "GET:/orders", "100")
+	post10 := newCountedFlowRule("order-flow-post", "POST:/orders", "10")
+
+	tests := []struct {
+		name     string
+		current  []*sentinelv1alpha1.FlowRule
+		parseErr error
+		want     string
+	}{
+		{
+			name:    "changed and removed",
+			current: []*sentinelv1alpha1.FlowRule{newCountedFlowRule("", "GET:/orders", "50")},
+			want:    "changed resource GET:/orders of origin default; removed resource POST:/orders of origin default",
+		},
+		{
+			name: "added",
+			current: []*sentinelv1alpha1.FlowRule{get100, post10,
+				newCountedFlowRule("", "PUT:/orders", "5"), newCountedFlowRule("", "DELETE:/orders", "5")},
+			want: "added resource DELETE:/orders of origin default, resource PUT:/orders of origin default",
+		},
+		{name: "reordered", current: []*sentinelv1alpha1.FlowRule{post10, get100}, want: "reordered rules"},
+		{name: "unreadable", parseErr: errors.New("invalid character"), want: "unreadable rules: invalid character"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			p := newTestRulePublisher(get100.DeepCopy(), post10.DeepCopy())
+			set, err := p.newRuleSet(context.Background(), ruleKinds[0], "sentinel-group", newRuleTarget("order-service"))
+			g.Expect(err).NotTo(HaveOccurred())
+
+			current := make([]sentinelv1alpha1.Rule, 0, len(tt.current))
+			for _, rule := range tt.current {
+				current = append(current, rule)
+			}
+			g.Expect(set.diff(current, tt.parseErr)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestAdoptedRuleName(t *testing.T) {
+	keys := []string{"resource GET:/orders of origin default"}
+
+	tests := []struct {
+		name string
+		kind *ruleKind
+		app  string
+		want string
+	}{
+		{name: "app", kind: ruleKinds[0], app: "order-service", want: `^order-service-flow-[0-9a-f]{8}$`},
+		{name: "kind with a space", kind: ruleKinds[4], app: "order-service", want: `^order-service-paramflow-[0-9a-f]{8}$`},
+		{name: "invalid characters", kind: ruleKinds[0], app: "-Order_Service.", want: `^order-service-flow-[0-9a-f]{8}$`},
+		{name: "long app", kind: ruleKinds[0], app: strings.Repeat("a", 300), want: `^a{200}-flow-[0-9a-f]{8}$`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			set := &ruleSet{kind: tt.kind, target: newRuleTarget(tt.app)}
+			g.Expect(adoptedRuleName(set, keys)).To(MatchRegexp(tt.want))
+		})
+	}
+
+	g := NewWithT(t)
+	set := &ruleSet{kind: ruleKinds[0], target: newRuleTarget("order-service")}
+	g.Expect(adoptedRuleName(set, keys)).To(Equal(adoptedRuleName(set, keys)))
+	g.Expect(adoptedRuleName(set, keys)).NotTo(Equal(adoptedRuleName(set, []string{"resource PUT:/orders of origin default"})))
+}
+
+func TestWithSpecOf(t *testing.T) {
+	g := NewWithT(t)
+	owner := newFlowRule("order-flow", "order-service", "GET:/orders", time.Hour)
+	owner.Spec.Count = resource.MustParse("100")
+	owner.Status.SyncedHash = "synced"
+	source := newCountedFlowRule("", "GET:/orders", "50")
+	source.Spec.LimitApp = "gateway"
+
+	updated, err := withSpecOf(owner, source)
+	g.Expect(err).NotTo(HaveOccurred())
+	rule := updated.(*sentinelv1alpha1.FlowRule)
+	g.Expect(rule.CreationTimestamp.Equal(&owner.CreationTimestamp)).To(BeTrue())
+	rule.CreationTimestamp = owner.CreationTimestamp
+	g.Expect(rule.ObjectMeta).To(Equal(owner.ObjectMeta))
+	g.Expect(rule.Status).To(Equal(owner.Status))
+	g.Expect(rule.Spec.Count.String()).To(Equal("50"))
+	g.Expect(rule.Spec.LimitApp).To(Equal("gateway"))
+	g.Expect(owner.Spec.Count.String()).To(Equal("100"))
+}
+
+func TestRuleKindOf(t *testing.T) {
+	g := NewWithT(t)
+	for _, kind := range ruleKinds {
+		got, err := ruleKindOf(kind.object.DeepCopyObject().(sentinelv1alpha1.Rule))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(got).To(BeIdenticalTo(kind))
+	}
+
+	_, err := ruleKindOf(struct{ *sentinelv1alpha1.FlowRule }{})
+	g.Expect(err).To(MatchError(ContainSubstring("unknown rule kind")))
+}
+
+func TestPublishLock(t *testing.T) {
+	g := NewWithT(t)
+	p := newTestRulePublisher()
+
+	unlock := p.lock("sentinel-group/sentinel/order-service")
+	locked := make(chan struct{})
+	go func() {
+		defer p.lock("sentinel-group/sentinel/order-service")()
+		close(locked)
+	}()
+	g.Consistently(locked, 50*time.Millisecond).ShouldNot(BeClosed())
+	unlock()
+	g.Eventually(locked).Should(BeClosed())
+
+	g.Eventually(func() int {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return len(p.locks)
+	}).Should(BeZero(), "released locks are dropped")
+}
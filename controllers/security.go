@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/event"
+)
+
+const (
+	podSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+
+	podSecurityBaseline   = "baseline"
+	podSecurityRestricted = "restricted"
+)
+
+// baselineCapabilities are the capabilities the baseline level allows to add.
+var baselineCapabilities = map[corev1.Capability]bool{
+	"AUDIT_WRITE": true, "CHOWN": true, "DAC_OVERRIDE": true, "FOWNER": true, "FSETID": true,
+	"KILL": true, "MKNOD": true, "NET_BIND_SERVICE": true, "SETFCAP": true, "SETGID": true,
+	"SETPCAP": true, "SETUID": true, "SYS_CHROOT": true,
+}
+
+// UpdatePodSecurityStatus sets the PodSecurity condition from the checks of
+// the level enforced on the namespace of the dashboard, and records an event
+// when the pod starts violating it. Only the fields the Dashboard lets users
+// set are checked.
+func (r *DashboardReconciler) UpdatePodSecurityStatus(ctx context.Context, instance *sentinelv1alpha1.Dashboard) error {
+	var ns corev1.Namespace
+	if err := r.Get(ctx, types.NamespacedName{Name: instance.Namespace}, &ns); err != nil {
+		return errors.Wrap(err, "failed getting namespace")
+	}
+
+	level := ns.Labels[podSecurityEnforceLabel]
+	if level != podSecurityBaseline && level != podSecurityRestricted {
+		return r.UpdateCondition(ctx, instance, sentinelv1alpha1.PodSecurityConditionType, metav1.ConditionTrue,
+			"NotEnforced", "the namespace does not enforce the baseline or restricted level")
+	}
+
+	violations := podSecurityViolations(level, newPodTemplate(instance).Spec)
+	if len(violations) == 0 {
+		return r.UpdateCondition(ctx, instance, sentinelv1alpha1.PodSecurityConditionType, metav1.ConditionTrue,
+			"Compliant", fmt.Sprintf("the pod satisfies the %s level enforced on the namespace", level))
+	}
+
+	previous := r.GetCondition(ctx, instance, sentinelv1alpha1.PodSecurityConditionType)
+	message := fmt.Sprintf("the pod violates the %s level enforced on the namespace: %s", level, strings.Join(violations, "; "))
+	if err := r.UpdateCondition(ctx, instance, sentinelv1alpha1.PodSecurityConditionType, metav1.ConditionFalse,
+		"PolicyViolation", message); err != nil {
+		return err
+	}
+	if previous.Status != metav1.ConditionFalse {
+		r.Recorder.Eventf(instance, corev1.EventTypeWarning, string(event.DashboardPodSecurity),
+			"Dashboard %s pods will be rejected: %s", instance.Namespace+"/"+instance.Name, message)
+	}
+	return nil
+}
+
+// podSecurityViolations checks the security contexts of spec against the
+// baseline or restricted Pod Security Standard.
+func podSecurityViolations(level string, spec corev1.PodSpec) []string {
+	var violations []string
+	pod := spec.SecurityContext
+	if pod == nil {
+		pod = &corev1.PodSecurityContext{}
+	}
+
+	if pod.SeccompProfile != nil && pod.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+		violations = append(violations, "pod seccompProfile must not be Unconfined")
+	}
+	for _, c := range spec.Containers {
+		sc := c.SecurityContext
+		if sc == nil {
+			sc = &corev1.SecurityContext{}
+		}
+		prefix := "container " + c.Name + " "
+
+		if sc.Privileged != nil && *sc.Privileged {
+			violations = append(violations, prefix+"must not be privileged")
+		}
+		if sc.ProcMount != nil && *sc.ProcMount != corev1.DefaultProcMount {
+			violations = append(violations, prefix+"procMount must be Default")
+		}
+		if sc.SeccompProfile != nil && sc.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+			violations = append(violations, prefix+"seccompProfile must not be Unconfined")
+		}
+		var added []corev1.Capability
+		if sc.Capabilities != nil {
+			added = sc.Capabilities.Add
+		}
+		for _, capability := range added {
+			if !baselineCapabilities[capability] {
+				violations = append(violations, fmt.Sprintf("%smust not add capability %s", prefix, capability))
+			}
+		}
+		if level != podSecurityRestricted {
+			continue
+		}
+
+		if sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+			violations = append(violations, prefix+"allowPrivilegeEscalation must be false")
+		}
+		if !boolOr(sc.RunAsNonRoot, pod.RunAsNonRoot) {
+			violations = append(violations, prefix+"runAsNonRoot must be true")
+		}
+		if runAsUser := int64Or(sc.RunAsUser, pod.RunAsUser); runAsUser != nil && *runAsUser == 0 {
+			violations = append(violations, prefix+"runAsUser must not be 0")
+		}
+		seccomp := sc.SeccompProfile
+		if seccomp == nil {
+			seccomp = pod.SeccompProfile
+		}
+		if seccomp == nil || (seccomp.Type != corev1.SeccompProfileTypeRuntimeDefault && seccomp.Type != corev1.SeccompProfileTypeLocalhost) {
+			violations = append(violations, prefix+"seccompProfile must be RuntimeDefault or Localhost")
+		}
+		dropsAll := false
+		if sc.Capabilities != nil {
+			for _, capability := range sc.Capabilities.Drop {
+				dropsAll = dropsAll || capability == "ALL"
+			}
+		}
+		if !dropsAll {
+			violations = append(violations, prefix+"must drop capability ALL")
+		}
+		for _, capability := range added {
+			if capability != "NET_BIND_SERVICE" && baselineCapabilities[capability] {
+				violations = append(violations, fmt.Sprintf("%smust not add capability %s", prefix, capability))
+			}
+		}
+	}
+	return violations
+}
+
+// boolOr returns the container level value if set, else the pod level one.
+func boolOr(container, pod *bool) bool {
+	if container != nil {
+		return *container
+	}
+	return pod != nil && *pod
+}
+
+// int64Or returns the container level value if set, else the pod level one.
+func int64Or(container, pod *int64) *int64 {
+	if container != nil {
+		return container
+	}
+	return pod
+}
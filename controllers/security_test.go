@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+)
+
+func TestPodSecurityViolations(t *testing.T) {
+	restricted := func() corev1.PodSpec {
+		return corev1.PodSpec{
+			SecurityContext: &corev1.PodSecurityContext{
+				RunAsNonRoot:   pointer.Bool(true),
+				SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+			},
+			Containers: []corev1.Container{{
+				Name: "sentinel",
+				SecurityContext: &corev1.SecurityContext{
+					AllowPrivilegeEscalation: pointer.Bool(false),
+					Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+				},
+			}},
+		}
+	}
+	unmasked := corev1.UnmaskedProcMount
+
+	tests := []struct {
+		name   string
+		level  string
+		mutate func(*corev1.PodSpec)
+		want   []string
+	}{
+		{name: "restricted", level: podSecurityRestricted},
+		{
+			name:  "no security context is baseline",
+			level: podSecurityBaseline,
+			mutate: func(spec *corev1.PodSpec) {
+				spec.SecurityContext = nil
+				spec.Containers[0].SecurityContext = nil
+			},
+		},
+		{
+			name:  "no security context is not restricted",
+			level: podSecurityRestricted,
+			mutate: func(spec *corev1.PodSpec) {
+				spec.SecurityContext = nil
+				spec.Containers[0].SecurityContext = nil
+			},
+			want: []string{
+				"container sentinel allowPrivilegeEscalation must be false",
+				"container sentinel runAsNonRoot must be true",
+				"container sentinel seccompProfile must be RuntimeDefault or Localhost",
+				"container sentinel must drop capability ALL",
+			},
+		},
+		{
+			name:  "baseline violations",
+			level: podSecurityBaseline,
+			mutate: func(spec *corev1.PodSpec) {
+				spec.SecurityContext.SeccompProfile.Type = corev1.SeccompProfileTypeUnconfined
+				sc := spec.Containers[0].SecurityContext
+				sc.Privileged = pointer.Bool(true)
+				sc.ProcMount = &unmasked
+				sc.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined}
+				sc.Capabilities.Add = []corev1.Capability{"NET_BIND_SERVICE", "SYS_ADMIN"}
+			},
+			want: []string{
+				"pod seccompProfile must not be Unconfined",
+				"container sentinel must not be privileged",
+				"container sentinel procMount must be Default",
+				"container sentinel seccompProfile must not be Unconfined",
+				"container sentinel must not add capability SYS_ADMIN",
+			},
+		},
+		{
+			name:  "container settings win over the pod ones",
+			level: podSecurityRestricted,
+			mutate: func(spec *corev1.PodSpec) {
+				sc := spec.Containers[0].SecurityContext
+				sc.RunAsNonRoot = pointer.Bool(false)
+				sc.RunAsUser = pointer.Int64(0)
+				sc.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeLocalhost}
+			},
+			want: []string{
+				"container sentinel runAsNonRoot must be true",
+				"container sentinel runAsUser must not be 0",
+			},
+		},
+		{
+			name:  "restricted capabilities",
+			level: podSecurityRestricted,
+			mutate: func(spec *corev1.PodSpec) {
+				spec.Containers[0].SecurityContext.Capabilities.Add = []corev1.Capability{"NET_BIND_SERVICE", "CHOWN"}
+			},
+			want: []string{"container sentinel must not add capability CHOWN"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			spec := restricted()
+			if tt.mutate != nil {
+				tt.mutate(&spec)
+			}
+			g.Expect(podSecurityViolations(tt.level, spec)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestDefaultPodTemplateIsRestricted(t *testing.T) {
+	g := NewWithT(t)
+	instance := &sentinelv1alpha1.Dashboard{ObjectMeta: metav1.ObjectMeta{Name: "sentinel"}}
+	instance.SetDefaults("")
+	g.Expect(podSecurityViolations(podSecurityRestricted, newPodTemplate(instance).Spec)).To(BeEmpty())
+}
+
+func TestUpdatePodSecurityStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		level      string
+		privileged bool
+		wantStatus metav1.ConditionStatus
+		wantReason string
+		wantEvents int
+	}{
+		{name: "not enforced", level: "privileged", privileged: true, wantStatus: metav1.ConditionTrue, wantReason: "NotEnforced"},
+		{name: "compliant", level: podSecurityRestricted, wantStatus: metav1.ConditionTrue, wantReason: "Compliant"},
+		{
+			name:       "violation",
+			level:      podSecurityBaseline,
+			privileged: true,
+			wantStatus: metav1.ConditionFalse,
+			wantReason: "PolicyViolation",
+			wantEvents: 1,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   "sentinel-group",
+				Labels: map[string]string{podSecurityEnforceLabel: tt.level},
+			}}
+			instance := &sentinelv1alpha1.Dashboard{ObjectMeta: metav1.ObjectMeta{Name: "sentinel", Namespace: ns.Name}}
+			instance.SetDefaults("")
+			if tt.privileged {
+				instance.Spec.SecurityContext = &corev1.SecurityContext{Privileged: pointer.Bool(true)}
+			}
+			r := newTestDashboardReconciler(ns)
+
+			// the event is only recorded when the pod starts violating the level
+			for i := 0; i < 2; i++ {
+				g.Expect(r.UpdatePodSecurityStatus(context.Background(), instance)).To(Succeed())
+			}
+			cond := r.GetCondition(context.Background(), instance, sentinelv1alpha1.PodSecurityConditionType)
+			g.Expect(cond.Status).To(Equal(tt.wantStatus))
+			g.Expect(cond.Reason).To(Equal(tt.wantReason))
+			g.Expect(r.Recorder.(*record.FakeRecorder).Events).To(HaveLen(tt.wantEvents))
+		})
+	}
+}
@@ -0,0 +1,536 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+)
+
+// ruleKinds are the rule kinds published to the datasources. The data ids
+// are the ones the Nacos rule providers of the dashboard read.
+var ruleKinds = []*ruleKind{
+	{
+		name:         "flow",
+		dataIDSuffix: "-flow-rules",
+		object:       &sentinelv1alpha1.FlowRule{},
+		newList:      func() client.ObjectList { return &sentinelv1alpha1.FlowRuleList{} },
+		render:       renderFlowRule,
+		parse:        parseFlowRules,
+	},
+	{
+		name:         "degrade",
+		dataIDSuffix: "-degrade-rules",
+		object:       &sentinelv1alpha1.DegradeRule{},
+		newList:      func() client.ObjectList { return &sentinelv1alpha1.DegradeRuleList{} },
+		render:       renderDegradeRule,
+		parse:        parseDegradeRules,
+	},
+	{
+		name:         "system",
+		dataIDSuffix: "-system-rules",
+		object:       &sentinelv1alpha1.SystemRule{},
+		newList:      func() client.ObjectList { return &sentinelv1alpha1.SystemRuleList{} },
+		render:       renderSystemRule,
+		parse:        parseSystemRules,
+	},
+	{
+		name:         "authority",
+		dataIDSuffix: "-authority-rules",
+		object:       &sentinelv1alpha1.AuthorityRule{},
+		newList:      func() client.ObjectList { return &sentinelv1alpha1.AuthorityRuleList{} },
+		render:       renderAuthorityRule,
+		parse:        parseAuthorityRules,
+	},
+	{
+		name:         "param flow",
+		dataIDSuffix: "-param-rules",
+		object:       &sentinelv1alpha1.ParamFlowRule{},
+		newList:      func() client.ObjectList { return &sentinelv1alpha1.ParamFlowRuleList{} },
+		render:       renderParamFlowRule,
+		parse:        parseParamFlowRules,
+	},
+}
+
+// defaultLimitApp is the origin of the rules applying to any origin.
+const defaultLimitApp = "default"
+
+// sentinelFlowRule is the JSON form of a flow rule read by the Sentinel clients.
+type sentinelFlowRule struct {
+	App               string                 `json:"app"`
+	Resource          string                 `json:"resource"`
+	LimitApp          string                 `json:"limitApp"`
+	Grade             int                    `json:"grade"`
+	Count             float64                `json:"count"`
+	Strategy          int                    `json:"strategy"`
+	RefResource       string                 `json:"refResource,omitempty"`
+	ControlBehavior   int                    `json:"controlBehavior"`
+	WarmUpPeriodSec   int32                  `json:"warmUpPeriodSec"`
+	MaxQueueingTimeMs int32                  `json:"maxQueueingTimeMs"`
+	ClusterMode       bool                   `json:"clusterMode"`
+	ClusterConfig     *sentinelClusterConfig `json:"clusterConfig,omitempty"`
+}
+
+// sentinelClusterConfig is the JSON form of the cluster settings of the flow
+// and param flow rules.
+type sentinelClusterConfig struct {
+	FlowID                  int64 `json:"flowId"`
+	ThresholdType           int   `json:"thresholdType"`
+	FallbackToLocalWhenFail bool  `json:"fallbackToLocalWhenFail"`
+}
+
+// sentinelDegradeRule is the JSON form of a circuit breaking rule.
+type sentinelDegradeRule struct {
+	App                string  `json:"app"`
+	Resource           string  `json:"resource"`
+	LimitApp           string  `json:"limitApp"`
+	Grade              int     `json:"grade"`
+	Count              float64 `json:"count"`
+	SlowRatioThreshold float64 `json:"slowRatioThreshold"`
+	TimeWindow         int32   `json:"timeWindow"`
+	MinRequestAmount   int32   `json:"minRequestAmount"`
+	StatIntervalMs     int32   `json:"statIntervalMs"`
+}
+
+// sentinelSystemRule is the JSON form of a system rule. Unset thresholds are -1.
+type sentinelSystemRule struct {
+	App               string  `json:"app"`
+	HighestSystemLoad float64 `json:"highestSystemLoad"`
+	HighestCPUUsage   float64 `json:"highestCpuUsage"`
+	QPS               float64 `json:"qps"`
+	AvgRT             int64   `json:"avgRt"`
+	MaxThread         int64   `json:"maxThread"`
+}
+
+// sentinelAuthorityRule is the JSON form of an authority rule.
+type sentinelAuthorityRule struct {
+	App      string `json:"app"`
+	Resource string `json:"resource"`
+	LimitApp string `json:"limitApp"`
+	Strategy int    `json:"strategy"`
+}
+
+// sentinelParamFlowRule is the JSON form of a hot parameter flow rule.
+type sentinelParamFlowRule struct {
+	App               string                  `json:"app"`
+	Resource          string                  `json:"resource"`
+	LimitApp          string                  `json:"limitApp"`
+	Grade             int                     `json:"grade"`
+	ParamIdx          int32                   `json:"paramIdx"`
+	Count             float64                 `json:"count"`
+	ControlBehavior   int                     `json:"controlBehavior"`
+	MaxQueueingTimeMs int32                   `json:"maxQueueingTimeMs"`
+	BurstCount        int32                   `json:"burstCount"`
+	DurationInSec     int64                   `json:"durationInSec"`
+	ParamFlowItemList []sentinelParamFlowItem `json:"paramFlowItemList"`
+	ClusterMode       bool                    `json:"clusterMode"`
+	ClusterConfig     *sentinelClusterConfig  `json:"clusterConfig,omitempty"`
+}
+
+type sentinelParamFlowItem struct {
+	Object    string `json:"object"`
+	Count     int32  `json:"count"`
+	ClassType string `json:"classType"`
+}
+
+var (
+	flowGrades = map[sentinelv1alpha1.FlowGrade]int{
+		sentinelv1alpha1.FlowGradeThread: 0,
+		sentinelv1alpha1.FlowGradeQPS:    1,
+	}
+	flowStrategies = map[sentinelv1alpha1.FlowStrategy]int{
+		sentinelv1alpha1.FlowStrategyDirect: 0,
+		sentinelv1alpha1.FlowStrategyRelate: 1,
+		sentinelv1alpha1.FlowStrategyChain:  2,
+	}
+	flowControlBehaviors = map[sentinelv1alpha1.FlowControlBehavior]int{
+		sentinelv1alpha1.FlowControlBehaviorDefault:           0,
+		sentinelv1alpha1.FlowControlBehaviorWarmUp:            1,
+		sentinelv1alpha1.FlowControlBehaviorRateLimiter:       2,
+		sentinelv1alpha1.FlowControlBehaviorWarmUpRateLimiter: 3,
+	}
+	clusterThresholdTypes = map[sentinelv1alpha1.ClusterThresholdType]int{
+		sentinelv1alpha1.ClusterThresholdAvgLocal: 0,
+		sentinelv1alpha1.ClusterThresholdGlobal:   1,
+	}
+	degradeGrades = map[sentinelv1alpha1.DegradeGrade]int{
+		sentinelv1alpha1.DegradeGradeSlowRequestRatio: 0,
+		sentinelv1alpha1.DegradeGradeErrorRatio:       1,
+		sentinelv1alpha1.DegradeGradeErrorCount:       2,
+	}
+	authorityStrategies = map[sentinelv1alpha1.AuthorityStrategy]int{
+		sentinelv1alpha1.AuthorityStrategyWhite: 0,
+		sentinelv1alpha1.AuthorityStrategyBlack: 1,
+	}
+)
+
+// flowGrade returns the Sentinel grade of grade, which defaults to QPS.
+func flowGrade(grade sentinelv1alpha1.FlowGrade) int {
+	if value, ok := flowGrades[grade]; ok {
+		return value
+	}
+	return flowGrades[sentinelv1alpha1.FlowGradeQPS]
+}
+
+// newSentinelClusterConfig renders the cluster settings of a rule in cluster mode.
+func newSentinelClusterConfig(rule sentinelv1alpha1.Rule, config *sentinelv1alpha1.FlowClusterConfig) *sentinelClusterConfig {
+	if config == nil {
+		config = &sentinelv1alpha1.FlowClusterConfig{}
+	}
+	out := &sentinelClusterConfig{
+		FlowID:                  ruleID(rule.GetUID()),
+		ThresholdType:           clusterThresholdTypes[config.ThresholdType],
+		FallbackToLocalWhenFail: config.FallbackToLocalWhenFail == nil || *config.FallbackToLocalWhenFail,
+	}
+	if config.FlowID != nil {
+		out.FlowID = *config.FlowID
+	}
+	return out
+}
+
+func renderFlowRule(obj sentinelv1alpha1.Rule) (interface{}, []string) {
+	rule := obj.(*sentinelv1alpha1.FlowRule)
+	limitApp := rule.Spec.LimitApp
+	if limitApp == "" {
+		limitApp = defaultLimitApp
+	}
+	out := sentinelFlowRule{
+		App:               rule.Spec.App,
+		Resource:          rule.Spec.Resource,
+		LimitApp:          limitApp,
+		Grade:             flowGrade(rule.Spec.Grade),
+		Count:             rule.Spec.Count.AsApproximateFloat64(),
+		Strategy:          flowStrategies[rule.Spec.Strategy],
+		RefResource:       rule.Spec.RefResource,
+		ControlBehavior:   flowControlBehaviors[rule.Spec.ControlBehavior],
+		WarmUpPeriodSec:   10,
+		MaxQueueingTimeMs: 500,
+		ClusterMode:       rule.Spec.ClusterMode,
+	}
+	if rule.Spec.WarmUpPeriodSec != nil {
+		out.WarmUpPeriodSec = *rule.Spec.WarmUpPeriodSec
+	}
+	if rule.Spec.MaxQueueingTimeMs != nil {
+		out.MaxQueueingTimeMs = *rule.Spec.MaxQueueingTimeMs
+	}
+	if rule.Spec.ClusterMode {
+		out.ClusterConfig = newSentinelClusterConfig(rule, rule.Spec.ClusterConfig)
+	}
+	return out, []string{fmt.Sprintf("resource %s of origin %s", rule.Spec.Resource, limitApp)}
+}
+
+func renderDegradeRule(obj sentinelv1alpha1.Rule) (interface{}, []string) {
+	rule := obj.(*sentinelv1alpha1.DegradeRule)
+	out := sentinelDegradeRule{
+		App:                rule.Spec.App,
+		Resource:           rule.Spec.Resource,
+		LimitApp:           defaultLimitApp,
+		Grade:              degradeGrades[rule.Spec.Grade],
+		Count:              rule.Spec.Count.AsApproximateFloat64(),
+		SlowRatioThreshold: 1,
+		TimeWindow:         rule.Spec.TimeWindow,
+		MinRequestAmount:   5,
+		StatIntervalMs:     1000,
+	}
+	if rule.Spec.SlowRatioThreshold != nil {
+		out.SlowRatioThreshold = rule.Spec.SlowRatioThreshold.AsApproximateFloat64()
+	}
+	if rule.Spec.MinRequestAmount != nil {
+		out.MinRequestAmount = *rule.Spec.MinRequestAmount
+	}
+	if rule.Spec.StatIntervalMs != nil {
+		out.StatIntervalMs = *rule.Spec.StatIntervalMs
+	}
+	grade := rule.Spec.Grade
+	if grade == "" {
+		grade = sentinelv1alpha1.DegradeGradeSlowRequestRatio
+	}
+	return out, []string{fmt.Sprintf("resource %s with grade %s", rule.Spec.Resource, grade)}
+}
+
+func renderSystemRule(obj sentinelv1alpha1.Rule) (interface{}, []string) {
+	rule := obj.(*sentinelv1alpha1.SystemRule)
+	out := sentinelSystemRule{
+		App:               rule.Spec.App,
+		HighestSystemLoad: -1,
+		HighestCPUUsage:   -1,
+		QPS:               -1,
+		AvgRT:             -1,
+		MaxThread:         -1,
+	}
+	var keys []string
+	if rule.Spec.HighestSystemLoad != nil {
+		out.HighestSystemLoad = rule.Spec.HighestSystemLoad.AsApproximateFloat64()
+		keys = append(keys, "threshold highestSystemLoad")
+	}
+	if rule.Spec.HighestCPUUsage != nil {
+		out.HighestCPUUsage = rule.Spec.HighestCPUUsage.AsApproximateFloat64()
+		keys = append(keys, "threshold highestCpuUsage")
+	}
+	if rule.Spec.QPS != nil {
+		out.QPS = rule.Spec.QPS.AsApproximateFloat64()
+		keys = append(keys, "threshold qps")
+	}
+	if rule.Spec.AvgRT != nil {
+		out.AvgRT = *rule.Spec.AvgRT
+		keys = append(keys, "threshold avgRt")
+	}
+	if rule.Spec.MaxThread != nil {
+		out.MaxThread = *rule.Spec.MaxThread
+		keys = append(keys, "threshold maxThread")
+	}
+	return out, keys
+}
+
+func renderAuthorityRule(obj sentinelv1alpha1.Rule) (interface{}, []string) {
+	rule := obj.(*sentinelv1alpha1.AuthorityRule)
+	out := sentinelAuthorityRule{
+		App:      rule.Spec.App,
+		Resource: rule.Spec.Resource,
+		LimitApp: strings.Join(rule.Spec.LimitApps, ","),
+		Strategy: authorityStrategies[rule.Spec.Strategy],
+	}
+	return out, []string{fmt.Sprintf("resource %s", rule.Spec.Resource)}
+}
+
+func renderParamFlowRule(obj sentinelv1alpha1.Rule) (interface{}, []string) {
+	rule := obj.(*sentinelv1alpha1.ParamFlowRule)
+	out := sentinelParamFlowRule{
+		App:               rule.Spec.App,
+		Resource:          rule.Spec.Resource,
+		LimitApp:          defaultLimitApp,
+		Grade:             flowGrade(rule.Spec.Grade),
+		ParamIdx:          rule.Spec.ParamIdx,
+		Count:             rule.Spec.Count.AsApproximateFloat64(),
+		ControlBehavior:   flowControlBehaviors[rule.Spec.ControlBehavior],
+		BurstCount:        rule.Spec.BurstCount,
+		DurationInSec:     rule.Spec.DurationInSec,
+		ParamFlowItemList: []sentinelParamFlowItem{},
+		ClusterMode:       rule.Spec.ClusterMode,
+	}
+	if rule.Spec.MaxQueueingTimeMs != nil {
+		out.MaxQueueingTimeMs = *rule.Spec.MaxQueueingTimeMs
+	}
+	if out.DurationInSec == 0 {
+		out.DurationInSec = 1
+	}
+	for _, item := range rule.Spec.ParamFlowItems {
+		classType := item.ClassType
+		if classType == "" {
+			classType = "java.lang.String"
+		}
+		out.ParamFlowItemList = append(out.ParamFlowItemList, sentinelParamFlowItem{
+			Object:    item.Object,
+			Count:     item.Count,
+			ClassType: classType,
+		})
+	}
+	if rule.Spec.ClusterMode {
+		out.ClusterConfig = newSentinelClusterConfig(rule, rule.Spec.ClusterConfig)
+	}
+	return out, []string{fmt.Sprintf("resource %s parameter %d", rule.Spec.Resource, rule.Spec.ParamIdx)}
+}
+
+// enumOf returns the key of values mapped to value, or fallback.
+func enumOf[T comparable](values map[T]int, value int, fallback T) T {
+	for key, v := range values {
+		if v == value {
+			return key
+		}
+	}
+	return fallback
+}
+
+// floatQuantity returns the quantity of a threshold read from a datasource.
+func floatQuantity(value float64) resource.Quantity {
+	return resource.MustParse(strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// newClusterConfig returns the cluster settings of a rule read from a datasource.
+func newClusterConfig(config *sentinelClusterConfig) *sentinelv1alpha1.FlowClusterConfig {
+	if config == nil {
+		return nil
+	}
+	out := &sentinelv1alpha1.FlowClusterConfig{
+		ThresholdType: enumOf(clusterThresholdTypes, config.ThresholdType, sentinelv1alpha1.ClusterThresholdAvgLocal),
+	}
+	if config.FlowID > 0 {
+		flowID := config.FlowID
+		out.FlowID = &flowID
+	}
+	if !config.FallbackToLocalWhenFail {
+		fallback := false
+		out.FallbackToLocalWhenFail = &fallback
+	}
+	return out
+}
+
+func parseFlowRules(content []byte, target sentinelv1alpha1.RuleTarget) ([]sentinelv1alpha1.Rule, error) {
+	var items []sentinelFlowRule
+	if err := json.Unmarshal(content, &items); err != nil {
+		return nil, err
+	}
+	rules := make([]sentinelv1alpha1.Rule, 0, len(items))
+	for _, item := range items {
+		item := item
+		spec := sentinelv1alpha1.FlowRuleSpec{
+			RuleTarget:      target,
+			Resource:        item.Resource,
+			LimitApp:        item.LimitApp,
+			Grade:           enumOf(flowGrades, item.Grade, sentinelv1alpha1.FlowGradeQPS),
+			Count:           floatQuantity(item.Count),
+			Strategy:        enumOf(flowStrategies, item.Strategy, sentinelv1alpha1.FlowStrategyDirect),
+			ControlBehavior: enumOf(flowControlBehaviors, item.ControlBehavior, sentinelv1alpha1.FlowControlBehaviorDefault),
+			ClusterMode:     item.ClusterMode,
+		}
+		if spec.Strategy != sentinelv1alpha1.FlowStrategyDirect {
+			spec.RefResource = item.RefResource
+		}
+		if spec.Grade == sentinelv1alpha1.FlowGradeThread {
+			spec.ControlBehavior = sentinelv1alpha1.FlowControlBehaviorDefault
+		}
+		switch spec.ControlBehavior {
+		case sentinelv1alpha1.FlowControlBehaviorWarmUp, sentinelv1alpha1.FlowControlBehaviorWarmUpRateLimiter:
+			if item.WarmUpPeriodSec > 0 {
+				spec.WarmUpPeriodSec = &item.WarmUpPeriodSec
+			}
+		}
+		switch spec.ControlBehavior {
+		case sentinelv1alpha1.FlowControlBehaviorRateLimiter, sentinelv1alpha1.FlowControlBehaviorWarmUpRateLimiter:
+			spec.MaxQueueingTimeMs = &item.MaxQueueingTimeMs
+		}
+		if item.ClusterMode {
+			spec.ClusterConfig = newClusterConfig(item.ClusterConfig)
+		}
+		rules = append(rules, &sentinelv1alpha1.FlowRule{Spec: spec})
+	}
+	return rules, nil
+}
+
+func parseDegradeRules(content []byte, target sentinelv1alpha1.RuleTarget) ([]sentinelv1alpha1.Rule, error) {
+	var items []sentinelDegradeRule
+	if err := json.Unmarshal(content, &items); err != nil {
+		return nil, err
+	}
+	rules := make([]sentinelv1alpha1.Rule, 0, len(items))
+	for _, item := range items {
+		item := item
+		spec := sentinelv1alpha1.DegradeRuleSpec{
+			RuleTarget: target,
+			Resource:   item.Resource,
+			Grade:      enumOf(degradeGrades, item.Grade, sentinelv1alpha1.DegradeGradeSlowRequestRatio),
+			Count:      floatQuantity(item.Count),
+			TimeWindow: item.TimeWindow,
+		}
+		if spec.Grade == sentinelv1alpha1.DegradeGradeSlowRequestRatio && item.SlowRatioThreshold > 0 {
+			threshold := floatQuantity(item.SlowRatioThreshold)
+			spec.SlowRatioThreshold = &threshold
+		}
+		if item.MinRequestAmount > 0 {
+			spec.MinRequestAmount = &item.MinRequestAmount
+		}
+		if item.StatIntervalMs > 0 {
+			spec.StatIntervalMs = &item.StatIntervalMs
+		}
+		rules = append(rules, &sentinelv1alpha1.DegradeRule{Spec: spec})
+	}
+	return rules, nil
+}
+
+func parseSystemRules(content []byte, target sentinelv1alpha1.RuleTarget) ([]sentinelv1alpha1.Rule, error) {
+	var items []sentinelSystemRule
+	if err := json.Unmarshal(content, &items); err != nil {
+		return nil, err
+	}
+	rules := make([]sentinelv1alpha1.Rule, 0, len(items))
+	for _, item := range items {
+		item := item
+		spec := sentinelv1alpha1.SystemRuleSpec{RuleTarget: target}
+		if item.HighestSystemLoad >= 0 {
+			load := floatQuantity(item.HighestSystemLoad)
+			spec.HighestSystemLoad = &load
+		}
+		if item.HighestCPUUsage >= 0 {
+			usage := floatQuantity(item.HighestCPUUsage)
+			spec.HighestCPUUsage = &usage
+		}
+		if item.QPS >= 0 {
+			qps := floatQuantity(item.QPS)
+			spec.QPS = &qps
+		}
+		if item.AvgRT >= 0 {
+			spec.AvgRT = &item.AvgRT
+		}
+		if item.MaxThread >= 0 {
+			spec.MaxThread = &item.MaxThread
+		}
+		rules = append(rules, &sentinelv1alpha1.SystemRule{Spec: spec})
+	}
+	return rules, nil
+}
+
+func parseAuthorityRules(content []byte, target sentinelv1alpha1.RuleTarget) ([]sentinelv1alpha1.Rule, error) {
+	var items []sentinelAuthorityRule
+	if err := json.Unmarshal(content, &items); err != nil {
+		return nil, err
+	}
+	rules := make([]sentinelv1alpha1.Rule, 0, len(items))
+	for _, item := range items {
+		spec := sentinelv1alpha1.AuthorityRuleSpec{
+			RuleTarget: target,
+			Resource:   item.Resource,
+			Strategy:   enumOf(authorityStrategies, item.Strategy, sentinelv1alpha1.AuthorityStrategyWhite),
+		}
+		for _, app := range strings.Split(item.LimitApp, ",") {
+			if app = strings.TrimSpace(app); app != "" {
+				spec.LimitApps = append(spec.LimitApps, app)
+			}
+		}
+		rules = append(rules, &sentinelv1alpha1.AuthorityRule{Spec: spec})
+	}
+	return rules, nil
+}
+
+func parseParamFlowRules(content []byte, target sentinelv1alpha1.RuleTarget) ([]sentinelv1alpha1.Rule, error) {
+	var items []sentinelParamFlowRule
+	if err := json.Unmarshal(content, &items); err != nil {
+		return nil, err
+	}
+	rules := make([]sentinelv1alpha1.Rule, 0, len(items))
+	for _, item := range items {
+		item := item
+		spec := sentinelv1alpha1.ParamFlowRuleSpec{
+			RuleTarget:      target,
+			Resource:        item.Resource,
+			ParamIdx:        item.ParamIdx,
+			Grade:           enumOf(flowGrades, item.Grade, sentinelv1alpha1.FlowGradeQPS),
+			Count:           floatQuantity(item.Count),
+			ControlBehavior: sentinelv1alpha1.FlowControlBehaviorDefault,
+			BurstCount:      item.BurstCount,
+			DurationInSec:   item.DurationInSec,
+			ClusterMode:     item.ClusterMode,
+		}
+		if spec.Grade == sentinelv1alpha1.FlowGradeQPS &&
+			item.ControlBehavior == flowControlBehaviors[sentinelv1alpha1.FlowControlBehaviorRateLimiter] {
+			spec.ControlBehavior = sentinelv1alpha1.FlowControlBehaviorRateLimiter
+			spec.MaxQueueingTimeMs = &item.MaxQueueingTimeMs
+		}
+		for _, paramItem := range item.ParamFlowItemList {
+			spec.ParamFlowItems = append(spec.ParamFlowItems, sentinelv1alpha1.ParamFlowItem{
+				Object:    paramItem.Object,
+				ClassType: paramItem.ClassType,
+				Count:     paramItem.Count,
+			})
+		}
+		if item.ClusterMode {
+			spec.ClusterConfig = newClusterConfig(item.ClusterConfig)
+		}
+		rules = append(rules, &sentinelv1alpha1.ParamFlowRule{Spec: spec})
+	}
+	return rules, nil
+}
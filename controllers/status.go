@@ -2,10 +2,17 @@ package controllers
 
 import (
 	"context"
+	"net"
+	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
@@ -46,7 +53,7 @@ func (r *DashboardReconciler) UpdateCondition(ctx context.Context, instance *sen
 	cond := sentinelv1alpha1.DashboardCondition{
 		Type:               string(conditionType),
 		Status:             status,
-		ObservedGeneration: 0,
+		ObservedGeneration: instance.Generation,
 		LastTransitionTime: now,
 		Reason:             reason,
 		Message:            message,
@@ -56,6 +63,19 @@ func (r *DashboardReconciler) UpdateCondition(ctx context.Context, instance *sen
 	return nil
 }
 
+// RemoveCondition removes the condition of conditionType from the status.
+func (r *DashboardReconciler) RemoveCondition(ctx context.Context, instance *sentinelv1alpha1.Dashboard,
+	conditionType sentinelv1alpha1.DashboardConditionType) {
+
+	conditions := instance.Status.Conditions[:0]
+	for _, cond := range instance.Status.Conditions {
+		if cond.Type != string(conditionType) {
+			conditions = append(conditions, cond)
+		}
+	}
+	instance.Status.Conditions = conditions
+}
+
 func (r *DashboardReconciler) GetCondition(ctx context.Context, instance *sentinelv1alpha1.Dashboard,
 	conditionType sentinelv1alpha1.DashboardConditionType) sentinelv1alpha1.DashboardCondition {
 
@@ -85,3 +105,167 @@ func (r *DashboardReconciler) UpdateStatus(ctx context.Context, instance *sentin
 	}
 	return nil
 }
+
+// UpdateObservedStatus copies the observed state of the owned resources into
+// the status of the dashboard.
+func (r *DashboardReconciler) UpdateObservedStatus(ctx context.Context, instance *sentinelv1alpha1.Dashboard) error {
+	key := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+	instance.Status.ObservedGeneration = instance.Generation
+
+	workload, err := r.getWorkloadState(ctx, instance)
+	if err != nil {
+		return errors.Wrap(err, "failed getting workload")
+	}
+	instance.Status.Replicas = workload.replicas
+	instance.Status.Selector = metav1.FormatLabelSelector(&metav1.LabelSelector{MatchLabels: dashboardPodLabels(instance)})
+	instance.Status.ReadyReplicas = workload.readyReplicas
+	instance.Status.AvailableReplicas = workload.availableReplicas
+	instance.Status.UpdatedReplicas = workload.updatedReplicas
+
+	var pods corev1.PodList
+	if err := r.APIReader.List(ctx, &pods, client.InNamespace(instance.Namespace), client.MatchingLabels(dashboardPodLabels(instance))); err != nil {
+		return errors.Wrap(err, "failed listing pods")
+	}
+	newest := newestReadyPod(instance, pods.Items)
+	if newest != nil {
+		for _, status := range newest.Status.ContainerStatuses {
+			if status.Name == instance.Name {
+				instance.Status.ImageDigest = imageDigest(status.ImageID)
+			}
+		}
+	}
+
+	var injected corev1.PodList
+	if err := r.List(ctx, &injected, client.MatchingFields{injectedDashboardIndex: key.String()}); err != nil {
+		return errors.Wrap(err, "failed listing injected pods")
+	}
+	instance.Status.InjectedPods = countInjectedPods(injected.Items)
+
+	var svc corev1.Service
+	if err := r.Get(ctx, key, &svc); client.IgnoreNotFound(err) != nil {
+		return errors.Wrap(err, "failed getting service")
+	}
+	instance.Status.URL = serviceURL(&svc, newest)
+
+	if err := r.UpdatePodSecurityStatus(ctx, instance); err != nil {
+		return errors.Wrap(err, "failed updating pod security status")
+	}
+
+	if err := r.UpdateRouteStatus(ctx, instance); err != nil {
+		return errors.Wrap(err, "failed updating route status")
+	}
+
+	if err := r.UpdateStorageStatus(ctx, instance); err != nil {
+		return errors.Wrap(err, "failed updating storage status")
+	}
+
+	if instance.Spec.Ingress != nil {
+		var ing networkingv1.Ingress
+		if err := r.Get(ctx, key, &ing); client.IgnoreNotFound(err) != nil {
+			return errors.Wrap(err, "failed getting ingress")
+		}
+		if url := ingressURL(&ing); url != "" {
+			instance.Status.URL = url
+		}
+	}
+
+	return nil
+}
+
+// newestReadyPod returns the most recently created pod whose dashboard container is ready.
+func newestReadyPod(instance *sentinelv1alpha1.Dashboard, pods []corev1.Pod) *corev1.Pod {
+	var newest *corev1.Pod
+	for i := range pods {
+		pod := &pods[i]
+		if !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name == instance.Name && status.Ready &&
+				(newest == nil || newest.CreationTimestamp.Before(&pod.CreationTimestamp)) {
+				newest = pod
+			}
+		}
+	}
+	return newest
+}
+
+// imageDigest extracts the digest from a container image id such as
+// docker-pullable://sentinel-group/sentinel-dashboard@sha256:1234.
+func imageDigest(imageID string) string {
+	if i := strings.LastIndex(imageID, "@"); i >= 0 {
+		return imageID[i+1:]
+	}
+	return strings.TrimPrefix(imageID, "docker://")
+}
+
+// serviceURL returns the URL the dashboard is reachable at through svc. Node
+// ports are reported on the node of pod, since any node would do.
+func serviceURL(svc *corev1.Service, pod *corev1.Pod) string {
+	if svc.CreationTimestamp.IsZero() || len(svc.Spec.Ports) == 0 {
+		return ""
+	}
+	port := svc.Spec.Ports[0]
+
+	switch svc.Spec.Type {
+	case corev1.ServiceTypeLoadBalancer:
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			host := ingress.IP
+			if ingress.Hostname != "" {
+				host = ingress.Hostname
+			}
+			if host != "" {
+				return "http://" + net.JoinHostPort(host, strconv.Itoa(int(port.Port)))
+			}
+		}
+		fallthrough
+	case corev1.ServiceTypeNodePort:
+		if pod != nil && pod.Status.HostIP != "" && port.NodePort != 0 {
+			return "http://" + net.JoinHostPort(pod.Status.HostIP, strconv.Itoa(int(port.NodePort)))
+		}
+	}
+	return "http://" + net.JoinHostPort(svc.Name+"."+svc.Namespace+".svc", strconv.Itoa(int(port.Port)))
+}
+
+// ingressURL returns the URL the dashboard is reachable at through ing: the
+// first host and path of its rules, or the address assigned by the ingress
+// controller when the rule matches every host.
+func ingressURL(ing *networkingv1.Ingress) string {
+	if ing.CreationTimestamp.IsZero() || len(ing.Spec.Rules) == 0 {
+		return ""
+	}
+	rule := ing.Spec.Rules[0]
+
+	host := rule.Host
+	if host == "" {
+		for _, ingress := range ing.Status.LoadBalancer.Ingress {
+			host = ingress.IP
+			if ingress.Hostname != "" {
+				host = ingress.Hostname
+			}
+			if host != "" {
+				break
+			}
+		}
+	}
+	if host == "" {
+		return ""
+	}
+
+	scheme := "http"
+	for _, tls := range ing.Spec.TLS {
+		if len(tls.Hosts) == 0 && rule.Host == "" {
+			scheme = "https"
+		}
+		for _, tlsHost := range tls.Hosts {
+			if tlsHost == rule.Host {
+				scheme = "https"
+			}
+		}
+	}
+	path := "/"
+	if rule.HTTP != nil && len(rule.HTTP.Paths) > 0 {
+		path = rule.HTTP.Paths[0].Path
+	}
+	return scheme + "://" + host + path
+}
@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+)
+
+func TestImageDigest(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(imageDigest("docker-pullable://sentinel-group/sentinel-dashboard@sha256:1234")).To(Equal("sha256:1234"))
+	g.Expect(imageDigest("docker://sha256:5678")).To(Equal("sha256:5678"))
+	g.Expect(imageDigest("sha256:5678")).To(Equal("sha256:5678"))
+}
+
+func TestNewestReadyPod(t *testing.T) {
+	g := NewWithT(t)
+	instance := &sentinelv1alpha1.Dashboard{ObjectMeta: metav1.ObjectMeta{Name: "sentinel-dashboard"}}
+	now := time.Now()
+	pod := func(name string, age time.Duration, ready bool) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(now.Add(-age))},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "sidecar", Ready: true},
+				{Name: instance.Name, Ready: ready},
+			}},
+		}
+	}
+	deleting := pod("deleting", 0, true)
+	deletionTimestamp := metav1.NewTime(now)
+	deleting.DeletionTimestamp = &deletionTimestamp
+
+	g.Expect(newestReadyPod(instance, nil)).To(BeNil())
+	g.Expect(newestReadyPod(instance, []corev1.Pod{pod("starting", 0, false)})).To(BeNil())
+
+	newest := newestReadyPod(instance, []corev1.Pod{
+		pod("old", time.Hour, true),
+		deleting,
+		pod("new", time.Minute, true),
+		pod("starting", 0, false),
+	})
+	g.Expect(newest).NotTo(BeNil())
+	g.Expect(newest.Name).To(Equal("new"))
+}
+
+func TestServiceURL(t *testing.T) {
+	service := func(serviceType corev1.ServiceType, nodePort int32, ingress ...corev1.LoadBalancerIngress) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "sentinel-dashboard", Namespace: "sentinel-group", CreationTimestamp: metav1.Now()},
+			Spec: corev1.ServiceSpec{
+				Type:  serviceType,
+				Ports: []corev1.ServicePort{{Port: 8080, NodePort: nodePort}},
+			},
+			Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: ingress}},
+		}
+	}
+	pod := &corev1.Pod{Status: corev1.PodStatus{HostIP: "10.0.0.1"}}
+	tests := []struct {
+		name string
+		svc  *corev1.Service
+		pod  *corev1.Pod
+		want string
+	}{
+		{name: "not created", svc: &corev1.Service{}, want: ""},
+		{name: "cluster ip", svc: service(corev1.ServiceTypeClusterIP, 0), pod: pod,
+			want: "http://sentinel-dashboard.sentinel-group.svc:8080"},
+		{name: "node port", svc: service(corev1.ServiceTypeNodePort, 30080), pod: pod, want: "http://10.0.0.1:30080"},
+		{name: "node port without pod", svc: service(corev1.ServiceTypeNodePort, 30080),
+			want: "http://sentinel-dashboard.sentinel-group.svc:8080"},
+		{name: "load balancer hostname", svc: service(corev1.ServiceTypeLoadBalancer, 30080,
+			corev1.LoadBalancerIngress{IP: "1.2.3.4", Hostname: "lb.example.com"}), pod: pod, want: "http://lb.example.com:8080"},
+		{name: "pending load balancer", svc: service(corev1.ServiceTypeLoadBalancer, 30080), pod: pod,
+			want: "http://10.0.0.1:30080"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			NewWithT(t).Expect(serviceURL(tt.svc, tt.pod)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestDashboardPodLabels(t *testing.T) {
+	g := NewWithT(t)
+	instance := &sentinelv1alpha1.Dashboard{ObjectMeta: metav1.ObjectMeta{Name: "sentinel-dashboard"}}
+	template := newPodTemplate(instance)
+	selector := labels.SelectorFromSet(dashboardPodLabels(instance))
+	g.Expect(selector.Matches(labels.Set(template.Labels))).To(BeTrue())
+	g.Expect(selector.Matches(labels.Set{"app": instance.Name})).To(BeFalse(),
+		"pods of other workloads using the same app label are not selected")
+}
+
+func TestIngressURL(t *testing.T) {
+	created := metav1.NewTime(time.Now())
+	rule := func(host string, paths ...string) networkingv1.IngressRule {
+		var httpPaths []networkingv1.HTTPIngressPath
+		for _, path := range paths {
+			httpPaths = append(httpPaths, networkingv1.HTTPIngressPath{Path: path})
+		}
+		return networkingv1.IngressRule{
+			Host:             host,
+			IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{Paths: httpPaths}},
+		}
+	}
+	loadBalancer := func(ingresses ...corev1.LoadBalancerIngress) networkingv1.IngressStatus {
+		return networkingv1.IngressStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: ingresses}}
+	}
+
+	tests := []struct {
+		name string
+		ing  networkingv1.Ingress
+		want string
+	}{
+		{
+			name: "not created",
+			ing:  networkingv1.Ingress{Spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{rule("sentinel.example.com", "/")}}},
+		},
+		{
+			name: "no rules",
+			ing:  networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created}},
+		},
+		{
+			name: "first host and path",
+			ing: networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+				Spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{
+					rule("sentinel.example.com", "/sentinel", "/"),
+					rule("other.example.com", "/"),
+				}},
+			},
+			want: "http://sentinel.example.com/sentinel",
+		},
+		{
+			name: "tls of the host",
+			ing: networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{rule("sentinel.example.com")},
+					TLS:   []networkingv1.IngressTLS{{Hosts: []string{"sentinel.example.com"}, SecretName: "tls"}},
+				},
+			},
+			want: "https://sentinel.example.com/",
+		},
+		{
+			name: "tls of another host",
+			ing: networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{rule("sentinel.example.com", "/")},
+					TLS:   []networkingv1.IngressTLS{{Hosts: []string{"other.example.com"}, SecretName: "tls"}},
+				},
+			},
+			want: "http://sentinel.example.com/",
+		},
+		{
+			name: "any host without an address",
+			ing: networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+				Spec:       networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{rule("", "/")}},
+			},
+		},
+		{
+			name: "any host at the load balancer hostname",
+			ing: networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{rule("", "/")},
+					TLS:   []networkingv1.IngressTLS{{SecretName: "tls"}},
+				},
+				Status: loadBalancer(
+					corev1.LoadBalancerIngress{},
+					corev1.LoadBalancerIngress{IP: "203.0.113.1", Hostname: "lb.example.com"},
+				),
+			},
+			want: "https://lb.example.com/",
+		},
+		{
+			name: "any host at the load balancer IP",
+			ing: networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+				Spec:       networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{rule("", "/")}},
+				Status:     loadBalancer(corev1.LoadBalancerIngress{IP: "203.0.113.1"}),
+			},
+			want: "http://203.0.113.1/",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(ingressURL(&tt.ing)).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/nacos"
+)
+
+const (
+	// clusterMapDataIDSuffix is appended to the app name to name the
+	// assignment of its clients to token servers in the datasource.
+	clusterMapDataIDSuffix = "-cluster-map"
+
+	// tokenServerSuffix is appended to the name of the TokenServer to name
+	// its Deployment and Service.
+	tokenServerSuffix = "-token-server"
+
+	// tokenServerNamespacesEnv lists the apps the token server checks the
+	// cluster rules of, which Sentinel calls the namespace set of the server.
+	tokenServerNamespacesEnv = "SENTINEL_TOKEN_SERVER_NAMESPACES"
+
+	// tokenServerPortEnv is the port the token server listens on.
+	tokenServerPortEnv = "SENTINEL_TOKEN_SERVER_PORT"
+)
+
+// sentinelClusterGroup is the JSON form of the assignment of clients to a
+// token server read by the Sentinel clients from the cluster map of an app.
+type sentinelClusterGroup struct {
+	MachineID   string   `json:"machineId"`
+	IP          string   `json:"ip"`
+	Port        int32    `json:"port"`
+	ClientSet   []string `json:"clientSet"`
+	BelongToApp bool     `json:"belongToApp"`
+}
+
+func tokenServerName(server *sentinelv1alpha1.TokenServer) string {
+	return server.Name + tokenServerSuffix
+}
+
+func tokenServerLabels(server *sentinelv1alpha1.TokenServer) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":     "sentinel-token-server",
+		"app.kubernetes.io/instance": server.Name,
+	}
+}
+
+func tokenServerPort(server *sentinelv1alpha1.TokenServer) int32 {
+	if server.Spec.Port == 0 {
+		return sentinelv1alpha1.DefaultTokenServerPort
+	}
+	return server.Spec.Port
+}
+
+func tokenServerClientPort(server *sentinelv1alpha1.TokenServer) int32 {
+	if server.Spec.ClientPort == 0 {
+		return sentinelv1alpha1.DefaultTransportPort
+	}
+	return server.Spec.ClientPort
+}
+
+// tokenServerHost returns the host of the Service of the token server.
+func tokenServerHost(server *sentinelv1alpha1.TokenServer) string {
+	return fmt.Sprintf("%s.%s.svc", tokenServerName(server), server.Namespace)
+}
+
+// tokenServerAddress returns the host:port the clients connect to.
+func tokenServerAddress(server *sentinelv1alpha1.TokenServer) string {
+	return net.JoinHostPort(tokenServerHost(server), strconv.Itoa(int(tokenServerPort(server))))
+}
+
+func MutateTokenServerService(server *sentinelv1alpha1.TokenServer, svc *corev1.Service) {
+	svc.Labels = tokenServerLabels(server)
+	svc.Spec.Type = corev1.ServiceTypeClusterIP
+	svc.Spec.Selector = tokenServerLabels(server)
+	svc.Spec.Ports = []corev1.ServicePort{
+		{
+			Name:       "token",
+			Port:       tokenServerPort(server),
+			Protocol:   corev1.ProtocolTCP,
+			TargetPort: intstr.FromString("token"),
+		},
+	}
+}
+
+// MutateTokenServerDeployment renders the Deployment of the token server.
+// The token server keeps the cluster state in memory, so it runs a single
+// replica, and the old pod is stopped before the new one starts.
+func MutateTokenServerDeployment(server *sentinelv1alpha1.TokenServer, dashboard *sentinelv1alpha1.Dashboard,
+	deploy *appsv1.Deployment) {
+	labels := tokenServerLabels(server)
+	replicas := int32(1)
+	automountServiceAccountToken := false
+	deploy.Labels = labels
+	deploy.Spec = appsv1.DeploymentSpec{
+		Replicas: &replicas,
+		Selector: &metav1.LabelSelector{MatchLabels: labels},
+		Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+		Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:            "token-server",
+						Image:           server.Spec.Image,
+						ImagePullPolicy: corev1.PullIfNotPresent,
+						Ports: []corev1.ContainerPort{
+							{Name: "token", ContainerPort: tokenServerPort(server), Protocol: corev1.ProtocolTCP},
+							{Name: "api", ContainerPort: sentinelv1alpha1.DefaultTransportPort, Protocol: corev1.ProtocolTCP},
+						},
+						Env:       newTokenServerEnv(server, dashboard),
+						Resources: server.Spec.Resources,
+						ReadinessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{
+								TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromString("token")},
+							},
+							PeriodSeconds:    5,
+							FailureThreshold: 3,
+						},
+						VolumeMounts:    []corev1.VolumeMount{{Name: tmpVolumeName, MountPath: "/tmp"}},
+						SecurityContext: restrictedSecurityContext(),
+					},
+				},
+				Volumes: []corev1.Volume{
+					{
+						Name:         tmpVolumeName,
+						VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+					},
+				},
+				SecurityContext:              restrictedPodSecurityContext(),
+				ImagePullSecrets:             server.Spec.ImagePullSecrets,
+				AutomountServiceAccountToken: &automountServiceAccountToken,
+			},
+		},
+	}
+}
+
+// newTokenServerEnv renders the env the image of the token server is
+// configured by: the datasource of the dashboard, which the cluster rules are
+// read from, the apps it serves, its port and the Sentinel client options
+// reporting it to the dashboard, merged with spec.env. The dashboard is nil
+// when it does not exist.
+func newTokenServerEnv(server *sentinelv1alpha1.TokenServer, dashboard *sentinelv1alpha1.Dashboard) []corev1.EnvVar {
+	var env []corev1.EnvVar
+	options := []string{systemProperty("csp.sentinel.log.dir", "/tmp/logs/csp")}
+	if dashboard != nil {
+		env = append(env, newDatasourceEnv(dashboard)...)
+		options = append(options, newSentinelClientProperties(dashboard, server.Name, sentinelv1alpha1.DefaultTransportPort)...)
+	}
+	env = append(env,
+		corev1.EnvVar{Name: tokenServerNamespacesEnv, Value: strings.Join(server.Spec.Apps, ",")},
+		corev1.EnvVar{Name: tokenServerPortEnv, Value: strconv.Itoa(int(tokenServerPort(server)))},
+		corev1.EnvVar{Name: javaToolOptionsEnv, Value: strings.Join(options, " ")},
+	)
+	return mergeEnv(env, server.Spec.Env)
+}
+
+// newClusterMap renders the cluster map of an app assigning the client pods
+// to the token server.
+func newClusterMap(server *sentinelv1alpha1.TokenServer, pods []corev1.Pod) []sentinelClusterGroup {
+	clients := make([]string, 0, len(pods))
+	for j := range pods {
+		// Sentinel identifies the machines by ip@port
+		port := clientTransportPort(&pods[j], tokenServerClientPort(server))
+		clients = append(clients, pods[j].Status.PodIP+"@"+strconv.Itoa(int(port)))
+	}
+	sort.Strings(clients)
+
+	return []sentinelClusterGroup{
+		{
+			MachineID: tokenServerHost(server) + "@" + strconv.Itoa(sentinelv1alpha1.DefaultTransportPort),
+			IP:        tokenServerHost(server),
+			Port:      tokenServerPort(server),
+			ClientSet: clients,
+		},
+	}
+}
+
+// clusterMapScope identifies the Nacos config namespace the cluster maps of
+// dashboard are published to. The token servers of dashboards with the same
+// scope, in any namespace, publish to the same cluster map of an app. It is
+// empty without a Nacos datasource.
+func clusterMapScope(dashboard *sentinelv1alpha1.Dashboard) string {
+	if dashboard.Spec.Datasource == nil || dashboard.Spec.Datasource.Nacos == nil {
+		return ""
+	}
+	spec := dashboard.Spec.Datasource.Nacos
+	addrs := append([]string(nil), spec.ServerAddr...)
+	sort.Strings(addrs)
+	group := spec.Group
+	if group == "" {
+		group = nacos.DefaultGroup
+	}
+	return strings.Join([]string{strings.Join(addrs, ","), spec.Namespace, group}, "/")
+}
+
+// isClientPod returns whether pod can be assigned to a token server.
+// clientTransportPort returns the sentinel-api container port of the client
+// pod, which the injected pods declare, or defaultPort.
+func clientTransportPort(pod *corev1.Pod, defaultPort int32) int32 {
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.Name == transportPortName {
+				return port.ContainerPort
+			}
+		}
+	}
+	return defaultPort
+}
+
+func isClientPod(pod *corev1.Pod) bool {
+	return pod.DeletionTimestamp.IsZero() && pod.Status.PodIP != "" &&
+		pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed
+}
+
+// clientPodChanged filters the pod events down to those that may change the
+// cluster maps: the pods being created or deleted, or changing IP, phase,
+// deletion or labels, which select the apps and the clients.
+var clientPodChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldPod, ok := e.ObjectOld.(*corev1.Pod)
+		if !ok {
+			return false
+		}
+		newPod, ok := e.ObjectNew.(*corev1.Pod)
+		if !ok {
+			return false
+		}
+		return oldPod.Status.PodIP != newPod.Status.PodIP ||
+			oldPod.Status.Phase != newPod.Status.Phase ||
+			oldPod.DeletionTimestamp.IsZero() != newPod.DeletionTimestamp.IsZero() ||
+			!equality.Semantic.DeepEqual(oldPod.Labels, newPod.Labels)
+	},
+	GenericFunc: func(event.GenericEvent) bool { return false },
+}
@@ -0,0 +1,517 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/event"
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/nacos"
+)
+
+const (
+	// tokenServerFinalizer blocks the deletion of a TokenServer until its
+	// clients are unassigned from it.
+	tokenServerFinalizer = "sentinel.sentinelguard.io/token-server"
+
+	// tokenServerAppIndex indexes token servers by the apps they serve.
+	tokenServerAppIndex = ".spec.apps"
+
+	// tokenServerDashboardIndex indexes token servers by their dashboard.
+	tokenServerDashboardIndex = ".spec.dashboardRef.name"
+)
+
+// TokenServerReconciler reconciles a TokenServer object
+type TokenServerReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// HTTPClient publishes the cluster maps to the datasources.
+	// SetupWithManager sets a default client when it is nil.
+	HTTPClient *http.Client
+
+	// SecretReader reads the Secrets of the datasources from the API server,
+	// so that the manager does not cache the data of every Secret.
+	// SetupWithManager sets the API reader of the manager when it is nil.
+	SecretReader client.Reader
+}
+
+//+kubebuilder:rbac:groups=sentinel.sentinelguard.io,resources=tokenservers,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=sentinel.sentinelguard.io,resources=tokenservers/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=sentinel.sentinelguard.io,resources=tokenservers/finalizers,verbs=update
+//+kubebuilder:rbac:groups=sentinel.sentinelguard.io,resources=dashboards,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile deploys the token server, and publishes the cluster map of each
+// of its apps to the datasource of its dashboard, assigning the pods of the
+// app to the token server. When several token servers publish to the same
+// cluster map, from the same dashboard or from dashboards sharing its
+// datasource, the oldest one is assigned the pods.
+func (r *TokenServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var server sentinelv1alpha1.TokenServer
+	if err := r.Get(ctx, req.NamespacedName, &server); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "failed to get token server")
+		return ctrl.Result{}, err
+	}
+
+	dashboard, err := r.getDashboard(ctx, &server)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !server.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(&server, tokenServerFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		if err := r.unassign(ctx, &server, dashboard); err != nil {
+			return ctrl.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(&server, tokenServerFinalizer)
+		return ctrl.Result{}, errors.Wrap(client.IgnoreNotFound(r.Update(ctx, &server)), "failed removing finalizer")
+	}
+	if !controllerutil.ContainsFinalizer(&server, tokenServerFinalizer) {
+		controllerutil.AddFinalizer(&server, tokenServerFinalizer)
+		if err := r.Update(ctx, &server); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed adding finalizer")
+		}
+	}
+
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: tokenServerName(&server), Namespace: server.Namespace}}
+	if err := r.applyOwned(ctx, &server, svc, func() { MutateTokenServerService(&server, svc) }); err != nil {
+		return ctrl.Result{}, err
+	}
+	deploy := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: tokenServerName(&server), Namespace: server.Namespace}}
+	if err := r.applyOwned(ctx, &server, deploy, func() { MutateTokenServerDeployment(&server, dashboard, deploy) }); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	status := server.Status.DeepCopy()
+	status.ObservedGeneration = server.Generation
+	status.Address = tokenServerAddress(&server)
+	status.ReadyReplicas = deploy.Status.ReadyReplicas
+	ready := metav1.Condition{
+		Type:    sentinelv1alpha1.TokenServerReadyConditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Ready",
+		Message: fmt.Sprintf("token server is listening on %s", status.Address),
+	}
+	if deploy.Status.ReadyReplicas == 0 {
+		ready.Status, ready.Reason, ready.Message = metav1.ConditionFalse, "NotReady", "token server has no ready pod"
+	}
+	ready.ObservedGeneration = server.Generation
+	meta.SetStatusCondition(&status.Conditions, ready)
+
+	publishErr := r.assign(ctx, &server, dashboard, status)
+	if err := r.updateStatus(ctx, &server, status); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, publishErr
+}
+
+// getDashboard returns the dashboard of server, or nil when it does not exist.
+func (r *TokenServerReconciler) getDashboard(ctx context.Context,
+	server *sentinelv1alpha1.TokenServer) (*sentinelv1alpha1.Dashboard, error) {
+	var dashboard sentinelv1alpha1.Dashboard
+	key := types.NamespacedName{Namespace: server.Namespace, Name: server.Spec.DashboardRef.Name}
+	if err := r.Get(ctx, key, &dashboard); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed getting dashboard %s", key)
+	}
+	return &dashboard, nil
+}
+
+// applyOwned creates or updates obj as controlled by server.
+func (r *TokenServerReconciler) applyOwned(ctx context.Context, server *sentinelv1alpha1.TokenServer,
+	obj client.Object, mutate func()) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		result, err := controllerutil.CreateOrUpdate(ctx, r.Client, obj, func() error {
+			mutate()
+			return controllerutil.SetControllerReference(server, obj, r.Scheme)
+		})
+		if err == nil && result != controllerutil.OperationResultNone {
+			log.FromContext(ctx).Info("succeed updated token server resource", "result", result, "name", obj.GetName())
+		}
+		return err
+	})
+}
+
+// assign publishes the cluster maps of the apps of server, and unassigns the
+// clients of the apps it no longer serves. It records the result in status.
+// A missing dashboard or a dashboard without a Nacos datasource is only
+// reported, as retrying would not help.
+func (r *TokenServerReconciler) assign(ctx context.Context, server *sentinelv1alpha1.TokenServer,
+	dashboard *sentinelv1alpha1.Dashboard, status *sentinelv1alpha1.TokenServerStatus) error {
+	cond := metav1.Condition{Type: sentinelv1alpha1.PublishedConditionType, ObservedGeneration: server.Generation}
+	defer func() { meta.SetStatusCondition(&status.Conditions, cond) }()
+
+	if dashboard == nil {
+		cond.Status, cond.Reason = metav1.ConditionFalse, ruleDashboardNotFoundReason
+		cond.Message = fmt.Sprintf("dashboard %s not found", server.Spec.DashboardRef.Name)
+		return nil
+	}
+	nacosClient, err := newNacosClient(ctx, r.SecretReader, r.HTTPClient, dashboard)
+	if errors.Is(err, errUnsupportedDatasource) {
+		cond.Status, cond.Reason = metav1.ConditionFalse, ruleUnsupportedDatasourceReason
+		cond.Message = fmt.Sprintf("dashboard %s has no nacos datasource", dashboard.Name)
+		return nil
+	}
+	if err != nil {
+		cond.Status, cond.Reason, cond.Message = metav1.ConditionFalse, rulePublishFailedReason, err.Error()
+		return err
+	}
+
+	owners, err := r.appOwners(ctx, dashboard)
+	if err != nil {
+		return err
+	}
+	key := client.ObjectKeyFromObject(server).String()
+	var published, conflicts []string
+	var clients int
+	var errs []error
+	for _, app := range server.Spec.Apps {
+		if owner := owners[app]; owner != key {
+			conflicts = append(conflicts, fmt.Sprintf("app %s is already served by token server %s", app, owner))
+			continue
+		}
+		pods, err := r.clientPods(ctx, server, app)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := publishClusterMap(ctx, nacosClient, app, newClusterMap(server, pods)); err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed publishing cluster map of app %s", app))
+			continue
+		}
+		published = append(published, app)
+		clients += len(pods)
+	}
+	for _, app := range server.Status.PublishedApps {
+		if containsString(published, app) || owners[app] != "" {
+			continue
+		}
+		if err := publishClusterMap(ctx, nacosClient, app, []sentinelClusterGroup{}); err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed unassigning clients of app %s", app))
+			published = append(published, app)
+		}
+	}
+	sort.Strings(published)
+	status.PublishedApps = published
+	status.Clients = int32(clients)
+
+	switch {
+	case len(errs) > 0:
+		cond.Status, cond.Reason = metav1.ConditionFalse, rulePublishFailedReason
+		cond.Message = utilerrors.NewAggregate(errs).Error()
+	case len(conflicts) > 0:
+		cond.Status, cond.Reason, cond.Message = metav1.ConditionFalse, ruleConflictReason, strings.Join(conflicts, "; ")
+	default:
+		cond.Status, cond.Reason = metav1.ConditionTrue, rulePublishedReason
+		cond.Message = fmt.Sprintf("assigned %d clients of apps %s", clients, strings.Join(server.Spec.Apps, ", "))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// unassign clears the cluster maps published by server, unless another token
+// server serves the app.
+func (r *TokenServerReconciler) unassign(ctx context.Context, server *sentinelv1alpha1.TokenServer,
+	dashboard *sentinelv1alpha1.Dashboard) error {
+	if dashboard == nil || len(server.Status.PublishedApps) == 0 {
+		return nil
+	}
+	nacosClient, err := newNacosClient(ctx, r.SecretReader, r.HTTPClient, dashboard)
+	if errors.Is(err, errUnsupportedDatasource) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	owners, err := r.appOwners(ctx, dashboard)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, app := range server.Status.PublishedApps {
+		if owners[app] != "" {
+			continue
+		}
+		if err := publishClusterMap(ctx, nacosClient, app, []sentinelClusterGroup{}); err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed unassigning clients of app %s", app))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// appOwners maps the apps served by the token servers publishing to the
+// cluster maps of dashboard, except the ones being deleted, to the oldest
+// token server serving them, as namespace/name. The token servers of the
+// dashboards sharing its datasource, in any namespace, publish to the same
+// cluster maps.
+func (r *TokenServerReconciler) appOwners(ctx context.Context,
+	dashboard *sentinelv1alpha1.Dashboard) (map[string]string, error) {
+	var dashboards sentinelv1alpha1.DashboardList
+	if err := r.List(ctx, &dashboards); err != nil {
+		return nil, errors.Wrap(err, "failed listing dashboards")
+	}
+	scope := clusterMapScope(dashboard)
+	shared := make(map[types.NamespacedName]bool)
+	for i := range dashboards.Items {
+		if d := &dashboards.Items[i]; clusterMapScope(d) == scope {
+			shared[client.ObjectKeyFromObject(d)] = true
+		}
+	}
+
+	var list sentinelv1alpha1.TokenServerList
+	if err := r.List(ctx, &list); err != nil {
+		return nil, errors.Wrap(err, "failed listing token servers")
+	}
+	var servers []sentinelv1alpha1.TokenServer
+	for _, s := range list.Items {
+		key := types.NamespacedName{Namespace: s.Namespace, Name: s.Spec.DashboardRef.Name}
+		if shared[key] && s.DeletionTimestamp.IsZero() {
+			servers = append(servers, s)
+		}
+	}
+	sort.SliceStable(servers, func(i, j int) bool {
+		ti, tj := servers[i].CreationTimestamp, servers[j].CreationTimestamp
+		if !ti.Equal(&tj) {
+			return ti.Before(&tj)
+		}
+		if servers[i].Namespace != servers[j].Namespace {
+			return servers[i].Namespace < servers[j].Namespace
+		}
+		return servers[i].Name < servers[j].Name
+	})
+
+	owners := make(map[string]string)
+	for _, s := range servers {
+		for _, app := range s.Spec.Apps {
+			if _, ok := owners[app]; !ok {
+				owners[app] = client.ObjectKeyFromObject(&s).String()
+			}
+		}
+	}
+	return owners, nil
+}
+
+// clientPods lists the pods of app selected by server.
+func (r *TokenServerReconciler) clientPods(ctx context.Context, server *sentinelv1alpha1.TokenServer, app string) ([]corev1.Pod, error) {
+	selector := labels.Everything()
+	if server.Spec.ClientSelector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(server.Spec.ClientSelector); err != nil {
+			return nil, err
+		}
+	}
+
+	var list corev1.PodList
+	if err := r.List(ctx, &list, client.InNamespace(server.Namespace),
+		client.MatchingLabels{sentinelv1alpha1.AppLabel: app}); err != nil {
+		return nil, errors.Wrapf(err, "failed listing pods of app %s", app)
+	}
+	var pods []corev1.Pod
+	for _, pod := range list.Items {
+		if isClientPod(&pod) && selector.Matches(labels.Set(pod.Labels)) {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+// publishClusterMap writes the cluster map of app, unless the datasource
+// already holds it.
+func publishClusterMap(ctx context.Context, nacosClient *nacos.Client, app string, groups []sentinelClusterGroup) error {
+	content, err := json.Marshal(groups)
+	if err != nil {
+		return err
+	}
+	dataID := app + clusterMapDataIDSuffix
+	current, found, err := nacosClient.GetConfig(ctx, dataID)
+	if err != nil {
+		return err
+	}
+	if (found && current == string(content)) || (!found && len(groups) == 0) {
+		return nil
+	}
+	log.FromContext(ctx).Info("publishing cluster map", "dataId", dataID)
+	return nacosClient.PublishConfig(ctx, dataID, string(content))
+}
+
+// updateStatus writes status, and records an event when the Published
+// condition changes.
+func (r *TokenServerReconciler) updateStatus(ctx context.Context, server *sentinelv1alpha1.TokenServer,
+	status *sentinelv1alpha1.TokenServerStatus) error {
+	if equality.Semantic.DeepEqual(status, &server.Status) {
+		return nil
+	}
+	previous := meta.FindStatusCondition(server.Status.Conditions, sentinelv1alpha1.PublishedConditionType)
+	cond := meta.FindStatusCondition(status.Conditions, sentinelv1alpha1.PublishedConditionType)
+
+	patch := client.MergeFrom(server.DeepCopy())
+	server.Status = *status
+	if err := r.Status().Patch(ctx, server, patch); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if cond != nil && (previous == nil || previous.Status != cond.Status || previous.Reason != cond.Reason) {
+		eventType, reason := corev1.EventTypeNormal, event.TokenServerPublish
+		if cond.Status != metav1.ConditionTrue {
+			eventType = corev1.EventTypeWarning
+		}
+		if cond.Reason == ruleConflictReason {
+			reason = event.TokenServerConflict
+		}
+		r.Recorder.Event(server, eventType, string(reason), cond.Message)
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// podToTokenServers maps a pod of an app to the token servers serving the app.
+func (r *TokenServerReconciler) podToTokenServers(obj client.Object) []reconcile.Request {
+	app, ok := obj.GetLabels()[sentinelv1alpha1.AppLabel]
+	if !ok {
+		return nil
+	}
+	return r.tokenServerRequests(obj.GetNamespace(), client.MatchingFields{tokenServerAppIndex: app})
+}
+
+// dashboardToTokenServers maps a Dashboard to the token servers of the dashboard.
+func (r *TokenServerReconciler) dashboardToTokenServers(obj client.Object) []reconcile.Request {
+	return r.tokenServerRequests(obj.GetNamespace(), client.MatchingFields{tokenServerDashboardIndex: obj.GetName()})
+}
+
+// tokenServerToTokenServers maps a TokenServer to the token servers, in any
+// namespace, serving one of its apps, which may take over or give up the app.
+func (r *TokenServerReconciler) tokenServerToTokenServers(obj client.Object) []reconcile.Request {
+	server, ok := obj.(*sentinelv1alpha1.TokenServer)
+	if !ok {
+		return nil
+	}
+	var requests []reconcile.Request
+	seen := make(map[types.NamespacedName]bool)
+	for _, app := range server.Spec.Apps {
+		for _, request := range r.tokenServerRequests("", client.MatchingFields{tokenServerAppIndex: app}) {
+			if !seen[request.NamespacedName] {
+				seen[request.NamespacedName] = true
+				requests = append(requests, request)
+			}
+		}
+	}
+	return requests
+}
+
+// tokenServerRequests returns the requests for the token servers of
+// namespace matching fields, in any namespace when namespace is empty.
+func (r *TokenServerReconciler) tokenServerRequests(namespace string, fields client.MatchingFields) []reconcile.Request {
+	var servers sentinelv1alpha1.TokenServerList
+	if err := r.List(context.Background(), &servers, client.InNamespace(namespace), fields); err != nil {
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(servers.Items))
+	for _, server := range servers.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: server.Namespace, Name: server.Name},
+		})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *TokenServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Recorder = mgr.GetEventRecorderFor("tokenserver-controller")
+	if r.HTTPClient == nil {
+		r.HTTPClient = &http.Client{Transport: http.DefaultTransport, Timeout: defaultPublishTimeout}
+	}
+	if r.SecretReader == nil {
+		r.SecretReader = mgr.GetAPIReader()
+	}
+
+	indexer := mgr.GetFieldIndexer()
+	if err := indexer.IndexField(context.Background(), &sentinelv1alpha1.TokenServer{}, tokenServerAppIndex,
+		func(obj client.Object) []string { return obj.(*sentinelv1alpha1.TokenServer).Spec.Apps }); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(context.Background(), &sentinelv1alpha1.TokenServer{}, tokenServerDashboardIndex,
+		func(obj client.Object) []string {
+			return []string{obj.(*sentinelv1alpha1.TokenServer).Spec.DashboardRef.Name}
+		}); err != nil {
+		return err
+	}
+
+	// a token server taking over the apps of a deleted one is enqueued
+	// through the token servers serving the same apps
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&sentinelv1alpha1.TokenServer{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&corev1.Service{}).
+		Watches(&source.Kind{Type: &corev1.Pod{}},
+			handler.EnqueueRequestsFromMapFunc(r.podToTokenServers),
+			builder.WithPredicates(clientPodChanged)).
+		Watches(&source.Kind{Type: &sentinelv1alpha1.Dashboard{}}, handler.EnqueueRequestsFromMapFunc(r.dashboardToTokenServers)).
+		Watches(&source.Kind{Type: &sentinelv1alpha1.TokenServer{}}, handler.EnqueueRequestsFromMapFunc(r.tokenServerToTokenServers)).
+		Complete(r)
+}
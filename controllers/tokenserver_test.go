@@ -0,0 +1,322 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/nacos"
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/nacos/nacostest"
+)
+
+func newTestTokenServer(namespace, name string, age time.Duration, apps ...string) *sentinelv1alpha1.TokenServer {
+	return &sentinelv1alpha1.TokenServer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         namespace,
+			CreationTimestamp: metav1.NewTime(ruleCreated.Add(age)),
+		},
+		Spec: sentinelv1alpha1.TokenServerSpec{
+			DashboardRef: corev1.LocalObjectReference{Name: "sentinel-dashboard"},
+			Apps:         apps,
+			Image:        "registry.example.com/sentinel-token-server:1.8.6",
+		},
+	}
+}
+
+func newTestClientPod(name, app, ip string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "sentinel-group",
+			Labels:    map[string]string{sentinelv1alpha1.AppLabel: app},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: ip},
+	}
+}
+
+// newTestNacosDashboard returns a dashboard of namespace publishing to the
+// Nacos namespace nacosNamespace of server.
+func newTestNacosDashboard(namespace string, server *nacostest.Server, nacosNamespace string) *sentinelv1alpha1.Dashboard {
+	return &sentinelv1alpha1.Dashboard{
+		ObjectMeta: metav1.ObjectMeta{Name: "sentinel-dashboard", Namespace: namespace},
+		Spec: sentinelv1alpha1.DashboardSpec{
+			Datasource: &sentinelv1alpha1.DatasourceSpec{
+				Nacos: &sentinelv1alpha1.NacosDatasource{ServerAddr: []string{server.Addr()}, Namespace: nacosNamespace},
+			},
+		},
+	}
+}
+
+func clusterMapKey(app string) nacostest.Key {
+	return nacostest.Key{Group: nacos.DefaultGroup, DataID: app + clusterMapDataIDSuffix}
+}
+
+func TestNewClusterMap(t *testing.T) {
+	g := NewWithT(t)
+	server := newTestTokenServer("sentinel-group", "order", 0, "order-service")
+	pods := []corev1.Pod{
+		*newTestClientPod("order-1", "order-service", "10.0.0.2"),
+		*newTestClientPod("order-0", "order-service", "10.0.0.1"),
+	}
+
+	g.Expect(newClusterMap(server, pods)).To(Equal([]sentinelClusterGroup{{
+		MachineID: "order-token-server.sentinel-group.svc@8719",
+		IP:        "order-token-server.sentinel-group.svc",
+		Port:      sentinelv1alpha1.DefaultTokenServerPort,
+		ClientSet: []string{"10.0.0.1@8719", "10.0.0.2@8719"},
+	}}))
+
+	server.Spec.Port, server.Spec.ClientPort = 18731, 8720
+	groups := newClusterMap(server, pods)
+	g.Expect(groups[0].Port).To(Equal(int32(18731)))
+	g.Expect(groups[0].ClientSet).To(Equal([]string{"10.0.0.1@8720", "10.0.0.2@8720"}))
+
+	// a pod overriding its transport port declares it, as injected
+	overriding := newTestClientPod("order-2", "order-service", "10.0.0.3")
+	overriding.Spec.Containers = []corev1.Container{{
+		Name:  "app",
+		Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}, {Name: transportPortName, ContainerPort: 8721}},
+	}}
+	groups = newClusterMap(server, append(pods, *overriding))
+	g.Expect(groups[0].ClientSet).To(Equal([]string{"10.0.0.1@8720", "10.0.0.2@8720", "10.0.0.3@8721"}))
+
+	g.Expect(newClusterMap(server, nil)[0].ClientSet).To(BeEmpty())
+}
+
+func TestClientPodChanged(t *testing.T) {
+	running := newTestClientPod("order-0", "order-service", "10.0.0.1")
+	moved := running.DeepCopy()
+	moved.Status.PodIP = "10.0.0.2"
+	failed := running.DeepCopy()
+	failed.Status.Phase = corev1.PodFailed
+	deleting := running.DeepCopy()
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	relabeled := running.DeepCopy()
+	relabeled.Labels["cluster"] = "true"
+	annotated := running.DeepCopy()
+	annotated.Annotations = map[string]string{"restarted-at": "now"}
+
+	tests := []struct {
+		name string
+		pod  *corev1.Pod
+		want bool
+	}{
+		{name: "pod IP", pod: moved, want: true},
+		{name: "phase", pod: failed, want: true},
+		{name: "deletion", pod: deleting, want: true},
+		{name: "labels", pod: relabeled, want: true},
+		{name: "annotations", pod: annotated},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(clientPodChanged.Update(event.UpdateEvent{ObjectOld: running, ObjectNew: tt.pod})).To(Equal(tt.want))
+		})
+	}
+
+	g := NewWithT(t)
+	g.Expect(clientPodChanged.Create(event.CreateEvent{Object: running})).To(BeTrue())
+	g.Expect(clientPodChanged.Delete(event.DeleteEvent{Object: running})).To(BeTrue())
+	g.Expect(clientPodChanged.Generic(event.GenericEvent{Object: running})).To(BeFalse())
+}
+
+func TestPublishClusterMap(t *testing.T) {
+	group := sentinelClusterGroup{MachineID: "order-token-server.sentinel-group.svc@8719", ClientSet: []string{"10.0.0.1@8719"}}
+	published := `[{"machineId":"order-token-server.sentinel-group.svc@8719","ip":"","port":0,"clientSet":["10.0.0.1@8719"],"belongToApp":false}]`
+
+	tests := []struct {
+		name          string
+		current       string
+		groups        []sentinelClusterGroup
+		wantContent   string
+		wantPublishes int
+	}{
+		{name: "nothing to unassign", groups: []sentinelClusterGroup{}},
+		{name: "new cluster map", groups: []sentinelClusterGroup{group}, wantContent: published, wantPublishes: 1},
+		{name: "unchanged cluster map", current: published, groups: []sentinelClusterGroup{group}, wantContent: published},
+		{name: "clients unassigned", current: published, groups: []sentinelClusterGroup{}, wantContent: `[]`, wantPublishes: 1},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			server := nacostest.NewServer()
+			defer server.Close()
+			server.SetConfig(clusterMapKey("order-service"), tt.current)
+			nacosClient := nacos.NewClient(http.DefaultClient, nacos.Config{ServerAddr: []string{server.Addr()}})
+
+			g.Expect(publishClusterMap(context.Background(), nacosClient, "order-service", tt.groups)).To(Succeed())
+			content, _ := server.Config(clusterMapKey("order-service"))
+			g.Expect(content).To(Equal(tt.wantContent))
+			g.Expect(server.Publishes()).To(Equal(tt.wantPublishes))
+		})
+	}
+}
+
+func TestAssign(t *testing.T) {
+	nacosServer := nacostest.NewServer()
+	defer nacosServer.Close()
+	pods := []client.Object{
+		newTestClientPod("order-0", "order-service", "10.0.0.1"),
+		newTestClientPod("order-1", "order-service", "10.0.0.2"),
+		newTestClientPod("stock-0", "stock-service", "10.0.0.3"),
+	}
+
+	tests := []struct {
+		name            string
+		server          *sentinelv1alpha1.TokenServer
+		objs            []client.Object
+		wantApps        []string
+		wantClients     int32
+		wantReason      string
+		wantClusterMaps map[string]string
+	}{
+		{
+			name:        "clients assigned",
+			server:      newTestTokenServer("sentinel-group", "order", time.Hour, "order-service", "stock-service"),
+			wantApps:    []string{"order-service", "stock-service"},
+			wantClients: 3,
+			wantReason:  rulePublishedReason,
+			wantClusterMaps: map[string]string{
+				"order-service": "10.0.0.1@8719,10.0.0.2@8719",
+				"stock-service": "10.0.0.3@8719",
+			},
+		},
+		{
+			name:   "app served by an older token server of the dashboard",
+			server: newTestTokenServer("sentinel-group", "order", time.Hour, "order-service", "stock-service"),
+			objs: []client.Object{
+				newTestTokenServer("sentinel-group", "stock", 0, "stock-service"),
+			},
+			wantApps:        []string{"order-service"},
+			wantClients:     2,
+			wantReason:      ruleConflictReason,
+			wantClusterMaps: map[string]string{"order-service": "10.0.0.1@8719,10.0.0.2@8719"},
+		},
+		{
+			name:   "app served by an older token server of a dashboard sharing the datasource",
+			server: newTestTokenServer("sentinel-group", "order", time.Hour, "order-service", "stock-service"),
+			objs: []client.Object{
+				newTestNacosDashboard("other-group", nacosServer, ""),
+				newTestTokenServer("other-group", "stock", 0, "stock-service"),
+			},
+			wantApps:        []string{"order-service"},
+			wantClients:     2,
+			wantReason:      ruleConflictReason,
+			wantClusterMaps: map[string]string{"order-service": "10.0.0.1@8719,10.0.0.2@8719"},
+		},
+		{
+			name:   "app served by a token server of a dashboard with another datasource",
+			server: newTestTokenServer("sentinel-group", "order", time.Hour, "order-service", "stock-service"),
+			objs: []client.Object{
+				newTestNacosDashboard("other-group", nacosServer, "other"),
+				newTestTokenServer("other-group", "stock", 0, "stock-service"),
+			},
+			wantApps:    []string{"order-service", "stock-service"},
+			wantClients: 3,
+			wantReason:  rulePublishedReason,
+			wantClusterMaps: map[string]string{
+				"order-service": "10.0.0.1@8719,10.0.0.2@8719",
+				"stock-service": "10.0.0.3@8719",
+			},
+		},
+		{
+			name: "app no longer served",
+			server: func() *sentinelv1alpha1.TokenServer {
+				server := newTestTokenServer("sentinel-group", "order", time.Hour, "order-service")
+				server.Status.PublishedApps = []string{"order-service", "stock-service"}
+				return server
+			}(),
+			wantApps:        []string{"order-service"},
+			wantClients:     2,
+			wantReason:      rulePublishedReason,
+			wantClusterMaps: map[string]string{"order-service": "10.0.0.1@8719,10.0.0.2@8719", "stock-service": ""},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			for _, app := range []string{"order-service", "stock-service"} {
+				nacosServer.SetConfig(clusterMapKey(app), `[{"clientSet":["10.0.0.9@8719"]}]`)
+			}
+			dashboard := newTestNacosDashboard("sentinel-group", nacosServer, "")
+			objs := append([]client.Object{dashboard, tt.server}, tt.objs...)
+			r := newTestTokenServerReconciler(append(objs, pods...)...)
+
+			status := tt.server.Status.DeepCopy()
+			g.Expect(r.assign(context.Background(), tt.server, dashboard, status)).To(Succeed())
+			g.Expect(status.PublishedApps).To(Equal(tt.wantApps))
+			g.Expect(status.Clients).To(Equal(tt.wantClients))
+			cond := meta.FindStatusCondition(status.Conditions, sentinelv1alpha1.PublishedConditionType)
+			g.Expect(cond.Reason).To(Equal(tt.wantReason))
+
+			for app, clients := range tt.wantClusterMaps {
+				content, _ := nacosServer.Config(clusterMapKey(app))
+				if clients == "" {
+					g.Expect(content).To(Equal(`[]`))
+					continue
+				}
+				g.Expect(content).To(ContainSubstring(`"clientSet":["` + strings.ReplaceAll(clients, ",", `","`) + `"]`))
+			}
+		})
+	}
+}
+
+func TestUnassign(t *testing.T) {
+	nacosServer := nacostest.NewServer()
+	defer nacosServer.Close()
+
+	tests := []struct {
+		name        string
+		objs        []client.Object
+		wantCleared bool
+	}{
+		{name: "cluster map cleared", wantCleared: true},
+		{
+			name: "cluster map taken over by another token server",
+			objs: []client.Object{newTestTokenServer("sentinel-group", "order-copy", time.Hour, "order-service")},
+		},
+		{
+			name: "cluster map taken over by a token server of a dashboard sharing the datasource",
+			objs: []client.Object{
+				newTestNacosDashboard("other-group", nacosServer, ""),
+				newTestTokenServer("other-group", "order", time.Hour, "order-service"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			const clusterMap = `[{"clientSet":["10.0.0.1@8719"]}]`
+			nacosServer.SetConfig(clusterMapKey("order-service"), clusterMap)
+			dashboard := newTestNacosDashboard("sentinel-group", nacosServer, "")
+			server := newTestTokenServer("sentinel-group", "order", 0, "order-service")
+			server.Finalizers = []string{tokenServerFinalizer}
+			server.DeletionTimestamp = &metav1.Time{Time: ruleCreated}
+			server.Status.PublishedApps = []string{"order-service"}
+			r := newTestTokenServerReconciler(append([]client.Object{dashboard, server}, tt.objs...)...)
+
+			g.Expect(r.unassign(context.Background(), server, dashboard)).To(Succeed())
+			content, _ := nacosServer.Config(clusterMapKey("order-service"))
+			if tt.wantCleared {
+				g.Expect(content).To(Equal(`[]`))
+			} else {
+				g.Expect(content).To(Equal(clusterMap))
+			}
+		})
+	}
+}
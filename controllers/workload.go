@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/event"
+)
+
+// workloadState is the observed state of the Deployment or StatefulSet running a dashboard.
+type workloadState struct {
+	exists                   bool
+	replicas                 int32
+	readyReplicas            int32
+	availableReplicas        int32
+	updatedReplicas          int32
+	rolledOut                bool
+	progressDeadlineExceeded bool
+}
+
+func workloadKind(instance *sentinelv1alpha1.Dashboard) sentinelv1alpha1.WorkloadKind {
+	if instance.Spec.WorkloadKind == "" {
+		return sentinelv1alpha1.WorkloadKindDeployment
+	}
+	return instance.Spec.WorkloadKind
+}
+
+// newWorkloads returns the workload of the kind selected by spec.workloadKind,
+// and the workload of the other kind, which is deleted when switching kinds.
+func newWorkloads(instance *sentinelv1alpha1.Dashboard) (workload client.Object, stale client.Object) {
+	deploy, sts := &appsv1.Deployment{}, &appsv1.StatefulSet{}
+	for _, obj := range []client.Object{deploy, sts} {
+		obj.SetName(instance.Name)
+		obj.SetNamespace(instance.Namespace)
+	}
+	if workloadKind(instance) == sentinelv1alpha1.WorkloadKindStatefulSet {
+		return sts, deploy
+	}
+	return deploy, sts
+}
+
+// mutateWorkload renders the workload and records the datasource checksum
+// on its pod template, so that the pods are restarted when it changes.
+func mutateWorkload(instance *sentinelv1alpha1.Dashboard, workload client.Object, checksum string) {
+	var template *corev1.PodTemplateSpec
+	switch w := workload.(type) {
+	case *appsv1.Deployment:
+		MutateDeployment(instance, w)
+		template = &w.Spec.Template
+	case *appsv1.StatefulSet:
+		MutateStatefulSet(instance, w)
+		template = &w.Spec.Template
+	default:
+		return
+	}
+	if checksum != "" {
+		template.Annotations = map[string]string{datasourceChecksumAnnotation: checksum}
+	}
+}
+
+// statefulSetMissesDataClaim reports whether sts exists without the volume
+// claim template spec.storage needs. Claim templates are immutable, so such a
+// StatefulSet has to be recreated.
+func statefulSetMissesDataClaim(instance *sentinelv1alpha1.Dashboard, sts *appsv1.StatefulSet) bool {
+	if instance.Spec.Storage == nil || sts.CreationTimestamp.IsZero() {
+		return false
+	}
+	for _, claim := range sts.Spec.VolumeClaimTemplates {
+		if claim.Name == dataVolumeName {
+			return false
+		}
+	}
+	return true
+}
+
+// storageDrift lists the differences between spec.storage and claim, the
+// spec of the existing data claim or claim template, which cannot be applied:
+// the claim templates of a StatefulSet are immutable, and the size is the
+// only field of a claim that can be changed, to expand it.
+func storageDrift(storage *sentinelv1alpha1.StorageSpec, claim corev1.PersistentVolumeClaimSpec) []string {
+	var drift []string
+	want := newPersistentVolumeClaimSpec(storage)
+	if current := claim.Resources.Requests[corev1.ResourceStorage]; storage.Size.Cmp(current) != 0 {
+		drift = append(drift, fmt.Sprintf("size is %s, not %s", current.String(), storage.Size.String()))
+	}
+	// the class of a claim is defaulted by the cluster when not set
+	if want.StorageClassName != nil && (claim.StorageClassName == nil || *claim.StorageClassName != *want.StorageClassName) {
+		var current string
+		if claim.StorageClassName != nil {
+			current = *claim.StorageClassName
+		}
+		drift = append(drift, fmt.Sprintf("storageClassName is %q, not %q", current, *want.StorageClassName))
+	}
+	if !equality.Semantic.DeepEqual(claim.AccessModes, want.AccessModes) {
+		drift = append(drift, fmt.Sprintf("accessModes are %v, not %v", claim.AccessModes, want.AccessModes))
+	}
+	return drift
+}
+
+// UpdateStorageStatus sets the StorageSynced condition from the differences
+// between spec.storage and the data claim of the workload, and records an
+// event when spec.storage stops being applied. The condition is removed when
+// no storage is set.
+func (r *DashboardReconciler) UpdateStorageStatus(ctx context.Context, instance *sentinelv1alpha1.Dashboard) error {
+	storage := instance.Spec.Storage
+	if storage == nil {
+		r.RemoveCondition(ctx, instance, sentinelv1alpha1.StorageSyncedConditionType)
+		return nil
+	}
+
+	var claim *corev1.PersistentVolumeClaimSpec
+	if workloadKind(instance) == sentinelv1alpha1.WorkloadKindStatefulSet {
+		var sts appsv1.StatefulSet
+		if err := r.Get(ctx, client.ObjectKey{Namespace: instance.Namespace, Name: instance.Name}, &sts); client.IgnoreNotFound(err) != nil {
+			return errors.Wrap(err, "failed getting statefulset")
+		}
+		for i := range sts.Spec.VolumeClaimTemplates {
+			if sts.Spec.VolumeClaimTemplates[i].Name == dataVolumeName {
+				claim = &sts.Spec.VolumeClaimTemplates[i].Spec
+			}
+		}
+	} else {
+		var pvc corev1.PersistentVolumeClaim
+		err := r.Get(ctx, client.ObjectKey{Namespace: instance.Namespace, Name: dataClaimName(instance)}, &pvc)
+		if client.IgnoreNotFound(err) != nil {
+			return errors.Wrap(err, "failed getting persistentvolumeclaim")
+		}
+		if err == nil {
+			claim = &pvc.Spec
+		}
+	}
+	if claim == nil {
+		return r.UpdateCondition(ctx, instance, sentinelv1alpha1.StorageSyncedConditionType, metav1.ConditionUnknown,
+			"ClaimNotFound", "the data volume claim has not been created yet")
+	}
+
+	drift := storageDrift(storage, *claim)
+	if len(drift) == 0 {
+		return r.UpdateCondition(ctx, instance, sentinelv1alpha1.StorageSyncedConditionType, metav1.ConditionTrue,
+			"Synced", "the data volume claim matches spec.storage")
+	}
+
+	previous := r.GetCondition(ctx, instance, sentinelv1alpha1.StorageSyncedConditionType)
+	message := fmt.Sprintf("spec.storage cannot be applied to the existing data volume claim: %s", strings.Join(drift, "; "))
+	if err := r.UpdateCondition(ctx, instance, sentinelv1alpha1.StorageSyncedConditionType, metav1.ConditionFalse,
+		"ClaimImmutable", message); err != nil {
+		return err
+	}
+	if previous.Status != metav1.ConditionFalse {
+		r.Recorder.Eventf(instance, corev1.EventTypeWarning, string(event.DashboardStorage),
+			"Dashboard %s storage is not updated: %s", instance.Namespace+"/"+instance.Name, message)
+	}
+	return nil
+}
+
+// getWorkloadState observes the workload of the kind selected by spec.workloadKind.
+func (r *DashboardReconciler) getWorkloadState(ctx context.Context, instance *sentinelv1alpha1.Dashboard) (workloadState, error) {
+	workload, _ := newWorkloads(instance)
+	if err := r.Get(ctx, client.ObjectKeyFromObject(workload), workload); err != nil {
+		return workloadState{}, client.IgnoreNotFound(err)
+	}
+
+	switch w := workload.(type) {
+	case *appsv1.Deployment:
+		return workloadState{
+			exists:                   true,
+			replicas:                 w.Status.Replicas,
+			readyReplicas:            w.Status.ReadyReplicas,
+			availableReplicas:        w.Status.AvailableReplicas,
+			updatedReplicas:          w.Status.UpdatedReplicas,
+			rolledOut:                deploymentRolledOut(w),
+			progressDeadlineExceeded: deploymentProgressDeadlineExceeded(w),
+		}, nil
+	case *appsv1.StatefulSet:
+		return workloadState{
+			exists:            true,
+			replicas:          w.Status.Replicas,
+			readyReplicas:     w.Status.ReadyReplicas,
+			availableReplicas: w.Status.AvailableReplicas,
+			updatedReplicas:   w.Status.UpdatedReplicas,
+			rolledOut:         statefulSetRolledOut(w),
+		}, nil
+	}
+	return workloadState{}, nil
+}
+
+// statefulSetRolledOut reports whether the latest revision of the statefulset
+// is fully rolled out, in the same way as kubectl rollout status.
+func statefulSetRolledOut(sts *appsv1.StatefulSet) bool {
+	if sts.Generation > sts.Status.ObservedGeneration {
+		return false
+	}
+	replicas := int32(1)
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+	return sts.Status.ReadyReplicas >= replicas &&
+		sts.Status.UpdatedReplicas >= replicas &&
+		sts.Status.UpdateRevision == sts.Status.CurrentRevision
+}
@@ -0,0 +1,242 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
+)
+
+func TestStatefulSetRolledOut(t *testing.T) {
+	three := int32(3)
+	rolledOut := appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Generation: 2},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &three},
+		Status: appsv1.StatefulSetStatus{
+			ObservedGeneration: 2,
+			ReadyReplicas:      3,
+			UpdatedReplicas:    3,
+			CurrentRevision:    "sentinel-2",
+			UpdateRevision:     "sentinel-2",
+		},
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*appsv1.StatefulSet)
+		want   bool
+	}{
+		{name: "rolled out", want: true},
+		{name: "new generation not observed", mutate: func(sts *appsv1.StatefulSet) { sts.Generation = 3 }},
+		{name: "pod not ready", mutate: func(sts *appsv1.StatefulSet) { sts.Status.ReadyReplicas = 2 }},
+		{name: "pod not updated", mutate: func(sts *appsv1.StatefulSet) { sts.Status.UpdatedReplicas = 2 }},
+		{name: "revision rolling", mutate: func(sts *appsv1.StatefulSet) { sts.Status.UpdateRevision = "sentinel-3" }},
+		{
+			name: "one replica by default",
+			mutate: func(sts *appsv1.StatefulSet) {
+				sts.Spec.Replicas = nil
+				sts.Status.ReadyReplicas, sts.Status.UpdatedReplicas = 1, 1
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			sts := rolledOut.DeepCopy()
+			if tt.mutate != nil {
+				tt.mutate(sts)
+			}
+			g.Expect(statefulSetRolledOut(sts)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestMutateStatefulSetServiceName(t *testing.T) {
+	g := NewWithT(t)
+	instance := &sentinelv1alpha1.Dashboard{ObjectMeta: metav1.ObjectMeta{Name: "sentinel"}}
+	instance.SetDefaults("")
+
+	var sts appsv1.StatefulSet
+	MutateStatefulSet(instance, &sts)
+	g.Expect(sts.Spec.ServiceName).To(Equal("sentinel-headless"))
+
+	// the service name of an existing statefulset is immutable
+	sts.CreationTimestamp = metav1.Now()
+	sts.Spec.ServiceName = "sentinel"
+	MutateStatefulSet(instance, &sts)
+	g.Expect(sts.Spec.ServiceName).To(Equal("sentinel"))
+}
+
+func TestMutateHeadlessService(t *testing.T) {
+	g := NewWithT(t)
+	instance := &sentinelv1alpha1.Dashboard{ObjectMeta: metav1.ObjectMeta{Name: "sentinel"}}
+	instance.SetDefaults("")
+
+	var svc corev1.Service
+	MutateHeadlessService(instance, &svc)
+	g.Expect(svc.Labels).To(Equal(map[string]string{"app": "sentinel"}))
+	g.Expect(svc.Spec.ClusterIP).To(Equal(corev1.ClusterIPNone))
+	g.Expect(svc.Spec.Selector).To(Equal(map[string]string{"app": "sentinel"}))
+	g.Expect(svc.Spec.Ports).To(HaveLen(1))
+	g.Expect(svc.Spec.Ports[0].Port).To(Equal(int32(sentinelv1alpha1.DefaultPort)))
+}
+
+func TestStorageDrift(t *testing.T) {
+	fast, standard := "fast", "standard"
+	claim := corev1.PersistentVolumeClaimSpec{
+		AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+		StorageClassName: &standard,
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		storage sentinelv1alpha1.StorageSpec
+		want    []string
+	}{
+		{
+			name:    "in sync with the default class",
+			storage: sentinelv1alpha1.StorageSpec{Size: resource.MustParse("1Gi")},
+		},
+		{
+			name: "in sync",
+			storage: sentinelv1alpha1.StorageSpec{
+				Size:             resource.MustParse("1024Mi"),
+				StorageClassName: &standard,
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			},
+		},
+		{
+			name: "every field changed",
+			storage: sentinelv1alpha1.StorageSpec{
+				Size:             resource.MustParse("2Gi"),
+				StorageClassName: &fast,
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+			},
+			want: []string{
+				"size is 1Gi, not 2Gi",
+				`storageClassName is "standard", not "fast"`,
+				"accessModes are [ReadWriteOnce], not [ReadWriteMany]",
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(storageDrift(&tt.storage, claim)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestUpdateStorageStatus(t *testing.T) {
+	newDashboard := func(kind sentinelv1alpha1.WorkloadKind, size string) *sentinelv1alpha1.Dashboard {
+		instance := &sentinelv1alpha1.Dashboard{ObjectMeta: metav1.ObjectMeta{Name: "sentinel", Namespace: "sentinel-group"}}
+		instance.Spec.WorkloadKind = kind
+		instance.Spec.Storage = &sentinelv1alpha1.StorageSpec{Size: resource.MustParse(size)}
+		return instance
+	}
+	existing := func(obj client.Object, mutate func(instance *sentinelv1alpha1.Dashboard, obj client.Object)) client.Object {
+		instance := newDashboard(sentinelv1alpha1.WorkloadKindDeployment, "1Gi")
+		mutate(instance, obj)
+		obj.SetNamespace(instance.Namespace)
+		obj.SetCreationTimestamp(metav1.Now())
+		return obj
+	}
+	pvc := existing(&corev1.PersistentVolumeClaim{}, func(instance *sentinelv1alpha1.Dashboard, obj client.Object) {
+		obj.SetName(dataClaimName(instance))
+		MutatePersistentVolumeClaim(instance, obj.(*corev1.PersistentVolumeClaim))
+	})
+	sts := existing(&appsv1.StatefulSet{}, func(instance *sentinelv1alpha1.Dashboard, obj client.Object) {
+		instance.SetDefaults("")
+		obj.SetName(instance.Name)
+		MutateStatefulSet(instance, obj.(*appsv1.StatefulSet))
+	})
+
+	tests := []struct {
+		name       string
+		instance   *sentinelv1alpha1.Dashboard
+		objs       []client.Object
+		wantStatus metav1.ConditionStatus
+		wantReason string
+		wantEvents int
+	}{
+		{
+			name:       "claim not created",
+			instance:   newDashboard(sentinelv1alpha1.WorkloadKindDeployment, "1Gi"),
+			wantStatus: metav1.ConditionUnknown,
+			wantReason: "ClaimNotFound",
+		},
+		{
+			name:       "claim of the deployment in sync",
+			instance:   newDashboard(sentinelv1alpha1.WorkloadKindDeployment, "1Gi"),
+			objs:       []client.Object{pvc},
+			wantStatus: metav1.ConditionTrue,
+			wantReason: "Synced",
+		},
+		{
+			name:       "claim of the deployment shrunk",
+			instance:   newDashboard(sentinelv1alpha1.WorkloadKindDeployment, "512Mi"),
+			objs:       []client.Object{pvc},
+			wantStatus: metav1.ConditionFalse,
+			wantReason: "ClaimImmutable",
+			wantEvents: 1,
+		},
+		{
+			name:       "claim template of the statefulset in sync",
+			instance:   newDashboard(sentinelv1alpha1.WorkloadKindStatefulSet, "1Gi"),
+			objs:       []client.Object{sts},
+			wantStatus: metav1.ConditionTrue,
+			wantReason: "Synced",
+		},
+		{
+			name:       "claim template of the statefulset expanded",
+			instance:   newDashboard(sentinelv1alpha1.WorkloadKindStatefulSet, "2Gi"),
+			objs:       []client.Object{sts},
+			wantStatus: metav1.ConditionFalse,
+			wantReason: "ClaimImmutable",
+			wantEvents: 1,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			var objs []client.Object
+			for _, obj := range tt.objs {
+				objs = append(objs, obj.DeepCopyObject().(client.Object))
+			}
+			r := newTestDashboardReconciler(objs...)
+
+			g.Expect(r.UpdateStorageStatus(context.Background(), tt.instance)).To(Succeed())
+			cond := r.GetCondition(context.Background(), tt.instance, sentinelv1alpha1.StorageSyncedConditionType)
+			g.Expect(cond.Status).To(Equal(tt.wantStatus))
+			g.Expect(cond.Reason).To(Equal(tt.wantReason))
+			g.Expect(r.Recorder.(*record.FakeRecorder).Events).To(HaveLen(tt.wantEvents))
+
+			// the event is only recorded when the storage stops being applied
+			g.Expect(r.UpdateStorageStatus(context.Background(), tt.instance)).To(Succeed())
+			g.Expect(r.Recorder.(*record.FakeRecorder).Events).To(HaveLen(tt.wantEvents))
+		})
+	}
+
+	g := NewWithT(t)
+	instance := newDashboard(sentinelv1alpha1.WorkloadKindDeployment, "1Gi")
+	r := newTestDashboardReconciler(pvc)
+	g.Expect(r.UpdateStorageStatus(context.Background(), instance)).To(Succeed())
+	instance.Spec.Storage = nil
+	g.Expect(r.UpdateStorageStatus(context.Background(), instance)).To(Succeed())
+	g.Expect(instance.Status.Conditions).To(BeEmpty())
+}
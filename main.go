@@ -21,6 +21,8 @@ import (
 	"os"
 
 	"go.uber.org/zap/zapcore"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -28,8 +30,10 @@ import (
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	sentinelv1alpha1 "github.com/sentinel-group/sentinel-dashboard-k8s-operator/api/v1alpha1"
 	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/controllers"
@@ -52,11 +56,14 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var defaultDashboardImage string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&defaultDashboardImage, "default-dashboard-image", sentinelv1alpha1.DefaultImage,
+		"The dashboard image used for Dashboards that do not set spec.image.")
 	opts := zap.Options{
 		Development: true,
 		TimeEncoder: zapcore.ISO8601TimeEncoder,
@@ -66,6 +73,14 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	// only the pods of the Sentinel apps are cached: the injected pods and the
+	// clients of the token servers all have the app label
+	appPods, err := labels.Parse(sentinelv1alpha1.AppLabel)
+	if err != nil {
+		setupLog.Error(err, "unable to parse the pod selector")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -73,6 +88,9 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "e02f2ea4.sentinelguard.io",
+		NewCache: cache.BuilderWithOptions(cache.Options{
+			SelectorsByObject: cache.SelectorsByObject{&corev1.Pod{}: {Label: appPods}},
+		}),
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -91,12 +109,66 @@ func main() {
 	}
 
 	if err = (&controllers.DashboardReconciler{
+		Client:       mgr.GetClient(),
+		Scheme:       mgr.GetScheme(),
+		DefaultImage: defaultDashboardImage,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Dashboard")
+		os.Exit(1)
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&sentinelv1alpha1.Dashboard{}).SetupWebhookWithManager(mgr, defaultDashboardImage); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Dashboard")
+			os.Exit(1)
+		}
+	}
+	rulePublisher := controllers.NewRulePublisher(mgr)
+	for _, rule := range []struct {
+		kind   string
+		object interface {
+			sentinelv1alpha1.Rule
+			SetupWebhookWithManager(ctrl.Manager) error
+		}
+	}{
+		{"FlowRule", &sentinelv1alpha1.FlowRule{}},
+		{"DegradeRule", &sentinelv1alpha1.DegradeRule{}},
+		{"SystemRule", &sentinelv1alpha1.SystemRule{}},
+		{"AuthorityRule", &sentinelv1alpha1.AuthorityRule{}},
+		{"ParamFlowRule", &sentinelv1alpha1.ParamFlowRule{}},
+	} {
+		if err = (&controllers.RuleReconciler{
+			Client:    mgr.GetClient(),
+			Scheme:    mgr.GetScheme(),
+			Rule:      rule.object,
+			Publisher: rulePublisher,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", rule.kind)
+			os.Exit(1)
+		}
+		if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+			if err = rule.object.SetupWebhookWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create webhook", "webhook", rule.kind)
+				os.Exit(1)
+			}
+		}
+	}
+	if err = (&controllers.TokenServerReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Dashboard")
+		setupLog.Error(err, "unable to create controller", "controller", "TokenServer")
 		os.Exit(1)
 	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&sentinelv1alpha1.TokenServer{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "TokenServer")
+			os.Exit(1)
+		}
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		mgr.GetWebhookServer().Register(controllers.PodInjectorPath,
+			&webhook.Admission{Handler: &controllers.PodInjector{Client: mgr.GetClient()}})
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
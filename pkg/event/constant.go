@@ -11,4 +11,48 @@ const (
 
 	// DashboardReady represent health check passed
 	DashboardReady DashboardEventReason = "Ready"
+
+	// DashboardDatasource represent datasource resolving
+	DashboardDatasource DashboardEventReason = "Datasource"
+
+	// DashboardPhase represent dashboard phase transition
+	DashboardPhase DashboardEventReason = "PhaseChanged"
+
+	// DashboardDeleting represent dashboard finalization
+	DashboardDeleting DashboardEventReason = "Deleting"
+
+	// DashboardBackup represent rules backup before deletion
+	DashboardBackup DashboardEventReason = "Backup"
+
+	// DashboardLoginFailed represent dashboard rejecting the login of the operator
+	DashboardLoginFailed DashboardEventReason = "LoginFailed"
+
+	// DashboardStorage represent storage changes that cannot be applied
+	DashboardStorage DashboardEventReason = "Storage"
+
+	// DashboardPodSecurity represent pod security standard violations
+	DashboardPodSecurity DashboardEventReason = "PodSecurity"
+)
+
+type RuleEventReason string
+
+const (
+	// RulePublish represent rule set publishing to the datasource
+	RulePublish RuleEventReason = "Publish"
+
+	// RuleConflict represent rule declaring the same resource as an older one
+	RuleConflict RuleEventReason = "Conflict"
+
+	// RuleDrift represent rule set changed in the datasource outside of the rules
+	RuleDrift RuleEventReason = "Drift"
+)
+
+type TokenServerEventReason string
+
+const (
+	// TokenServerPublish represent cluster map publishing to the datasource
+	TokenServerPublish TokenServerEventReason = "Publish"
+
+	// TokenServerConflict represent token server serving an app served by an older one
+	TokenServerConflict TokenServerEventReason = "Conflict"
 )
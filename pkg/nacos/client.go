@@ -0,0 +1,190 @@
+// Package nacos is a minimal client of the config API of Nacos, used to
+// publish the rules of the apps to the Nacos datasource of a dashboard.
+package nacos
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultGroup is the group the dashboard stores the rules in unless configured.
+	DefaultGroup = "SENTINEL_GROUP"
+
+	// DefaultContextPath is the context path of the Nacos server unless configured.
+	DefaultContextPath = "/nacos"
+)
+
+// Config defines the Nacos server a Client talks to.
+type Config struct {
+	// ServerAddr are the addresses of the Nacos servers in host:port form.
+	// They are tried in order until one answers.
+	ServerAddr []string
+
+	// Namespace is the id of the Nacos namespace, empty for the public namespace.
+	Namespace string
+
+	// Group of the configs. Defaults to DefaultGroup.
+	Group string
+
+	// ContextPath of the Nacos servers. Defaults to DefaultContextPath.
+	ContextPath string
+
+	// Username and Password log in to Nacos when Username is set.
+	Username string
+	Password string
+}
+
+// Client gets and publishes the configs of a Nacos namespace and group.
+type Client struct {
+	httpClient *http.Client
+	config     Config
+}
+
+// NewClient returns a client of the Nacos servers of config.
+func NewClient(httpClient *http.Client, config Config) *Client {
+	if config.Group == "" {
+		config.Group = DefaultGroup
+	}
+	if config.ContextPath == "" {
+		config.ContextPath = DefaultContextPath
+	}
+	config.ContextPath = "/" + strings.Trim(config.ContextPath, "/")
+	return &Client{httpClient: httpClient, config: config}
+}
+
+// GetConfig returns the content of dataID, and false when it does not exist.
+func (c *Client) GetConfig(ctx context.Context, dataID string) (string, bool, error) {
+	var content string
+	found := true
+	err := c.do(ctx, func(base string, token string) error {
+		params := c.params(dataID, token)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/v1/cs/configs?"+params.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		status, body, err := c.send(req)
+		if err != nil {
+			return err
+		}
+		switch status {
+		case http.StatusOK:
+			content, found = string(body), true
+		case http.StatusNotFound:
+			content, found = "", false
+		default:
+			return errors.Errorf("getting config %s returned %d: %s", dataID, status, body)
+		}
+		return nil
+	})
+	return content, found, err
+}
+
+// PublishConfig creates or replaces the JSON content of dataID.
+func (c *Client) PublishConfig(ctx context.Context, dataID, content string) error {
+	return c.do(ctx, func(base string, token string) error {
+		params := c.params(dataID, token)
+		params.Set("content", content)
+		params.Set("type", "json")
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/v1/cs/configs", strings.NewReader(params.Encode()))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		status, body, err := c.send(req)
+		if err != nil {
+			return err
+		}
+		if status != http.StatusOK || strings.TrimSpace(string(body)) != "true" {
+			return errors.Errorf("publishing config %s returned %d: %s", dataID, status, body)
+		}
+		return nil
+	})
+}
+
+func (c *Client) params(dataID, token string) url.Values {
+	params := url.Values{
+		"dataId": []string{dataID},
+		"group":  []string{c.config.Group},
+	}
+	if c.config.Namespace != "" {
+		params.Set("tenant", c.config.Namespace)
+	}
+	if token != "" {
+		params.Set("accessToken", token)
+	}
+	return params
+}
+
+// do calls fn with the base URL of each server in turn, logging in first
+// when credentials are configured, until one of them succeeds.
+func (c *Client) do(ctx context.Context, fn func(base string, token string) error) error {
+	if len(c.config.ServerAddr) == 0 {
+		return errors.New("no nacos server address")
+	}
+
+	var err error
+	for _, addr := range c.config.ServerAddr {
+		base := "http://" + addr + c.config.ContextPath
+		var token string
+		if token, err = c.login(ctx, base); err != nil {
+			continue
+		}
+		if err = fn(base, token); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// login returns an access token, or an empty token without credentials.
+func (c *Client) login(ctx context.Context, base string) (string, error) {
+	if c.config.Username == "" {
+		return "", nil
+	}
+
+	form := url.Values{
+		"username": []string{c.config.Username},
+		"password": []string{c.config.Password},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/v1/auth/login", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	status, body, err := c.send(req)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK {
+		return "", errors.Errorf("logging in to nacos returned %d: %s", status, body)
+	}
+
+	var result struct {
+		AccessToken string `json:"accessToken"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", errors.Wrap(err, "cannot decode nacos login response")
+	}
+	return result.AccessToken, nil
+}
+
+func (c *Client) send(req *http.Request) (int, []byte, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
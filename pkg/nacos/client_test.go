@@ -0,0 +1,169 @@
+package nacos_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/nacos"
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/nacos/nacostest"
+)
+
+func TestGetConfig(t *testing.T) {
+	server := nacostest.NewServer()
+	defer server.Close()
+	server.SetConfig(nacostest.Key{Namespace: "sentinel", Group: nacos.DefaultGroup, DataID: "order-service-flow-rules"}, `[{"resource":"GET:/orders"}]`)
+	server.SetConfig(nacostest.Key{Group: nacos.DefaultGroup, DataID: "order-service-degrade-rules"}, `[]`)
+
+	tests := []struct {
+		name        string
+		namespace   string
+		group       string
+		dataID      string
+		wantContent string
+		wantFound   bool
+	}{
+		{
+			name:        "found",
+			namespace:   "sentinel",
+			dataID:      "order-service-flow-rules",
+			wantContent: `[{"resource":"GET:/orders"}]`,
+			wantFound:   true,
+		},
+		{
+			name:        "found in the public namespace",
+			dataID:      "order-service-degrade-rules",
+			wantContent: `[]`,
+			wantFound:   true,
+		},
+		{name: "not found", namespace: "sentinel", dataID: "order-service-degrade-rules"},
+		{name: "not found in another group", namespace: "sentinel", group: "OTHER", dataID: "order-service-flow-rules"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			client := nacos.NewClient(http.DefaultClient, nacos.Config{
+				ServerAddr: []string{server.Addr()},
+				Namespace:  tt.namespace,
+				Group:      tt.group,
+			})
+
+			content, found, err := client.GetConfig(context.Background(), tt.dataID)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(found).To(Equal(tt.wantFound))
+			g.Expect(content).To(Equal(tt.wantContent))
+		})
+	}
+}
+
+func TestPublishConfig(t *testing.T) {
+	g := NewWithT(t)
+	server := nacostest.NewServer()
+	defer server.Close()
+	client := nacos.NewClient(http.DefaultClient, nacos.Config{
+		ServerAddr:  []string{server.Addr()},
+		Namespace:   "sentinel",
+		ContextPath: "nacos/",
+	})
+
+	g.Expect(client.PublishConfig(context.Background(), "order-service-flow-rules", `[{"resource":"GET:/orders"}]`)).To(Succeed())
+	content, ok := server.Config(nacostest.Key{Namespace: "sentinel", Group: nacos.DefaultGroup, DataID: "order-service-flow-rules"})
+	g.Expect(ok).To(BeTrue())
+	g.Expect(content).To(Equal(`[{"resource":"GET:/orders"}]`))
+
+	// Nacos answers false with a 200 when it does not store the config
+	server.PublishResult = "false"
+	err := client.PublishConfig(context.Background(), "order-service-flow-rules", `[]`)
+	g.Expect(err).To(MatchError(ContainSubstring("publishing config order-service-flow-rules returned 200: false")))
+	content, _ = server.Config(nacostest.Key{Namespace: "sentinel", Group: nacos.DefaultGroup, DataID: "order-service-flow-rules"})
+	g.Expect(content).To(Equal(`[{"resource":"GET:/orders"}]`))
+}
+
+func TestLogin(t *testing.T) {
+	server := nacostest.NewServer()
+	defer server.Close()
+	server.Username, server.Password = "nacos", "s3cret"
+	server.SetConfig(nacostest.Key{Group: nacos.DefaultGroup, DataID: "order-service-flow-rules"}, `[]`)
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantErr  string
+	}{
+		{name: "logged in", username: "nacos", password: "s3cret"},
+		{name: "wrong password", username: "nacos", password: "other", wantErr: "logging in to nacos returned 403"},
+		{name: "no credentials", wantErr: "getting config order-service-flow-rules returned 403"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			client := nacos.NewClient(http.DefaultClient, nacos.Config{
+				ServerAddr: []string{server.Addr()},
+				Username:   tt.username,
+				Password:   tt.password,
+			})
+
+			_, found, err := client.GetConfig(context.Background(), "order-service-flow-rules")
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(found).To(BeTrue())
+			g.Expect(client.PublishConfig(context.Background(), "order-service-flow-rules", `[{}]`)).To(Succeed())
+		})
+	}
+}
+
+func TestFailover(t *testing.T) {
+	key := nacostest.Key{Group: nacos.DefaultGroup, DataID: "order-service-flow-rules"}
+	down := httptest.NewServer(http.NotFoundHandler())
+	downAddr := strings.TrimPrefix(down.URL, "http://")
+	down.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "server is starting", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	tests := []struct {
+		name  string
+		first string
+	}{
+		{name: "unreachable server", first: downAddr},
+		{name: "failing server", first: strings.TrimPrefix(failing.URL, "http://")},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			server := nacostest.NewServer()
+			defer server.Close()
+			server.SetConfig(key, `[]`)
+			client := nacos.NewClient(http.DefaultClient, nacos.Config{ServerAddr: []string{tt.first, server.Addr()}})
+
+			content, found, err := client.GetConfig(context.Background(), key.DataID)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(found).To(BeTrue())
+			g.Expect(content).To(Equal(`[]`))
+
+			g.Expect(client.PublishConfig(context.Background(), key.DataID, `[{}]`)).To(Succeed())
+			g.Expect(server.Publishes()).To(Equal(1))
+		})
+	}
+
+	g := NewWithT(t)
+	client := nacos.NewClient(http.DefaultClient, nacos.Config{ServerAddr: []string{downAddr}})
+	_, _, err := client.GetConfig(context.Background(), key.DataID)
+	g.Expect(err).To(HaveOccurred())
+
+	client = nacos.NewClient(http.DefaultClient, nacos.Config{})
+	_, _, err = client.GetConfig(context.Background(), key.DataID)
+	g.Expect(err).To(MatchError("no nacos server address"))
+}
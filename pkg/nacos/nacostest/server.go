@@ -0,0 +1,131 @@
+// Package nacostest provides an in-memory Nacos config server for the tests
+// of the clients of the nacos package.
+package nacostest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/sentinel-group/sentinel-dashboard-k8s-operator/pkg/nacos"
+)
+
+const accessToken = "nacostest-token"
+
+// Key identifies a config.
+type Key struct {
+	Namespace string
+	Group     string
+	DataID    string
+}
+
+// Server serves the login and config endpoints of the Nacos API at
+// nacos.DefaultContextPath.
+type Server struct {
+	*httptest.Server
+
+	// Username and Password are required to log in when Username is set,
+	// and the configs are then only served with the access token of the login.
+	Username string
+	Password string
+
+	// PublishResult, when set, is returned to publishes instead of storing the config.
+	PublishResult string
+
+	mu        sync.Mutex
+	configs   map[Key]string
+	publishes int
+}
+
+// NewServer starts a server without configs. The caller closes it.
+func NewServer() *Server {
+	s := &Server{configs: map[Key]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc(nacos.DefaultContextPath+"/v1/auth/login", s.login)
+	mux.HandleFunc(nacos.DefaultContextPath+"/v1/cs/configs", s.config)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Addr returns the host:port of the server.
+func (s *Server) Addr() string {
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+// Config returns the content of a config, and false when it does not exist.
+func (s *Server) Config(key Key) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.configs[key]
+	return content, ok
+}
+
+// SetConfig stores the content of a config, or deletes it when content is empty.
+func (s *Server) SetConfig(key Key, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if content == "" {
+		delete(s.configs, key)
+		return
+	}
+	s.configs[key] = content
+}
+
+// Publishes returns the number of configs published to the server.
+func (s *Server) Publishes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.publishes
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if s.Username == "" || r.FormValue("username") != s.Username || r.FormValue("password") != s.Password {
+		http.Error(w, "unknown user!", http.StatusForbidden)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"accessToken": accessToken, "tokenTtl": 18000})
+}
+
+func (s *Server) config(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.Username != "" && r.Form.Get("accessToken") != accessToken {
+		http.Error(w, "user not found!", http.StatusForbidden)
+		return
+	}
+	key := Key{Namespace: r.Form.Get("tenant"), Group: r.Form.Get("group"), DataID: r.Form.Get("dataId")}
+	if key.DataID == "" || key.Group == "" {
+		http.Error(w, "dataId and group are required", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		content, ok := s.Config(key)
+		if !ok {
+			http.Error(w, "config data not exist", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	case http.MethodPost:
+		s.mu.Lock()
+		s.publishes++
+		result := s.PublishResult
+		if result == "" {
+			s.configs[key] = r.PostForm.Get("content")
+			result = "true"
+		}
+		s.mu.Unlock()
+		_, _ = w.Write([]byte(result))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}